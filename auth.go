@@ -0,0 +1,429 @@
+package blockcache
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/crypt0walker/BlockCache/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 认证与按组授权
+// Server上的拦截器先用配置的认证器依次识别调用方身份（静态Bearer Token、HMAC签名、
+// mTLS证书），再按ACL检查该身份能否对请求的组执行该操作。
+// Client通过拦截器在每个请求上自动附加凭证，Group和Picker的使用方式不变。
+
+// Operation 缓存服务上的操作
+type Operation string
+
+const (
+	OpGet     Operation = "get"
+	OpSet     Operation = "set"
+	OpDelete  Operation = "delete"
+	OpHandoff Operation = "handoff"
+	// OpAdmin 管理接口（列出组、统计、节点、哈希环、导出、清空），不针对单个组的接口按组"*"授权
+	OpAdmin Operation = "admin"
+)
+
+// RequestInfo 参与认证的请求信息
+type RequestInfo struct {
+	FullMethod string // gRPC方法全名，如/pb.BlockCache/Get
+	Group      string
+	Key        string
+	Value      []byte
+}
+
+// ErrNoCredentials 认证器在请求中找不到自己负责的凭证时返回，交由下一个认证器处理
+var ErrNoCredentials = errors.New("no credentials")
+
+// Authenticator 服务端认证器，返回调用方身份
+type Authenticator interface {
+	Authenticate(ctx context.Context, req RequestInfo) (identity string, err error)
+}
+
+// ValueAuthenticator 认证器可以实现该接口，声明对某个请求的认证是否依赖value（如签名覆盖value）；
+// 不依赖时HTTP接口在读取请求体之前完成认证，未实现该接口的认证器按依赖value处理
+type ValueAuthenticator interface {
+	NeedsValue(ctx context.Context) bool
+}
+
+// ClientAuth 客户端凭证，返回需要附加到请求上的gRPC元数据
+type ClientAuth interface {
+	Credentials(ctx context.Context, req RequestInfo) (map[string]string, error)
+}
+
+// 认证相关的元数据
+const (
+	authorizationKey = "authorization"
+	hmacIdentityKey  = "x-blockcache-identity"
+	hmacTimestampKey = "x-blockcache-timestamp"
+	hmacSignatureKey = "x-blockcache-signature"
+)
+
+// AnonymousIdentity 允许匿名访问时，未携带凭证的调用方的身份
+const AnonymousIdentity = "anonymous"
+
+// ACLRule 一条授权规则，"*"匹配任意身份、组或操作
+type ACLRule struct {
+	Identity string
+	Groups   []string
+	Ops      []Operation
+}
+
+// ACL 身份到允许的组和操作的映射，任意一条规则匹配即放行
+type ACL struct {
+	Rules []ACLRule
+}
+
+// Allowed 判断identity能否对group执行op
+func (a *ACL) Allowed(identity, group string, op Operation) bool {
+	for _, r := range a.Rules {
+		if r.Identity != "*" && r.Identity != identity {
+			continue
+		}
+		if matchAny(r.Groups, group) && matchAny(r.Ops, op) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny[T ~string](patterns []T, v T) bool {
+	for _, p := range patterns {
+		if p == "*" || p == v {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthOptions 服务端认证与授权配置
+type AuthOptions struct {
+	// Authenticators 按顺序尝试，第一个找到凭证的认证器决定结果
+	Authenticators []Authenticator
+	// ACL 为nil时任何通过认证的身份都可以执行所有操作
+	ACL *ACL
+	// AllowAnonymous 未携带任何凭证的请求以AnonymousIdentity身份交给ACL判断
+	AllowAnonymous bool
+}
+
+// WithAuth 开启认证与按组授权
+func WithAuth(opts AuthOptions) ServerOption {
+	return func(o *ServerOptions) {
+		o.Auth = &opts
+	}
+}
+
+type identityKey struct{}
+
+// IdentityFromContext 返回认证拦截器识别出的调用方身份
+func IdentityFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(string)
+	return id, ok
+}
+
+// authenticate 依次尝试各认证器
+func (a *AuthOptions) authenticate(ctx context.Context, req RequestInfo) (string, error) {
+	for _, auth := range a.Authenticators {
+		identity, err := auth.Authenticate(ctx, req)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		if err != nil {
+			return "", status.Errorf(codes.Unauthenticated, "authentication failed: %v", err)
+		}
+		return identity, nil
+	}
+	if a.AllowAnonymous {
+		return AnonymousIdentity, nil
+	}
+	return "", status.Error(codes.Unauthenticated, "missing credentials")
+}
+
+// needsValue 判断认证该请求时是否有认证器需要读取value
+func (a *AuthOptions) needsValue(ctx context.Context) bool {
+	for _, auth := range a.Authenticators {
+		if v, ok := auth.(ValueAuthenticator); !ok || v.NeedsValue(ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize 按ACL检查权限
+func (a *AuthOptions) authorize(identity, group string, op Operation) error {
+	if a.ACL == nil || a.ACL.Allowed(identity, group, op) {
+		return nil
+	}
+	return status.Errorf(codes.PermissionDenied, "%s is not allowed to %s group %s", identity, op, group)
+}
+
+// cacheMethod 返回缓存服务方法对应的操作，其他服务（如健康检查）返回false
+func cacheMethod(fullMethod string) (Operation, bool) {
+	prefix := "/" + pb.BlockCache_ServiceDesc.ServiceName + "/"
+	if !strings.HasPrefix(fullMethod, prefix) {
+		return "", false
+	}
+	switch op := Operation(strings.ToLower(strings.TrimPrefix(fullMethod, prefix))); op {
+	case OpGet, OpSet, OpDelete, OpHandoff:
+		return op, true
+	default:
+		return OpAdmin, true
+	}
+}
+
+// requestInfo 从请求消息中提取认证所需的信息
+func requestInfo(fullMethod string, req interface{}) RequestInfo {
+	info := RequestInfo{FullMethod: fullMethod}
+	switch r := req.(type) {
+	case *pb.Request:
+		info.Group, info.Key, info.Value = r.GetGroup(), r.GetKey(), r.GetValue()
+	case interface{ GetGroup() string }:
+		info.Group = r.GetGroup()
+	case *pb.ListGroupsRequest, *pb.PeersRequest:
+		info.Group = "*"
+	}
+	return info
+}
+
+// unaryInterceptor 服务端一元调用的认证与授权
+func (a *AuthOptions) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	op, ok := cacheMethod(info.FullMethod)
+	if !ok {
+		return handler(ctx, req)
+	}
+	ctx, err := a.check(ctx, requestInfo(info.FullMethod, req), op)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// check 认证并授权一个请求，返回带有调用方身份的ctx，gRPC和HTTP接口共用
+func (a *AuthOptions) check(ctx context.Context, ri RequestInfo, op Operation) (context.Context, error) {
+	identity, err := a.authenticate(ctx, ri)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.authorize(identity, ri.Group, op); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, identityKey{}, identity), nil
+}
+
+// streamInterceptor 服务端流式调用的认证，流中每条消息的组由处理函数逐条授权
+func (a *AuthOptions) streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, ok := cacheMethod(info.FullMethod); !ok {
+		return handler(srv, ss)
+	}
+	identity, err := a.authenticate(ss.Context(), RequestInfo{FullMethod: info.FullMethod})
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: context.WithValue(ss.Context(), identityKey{}, identity)})
+}
+
+// StaticTokenAuth 静态Bearer Token认证，token到身份的映射
+type StaticTokenAuth struct {
+	tokens map[string]string
+}
+
+// NewStaticTokenAuth 创建静态Token认证器
+func NewStaticTokenAuth(tokens map[string]string) *StaticTokenAuth {
+	return &StaticTokenAuth{tokens: tokens}
+}
+
+// Authenticate 校验authorization: Bearer <token>
+func (s *StaticTokenAuth) Authenticate(ctx context.Context, req RequestInfo) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(authorizationKey)
+	if len(values) == 0 {
+		return "", ErrNoCredentials
+	}
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	for known, identity := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(known)) == 1 {
+			return identity, nil
+		}
+	}
+	return "", errors.New("invalid token")
+}
+
+// NeedsValue Token认证只读取请求头
+func (s *StaticTokenAuth) NeedsValue(ctx context.Context) bool {
+	return false
+}
+
+// bearerToken 客户端附加静态Token
+type bearerToken string
+
+// BearerToken 返回在每个请求上附加Bearer Token的客户端凭证
+func BearerToken(token string) ClientAuth {
+	return bearerToken(token)
+}
+
+func (t bearerToken) Credentials(ctx context.Context, req RequestInfo) (map[string]string, error) {
+	return map[string]string{authorizationKey: "Bearer " + string(t)}, nil
+}
+
+// HMACAuth 基于共享密钥的请求签名认证，签名覆盖方法、组、key、value和时间戳，可以防止篡改。
+// 时间戳只把签名的有效期限制在maxSkew内，不记录nonce，截获的请求在这段时间内仍可被重放。
+// 流式方法（Handoff、Dump）只对方法名签名，流中的条目不在签名范围内，需要配合TLS使用
+type HMACAuth struct {
+	secrets map[string][]byte
+	maxSkew time.Duration
+	now     func() time.Time
+}
+
+// NewHMACAuth 创建HMAC认证器，secrets为身份到密钥的映射，maxSkew<=0时默认5分钟
+func NewHMACAuth(secrets map[string][]byte, maxSkew time.Duration) *HMACAuth {
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+	return &HMACAuth{secrets: secrets, maxSkew: maxSkew, now: time.Now}
+}
+
+// Authenticate 校验签名和时间戳
+func (h *HMACAuth) Authenticate(ctx context.Context, req RequestInfo) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	identity, ts, sig := firstValue(md, hmacIdentityKey), firstValue(md, hmacTimestampKey), firstValue(md, hmacSignatureKey)
+	if identity == "" || sig == "" {
+		return "", ErrNoCredentials
+	}
+	secret, ok := h.secrets[identity]
+	if !ok {
+		return "", fmt.Errorf("unknown identity %s", identity)
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return "", errors.New("invalid timestamp")
+	}
+	if skew := h.now().Sub(time.Unix(unix, 0)); skew > h.maxSkew || skew < -h.maxSkew {
+		return "", errors.New("request timestamp out of range")
+	}
+	want := signRequest(secret, identity, ts, req)
+	got, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, want) {
+		return "", errors.New("invalid signature")
+	}
+	return identity, nil
+}
+
+// NeedsValue 请求携带签名时需要value才能校验
+func (h *HMACAuth) NeedsValue(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return firstValue(md, hmacSignatureKey) != ""
+}
+
+// hmacSigner 客户端请求签名
+type hmacSigner struct {
+	identity string
+	secret   []byte
+}
+
+// HMACSigner 返回用共享密钥为每个请求签名的客户端凭证
+func HMACSigner(identity string, secret []byte) ClientAuth {
+	return &hmacSigner{identity: identity, secret: secret}
+}
+
+func (s *hmacSigner) Credentials(ctx context.Context, req RequestInfo) (map[string]string, error) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	return map[string]string{
+		hmacIdentityKey:  s.identity,
+		hmacTimestampKey: ts,
+		hmacSignatureKey: hex.EncodeToString(signRequest(s.secret, s.identity, ts, req)),
+	}, nil
+}
+
+// signRequest 计算请求签名
+func signRequest(secret []byte, identity, ts string, req RequestInfo) []byte {
+	valueHash := sha256.Sum256(req.Value)
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%x", identity, ts, req.FullMethod, req.Group, req.Key, valueHash)
+	return mac.Sum(nil)
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// MTLSAuth 以客户端证书作为身份：优先使用CommonName，其次是第一个SAN
+// 证书链已由TLS握手校验（见TLSConfig.RequireClientCert）
+type MTLSAuth struct{}
+
+// Authenticate 从TLS连接中读取客户端证书
+func (MTLSAuth) Authenticate(ctx context.Context, req RequestInfo) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return "", ErrNoCredentials
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName, nil
+	}
+	if sans := certSANs(cert); len(sans) > 0 {
+		return sans[0], nil
+	}
+	return "", errors.New("client certificate has no identity")
+}
+
+// NeedsValue 证书在TLS握手时已经确定
+func (MTLSAuth) NeedsValue(ctx context.Context) bool {
+	return false
+}
+
+// clientAuthUnary 客户端拦截器，为一元调用附加凭证
+func clientAuthUnary(auth ClientAuth) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, err := attachCredentials(ctx, auth, requestInfo(method, req))
+		if err != nil {
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// clientAuthStream 客户端拦截器，为流式调用附加凭证
+func clientAuthStream(auth ClientAuth) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, err := attachCredentials(ctx, auth, RequestInfo{FullMethod: method})
+		if err != nil {
+			return nil, err
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+func attachCredentials(ctx context.Context, auth ClientAuth, req RequestInfo) (context.Context, error) {
+	md, err := auth.Credentials(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build credentials: %w", err)
+	}
+	for k, v := range md {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	return ctx, nil
+}
@@ -0,0 +1,183 @@
+package blockcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// startAuthServer 启动开启认证的测试服务
+func startAuthServer(t *testing.T, auth AuthOptions, opts ...grpc.ServerOption) string {
+	return startOptionsServer(t, &ServerOptions{Auth: &auth}, opts...)
+}
+
+func newAuthTestGroup(t *testing.T, name string) {
+	g := NewGroup(name, 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}))
+	t.Cleanup(func() { g.Close() })
+}
+
+func dialAuth(t *testing.T, addr string, opts ...ClientOption) *Client {
+	t.Helper()
+	client, err := NewClient(addr, "test", nil, append(opts, WithClientDialTimeout(2*time.Second))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestACL_Allowed 规则匹配与通配符
+func TestACL_Allowed(t *testing.T) {
+	acl := &ACL{Rules: []ACLRule{
+		{Identity: "reader", Groups: []string{"users"}, Ops: []Operation{OpGet}},
+		{Identity: "admin", Groups: []string{"*"}, Ops: []Operation{"*"}},
+		{Identity: "*", Groups: []string{"public"}, Ops: []Operation{OpGet}},
+	}}
+	tests := []struct {
+		identity, group string
+		op              Operation
+		want            bool
+	}{
+		{"reader", "users", OpGet, true},
+		{"reader", "users", OpSet, false},
+		{"reader", "orders", OpGet, false},
+		{"admin", "orders", OpDelete, true},
+		{"someone", "public", OpGet, true},
+		{"someone", "public", OpSet, false},
+	}
+	for _, tt := range tests {
+		if got := acl.Allowed(tt.identity, tt.group, tt.op); got != tt.want {
+			t.Errorf("Allowed(%s, %s, %s) = %v, want %v", tt.identity, tt.group, tt.op, got, tt.want)
+		}
+	}
+}
+
+// TestAuth_StaticToken Token认证与ACL
+func TestAuth_StaticToken(t *testing.T) {
+	newAuthTestGroup(t, "auth-token")
+	addr := startAuthServer(t, AuthOptions{
+		Authenticators: []Authenticator{NewStaticTokenAuth(map[string]string{"r-token": "reader"})},
+		ACL:            &ACL{Rules: []ACLRule{{Identity: "reader", Groups: []string{"auth-token"}, Ops: []Operation{OpGet}}}},
+	})
+	ctx := context.Background()
+
+	reader := dialAuth(t, addr, WithClientAuth(BearerToken("r-token")))
+	if _, err := reader.Get(ctx, "auth-token", "k"); err != nil {
+		t.Fatalf("reader Get: %v", err)
+	}
+	if err := reader.Set(ctx, "auth-token", "k", []byte("x")); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("reader Set: code = %v, want PermissionDenied", status.Code(err))
+	}
+
+	// 管理接口按OpAdmin授权
+	if _, err := reader.ListGroups(ctx); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("reader ListGroups: code = %v, want PermissionDenied", status.Code(err))
+	}
+
+	anonymous := dialAuth(t, addr)
+	if _, err := anonymous.Get(ctx, "auth-token", "k"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("anonymous Get: code = %v, want Unauthenticated", status.Code(err))
+	}
+
+	wrong := dialAuth(t, addr, WithClientAuth(BearerToken("guess")))
+	if _, err := wrong.Get(ctx, "auth-token", "k"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("wrong token Get: code = %v, want Unauthenticated", status.Code(err))
+	}
+}
+
+// TestAuth_HMAC 签名认证，密钥错误时拒绝
+func TestAuth_HMAC(t *testing.T) {
+	newAuthTestGroup(t, "auth-hmac")
+	addr := startAuthServer(t, AuthOptions{
+		Authenticators: []Authenticator{NewHMACAuth(map[string][]byte{"node-b": []byte("secret")}, 0)},
+	})
+	ctx := context.Background()
+
+	signed := dialAuth(t, addr, WithClientAuth(HMACSigner("node-b", []byte("secret"))))
+	if err := signed.Set(ctx, "auth-hmac", "k", []byte("x")); err != nil {
+		t.Fatalf("signed Set: %v", err)
+	}
+
+	forged := dialAuth(t, addr, WithClientAuth(HMACSigner("node-b", []byte("wrong"))))
+	if err := forged.Set(ctx, "auth-hmac", "k", []byte("x")); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("forged Set: code = %v, want Unauthenticated", status.Code(err))
+	}
+}
+
+// TestHMACAuth_RejectsTamperedAndStale 签名覆盖请求内容，过期请求被拒绝
+func TestHMACAuth_RejectsTamperedAndStale(t *testing.T) {
+	auth := NewHMACAuth(map[string][]byte{"node-b": []byte("secret")}, time.Minute)
+	req := RequestInfo{FullMethod: "/pb.BlockCache/Set", Group: "g", Key: "k", Value: []byte("v")}
+	creds, _ := HMACSigner("node-b", []byte("secret")).Credentials(context.Background(), req)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.New(creds))
+
+	if id, err := auth.Authenticate(ctx, req); err != nil || id != "node-b" {
+		t.Fatalf("Authenticate = %q, %v", id, err)
+	}
+
+	tampered := req
+	tampered.Value = []byte("evil")
+	if _, err := auth.Authenticate(ctx, tampered); err == nil {
+		t.Fatal("tampered value should be rejected")
+	}
+
+	auth.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if _, err := auth.Authenticate(ctx, req); err == nil {
+		t.Fatal("stale request should be rejected")
+	}
+}
+
+// TestAuth_MTLSIdentity 以客户端证书的CommonName作为身份
+func TestAuth_MTLSIdentity(t *testing.T) {
+	newAuthTestGroup(t, "auth-mtls")
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, 40, "node-a")
+	serverCfg := writeTLSFiles(t, t.TempDir(), ca, serverCert, serverKey)
+	serverCfg.RequireClientCert = true
+	certs, err := NewCertReloader(serverCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer certs.Close()
+
+	addr := startAuthServer(t, AuthOptions{
+		Authenticators: []Authenticator{MTLSAuth{}},
+		ACL:            &ACL{Rules: []ACLRule{{Identity: "node-b", Groups: []string{"*"}, Ops: []Operation{OpGet}}}},
+	}, grpc.Creds(certs.ServerCredentials()))
+
+	ctx := context.Background()
+	for name, want := range map[string]codes.Code{"node-b": codes.OK, "node-c": codes.PermissionDenied} {
+		certPEM, keyPEM := ca.issue(t, time.Now().UnixNano(), name)
+		client := dialAuth(t, addr, WithClientTLS(writeTLSFiles(t, t.TempDir(), ca, certPEM, keyPEM)))
+		if _, err := client.Get(ctx, "auth-mtls", "k"); status.Code(err) != want {
+			t.Fatalf("%s Get: code = %v, want %v", name, status.Code(err), want)
+		}
+	}
+}
+
+// TestAuth_HandoffPerGroup 迁移流中的每条数据按所属组授权
+func TestAuth_HandoffPerGroup(t *testing.T) {
+	newAuthTestGroup(t, "handoff-allowed")
+	newAuthTestGroup(t, "handoff-denied")
+	addr := startAuthServer(t, AuthOptions{
+		Authenticators: []Authenticator{NewStaticTokenAuth(map[string]string{"t": "node-b"})},
+		ACL:            &ACL{Rules: []ACLRule{{Identity: "node-b", Groups: []string{"handoff-allowed"}, Ops: []Operation{OpHandoff}}}},
+	})
+	client := dialAuth(t, addr, WithClientAuth(BearerToken("t")))
+	ctx := context.Background()
+	entries := []HandoffEntry{{Key: "k", Value: []byte("v")}}
+
+	if n, err := client.Handoff(ctx, "handoff-allowed", entries); err != nil || n != 1 {
+		t.Fatalf("allowed Handoff = %d, %v", n, err)
+	}
+	if _, err := client.Handoff(ctx, "handoff-denied", entries); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("denied Handoff: code = %v, want PermissionDenied", status.Code(err))
+	}
+}
@@ -0,0 +1,194 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 节点熔断与异常剔除
+// 节点变慢或故障时，若每次请求仍先打给它，就要白白等满超时。
+// 每个Client持有一个熔断器：连续失败或窗口内错误率超过阈值时打开，
+// PickPeer会绕开该节点，Group直接回源；冷却后进入半开状态，放少量探测请求，
+// 探测成功则恢复，失败则重新打开。
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+const (
+	BreakerClosed   BreakerState = iota // 正常放行
+	BreakerOpen                         // 熔断，请求绕开该节点
+	BreakerHalfOpen                     // 半开，只放行探测请求
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOptions 熔断配置
+type BreakerOptions struct {
+	ConsecutiveFailures int           // 连续失败多少次后打开，0表示不按连续失败判断
+	ErrorRate           float64       // 窗口内错误率超过该值后打开，0表示不按错误率判断
+	MinRequests         int           // 计算错误率所需的最少请求数
+	Window              time.Duration // 错误率统计窗口
+	OpenTimeout         time.Duration // 打开后多久进入半开
+	HalfOpenProbes      int           // 半开状态下允许同时进行的探测请求数
+}
+
+// DefaultBreakerOptions 默认熔断配置
+func DefaultBreakerOptions() BreakerOptions {
+	return BreakerOptions{
+		ConsecutiveFailures: 5,
+		ErrorRate:           0.5,
+		MinRequests:         20,
+		Window:              10 * time.Second,
+		OpenTimeout:         5 * time.Second,
+		HalfOpenProbes:      1,
+	}
+}
+
+// circuitBreaker 单个节点的熔断器
+type circuitBreaker struct {
+	mu    sync.Mutex
+	opts  BreakerOptions
+	state BreakerState
+	// 连续失败次数
+	consecutive int
+	// 当前窗口内的请求数与失败数
+	windowStart time.Time
+	requests    int
+	failures    int
+	// 打开的时间点
+	openedAt time.Time
+	// 半开状态下正在进行的探测请求数
+	probes int
+	// 便于测试替换的时钟
+	now func() time.Time
+}
+
+func newCircuitBreaker(opts BreakerOptions) *circuitBreaker {
+	if opts.HalfOpenProbes <= 0 {
+		opts.HalfOpenProbes = 1
+	}
+	return &circuitBreaker{
+		opts:        opts,
+		now:         time.Now,
+		windowStart: time.Now(),
+	}
+}
+
+// Allow 判断是否放行一次请求，放行后调用方必须调用Record汇报结果
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.opts.OpenTimeout {
+			return false
+		}
+		// 冷却结束，进入半开
+		b.state = BreakerHalfOpen
+		b.probes = 0
+		fallthrough
+	case BreakerHalfOpen:
+		if b.probes >= b.opts.HalfOpenProbes {
+			return false
+		}
+		b.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+// Record 汇报一次请求结果
+func (b *circuitBreaker) Record(err error) {
+	// 调用方主动取消不代表节点有问题（gRPC会把它转换成codes.Canceled）
+	if errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled {
+		b.mu.Lock()
+		if b.state == BreakerHalfOpen && b.probes > 0 {
+			b.probes--
+		}
+		b.mu.Unlock()
+		return
+	}
+	// 节点正常给出的业务错误（如key不存在）说明节点是健康的
+	if !isPeerFailure(err) {
+		err = nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if b.opts.Window > 0 && now.Sub(b.windowStart) >= b.opts.Window {
+		b.windowStart, b.requests, b.failures = now, 0, 0
+	}
+	b.requests++
+
+	if b.state == BreakerHalfOpen {
+		if b.probes > 0 {
+			b.probes--
+		}
+		if err != nil {
+			b.trip(now)
+		} else {
+			b.reset(now)
+		}
+		return
+	}
+
+	if err == nil {
+		b.consecutive = 0
+		return
+	}
+	b.consecutive++
+	b.failures++
+
+	if b.opts.ConsecutiveFailures > 0 && b.consecutive >= b.opts.ConsecutiveFailures {
+		b.trip(now)
+		return
+	}
+	if b.opts.ErrorRate > 0 && b.requests >= b.opts.MinRequests &&
+		float64(b.failures)/float64(b.requests) >= b.opts.ErrorRate {
+		b.trip(now)
+	}
+}
+
+// State 返回当前状态（打开且冷却已结束时视为半开）
+func (b *circuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.opts.OpenTimeout {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// trip 打开熔断器，调用者需持有锁
+func (b *circuitBreaker) trip(now time.Time) {
+	b.state = BreakerOpen
+	b.openedAt = now
+	b.consecutive = 0
+}
+
+// reset 恢复正常，调用者需持有锁
+func (b *circuitBreaker) reset(now time.Time) {
+	b.state = BreakerClosed
+	b.consecutive = 0
+	b.windowStart, b.requests, b.failures = now, 0, 0
+}
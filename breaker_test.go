@@ -0,0 +1,154 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBreaker(opts BreakerOptions) (*circuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := newCircuitBreaker(opts)
+	b.now = clock.now
+	b.windowStart = clock.t
+	return b, clock
+}
+
+var errPeer = errors.New("peer unavailable")
+
+// TestBreaker_ConsecutiveFailures 连续失败达到阈值后打开，冷却后半开探测
+func TestBreaker_ConsecutiveFailures(t *testing.T) {
+	b, clock := newTestBreaker(BreakerOptions{ConsecutiveFailures: 3, OpenTimeout: time.Second})
+
+	for i := 0; i < 3; i++ {
+		if !b.Allow() {
+			t.Fatalf("request %d should be allowed", i)
+		}
+		b.Record(errPeer)
+	}
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatal("breaker should be open after 3 consecutive failures")
+	}
+
+	// 冷却结束进入半开，只放行一个探测请求
+	clock.advance(time.Second)
+	if !b.Allow() {
+		t.Fatal("probe should be allowed in half-open state")
+	}
+	if b.Allow() {
+		t.Fatal("only one probe should be in flight")
+	}
+
+	// 探测失败重新打开
+	b.Record(errPeer)
+	if b.State() != BreakerOpen {
+		t.Fatal("failed probe should reopen the breaker")
+	}
+
+	// 再次冷却后探测成功，恢复正常
+	clock.advance(time.Second)
+	if !b.Allow() {
+		t.Fatal("probe should be allowed")
+	}
+	b.Record(nil)
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatal("successful probe should close the breaker")
+	}
+}
+
+// TestBreaker_SuccessResetsConsecutive 成功请求会清零连续失败计数
+func TestBreaker_SuccessResetsConsecutive(t *testing.T) {
+	b, _ := newTestBreaker(BreakerOptions{ConsecutiveFailures: 3, OpenTimeout: time.Second})
+	for i := 0; i < 10; i++ {
+		b.Record(errPeer)
+		b.Record(errPeer)
+		b.Record(nil)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatal("interleaved successes should keep the breaker closed")
+	}
+}
+
+// TestBreaker_ErrorRate 窗口内错误率超过阈值时打开，窗口滚动后重新计数
+func TestBreaker_ErrorRate(t *testing.T) {
+	b, clock := newTestBreaker(BreakerOptions{
+		ErrorRate:   0.5,
+		MinRequests: 10,
+		Window:      time.Second,
+		OpenTimeout: time.Second,
+	})
+
+	// 样本不足时不打开
+	for i := 0; i < 4; i++ {
+		b.Record(errPeer)
+		b.Record(nil)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatal("breaker should stay closed below MinRequests")
+	}
+
+	// 窗口滚动，旧的失败不再计入
+	clock.advance(time.Second)
+	for i := 0; i < 9; i++ {
+		b.Record(nil)
+	}
+	b.Record(errPeer)
+	if b.State() != BreakerClosed {
+		t.Fatal("10% error rate should not trip the breaker")
+	}
+
+	clock.advance(time.Second)
+	for i := 0; i < 5; i++ {
+		b.Record(nil)
+		b.Record(errPeer)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatal("50% error rate should trip the breaker")
+	}
+}
+
+// TestBreaker_IgnoresCallerCancel 调用方取消的请求不计为节点失败
+func TestBreaker_IgnoresCallerCancel(t *testing.T) {
+	b, _ := newTestBreaker(BreakerOptions{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+	b.Record(context.Canceled)
+	if b.State() != BreakerClosed {
+		t.Fatal("canceled requests should not trip the breaker")
+	}
+}
+
+// TestBreaker_IgnoresBusinessErrors 节点正常返回的业务错误不计入失败，传输层错误才计入
+func TestBreaker_IgnoresBusinessErrors(t *testing.T) {
+	business := []error{
+		fmt.Errorf("%w: k", ErrNotFound),
+		fmt.Errorf("%w: db down", ErrBackend),
+		status.Error(codes.NotFound, "key not found"),
+		// 旧版本节点返回的未分类回源错误
+		status.Error(codes.Unknown, "failed to get from getter: db down"),
+	}
+	for _, err := range business {
+		b, _ := newTestBreaker(BreakerOptions{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+		b.Record(err)
+		if b.State() != BreakerClosed {
+			t.Fatalf("%v should not trip the breaker", err)
+		}
+	}
+
+	for _, err := range []error{status.Error(codes.Unavailable, "connection refused"), errPeer} {
+		b, _ := newTestBreaker(BreakerOptions{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+		b.Record(err)
+		if b.State() != BreakerOpen {
+			t.Fatalf("%v should trip the breaker", err)
+		}
+	}
+}
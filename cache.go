@@ -19,6 +19,9 @@ type Cache struct {
 	misses      int64        // 缓存未命中次数
 	initialized int32        // 原子变量，标记缓存是否已初始化
 	closed      int32        // 原子变量，标记缓存是否已关闭
+	// evictions、expirations 已替换掉的存储累计的淘汰数，切换存储类型后统计仍然单调递增
+	evictions   int64
+	expirations int64
 }
 
 // CacheOptions 缓存配置选项
@@ -64,18 +67,8 @@ func (c *Cache) ensureInitialized() {
 	defer c.mu.Unlock()
 
 	if c.initialized == 0 {
-		// 创建存储选项
-		storeOpts := store.Options{
-			MaxBytes:        c.opts.MaxBytes,
-			BucketCount:     c.opts.BucketCount,
-			CapPerBucket:    c.opts.CapPerBucket,
-			Level2Cap:       c.opts.Level2Cap,
-			CleanupInterval: c.opts.CleanupTime,
-			OnEvicted:       c.opts.OnEvicted,
-		}
-
 		// 创建存储实例
-		c.store = store.NewStore(c.opts.CacheType, storeOpts)
+		c.store = store.NewStore(c.opts.CacheType, c.storeOptions())
 
 		// 标记为已初始化
 		atomic.StoreInt32(&c.initialized, 1)
@@ -84,6 +77,18 @@ func (c *Cache) ensureInitialized() {
 	}
 }
 
+// storeOptions 按当前配置生成存储选项
+func (c *Cache) storeOptions() store.Options {
+	return store.Options{
+		MaxBytes:        c.opts.MaxBytes,
+		BucketCount:     c.opts.BucketCount,
+		CapPerBucket:    c.opts.CapPerBucket,
+		Level2Cap:       c.opts.Level2Cap,
+		CleanupInterval: c.opts.CleanupTime,
+		OnEvicted:       c.opts.OnEvicted,
+	}
+}
+
 // Add 向缓存中添加一个 key-value 对
 func (c *Cache) Add(key string, value ByteView) {
 	if atomic.LoadInt32(&c.closed) == 1 {
@@ -93,6 +98,12 @@ func (c *Cache) Add(key string, value ByteView) {
 
 	c.ensureInitialized()
 
+	// 持有读锁，避免与SetCacheType替换存储、Close释放存储并发
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return
+	}
 	if err := c.store.Set(key, value); err != nil {
 		logrus.Warnf("Failed to add key %s to cache: %v", key, err)
 	}
@@ -112,6 +123,11 @@ func (c *Cache) Get(ctx context.Context, key string) (value ByteView, ok bool) {
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	// Close释放存储后initialized才被重置，这里要再检查一次
+	if c.store == nil {
+		atomic.AddInt64(&c.misses, 1)
+		return ByteView{}, false
+	}
 
 	// 从底层存储获取
 	val, found := c.store.Get(key)
@@ -151,6 +167,11 @@ func (c *Cache) AddWithExpiration(key string, value ByteView, expirationTime tim
 	}
 
 	// 设置到底层存储
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.store == nil {
+		return
+	}
 	if err := c.store.SetWithExpiration(key, value, expiration); err != nil {
 		logrus.Warnf("Failed to add key %s to cache with expiration: %v", key, err)
 	}
@@ -164,6 +185,9 @@ func (c *Cache) Delete(key string) bool {
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.store == nil {
+		return false
+	}
 
 	return c.store.Delete(key)
 }
@@ -176,6 +200,9 @@ func (c *Cache) Clear() {
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if c.store == nil {
+		return
+	}
 
 	c.store.Clear()
 
@@ -192,10 +219,84 @@ func (c *Cache) Len() int {
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if c.store == nil {
+		return 0
+	}
 
 	return c.store.Len()
 }
 
+// StoreStats 返回底层存储的容量与淘汰统计，未初始化时只有容量上限
+func (c *Cache) StoreStats() store.Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if atomic.LoadInt32(&c.closed) == 1 || c.store == nil {
+		return store.Stats{MaxBytes: c.opts.MaxBytes, Evictions: c.evictions, Expirations: c.expirations}
+	}
+	stats := c.store.Stats()
+	stats.Evictions += c.evictions
+	stats.Expirations += c.expirations
+	return stats
+}
+
+// SetMaxBytes 在线调整容量上限：缩小时立即按LRU顺序淘汰，扩大时立即生效；
+// 未初始化时只记录到配置中
+func (c *Cache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.opts.MaxBytes = maxBytes
+	if c.store != nil {
+		c.store.SetMaxBytes(maxBytes)
+	}
+}
+
+// CacheType 返回当前使用的缓存类型
+func (c *Cache) CacheType() store.CacheType {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.opts.CacheType
+}
+
+// SetCacheType 在线切换底层存储类型，已有的未过期数据按访问顺序迁移到新存储，剩余TTL保持不变；
+// 迁移期间持有写锁，读写请求会短暂等待。新旧类型是同一个存储实现时只记录类型
+func (c *Cache) SetCacheType(cacheType store.CacheType) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.opts.CacheType == cacheType {
+		return
+	}
+	previous := c.opts.CacheType
+	c.opts.CacheType = cacheType
+	// 两种类型对应同一个存储实现时重建存储没有意义，只记录类型
+	if c.store == nil || store.Implementation(previous) == store.Implementation(cacheType) {
+		return
+	}
+
+	old := c.store
+	next := store.NewStore(cacheType, c.storeOptions())
+	migrated := 0
+	// Range从最久未访问的项开始，依次写入后新存储中的访问顺序与原来一致
+	old.Range(func(key string, value store.Value, ttl time.Duration) bool {
+		var err error
+		if ttl > 0 {
+			err = next.SetWithExpiration(key, value, ttl)
+		} else {
+			err = next.Set(key, value)
+		}
+		if err == nil {
+			migrated++
+		}
+		return true
+	})
+	old.Close()
+	stats := old.Stats()
+	c.evictions += stats.Evictions
+	c.expirations += stats.Expirations
+	c.store = next
+	logrus.Infof("Cache migrated to type %s, %d items moved", cacheType, migrated)
+}
+
 // Close 关闭缓存，释放资源
 func (c *Cache) Close() {
 	// 如果已经关闭，直接返回
@@ -220,6 +321,27 @@ func (c *Cache) Close() {
 	logrus.Debugf("Cache closed, hits: %d, misses: %d", atomic.LoadInt64(&c.hits), atomic.LoadInt64(&c.misses))
 }
 
+// Range 遍历缓存中未过期的项，ttl为剩余存活时间（0表示不过期），fn返回false时停止
+func (c *Cache) Range(fn func(key string, value ByteView, ttl time.Duration) bool) {
+	if atomic.LoadInt32(&c.closed) == 1 || atomic.LoadInt32(&c.initialized) == 0 {
+		return
+	}
+
+	c.mu.RLock()
+	s := c.store
+	c.mu.RUnlock()
+	if s == nil {
+		return
+	}
+
+	s.Range(func(key string, value store.Value, ttl time.Duration) bool {
+		if bv, ok := value.(ByteView); ok {
+			return fn(key, bv, ttl)
+		}
+		return true
+	})
+}
+
 // Stats 返回缓存统计信息
 func (c *Cache) Stats() map[string]interface{} {
 	stats := map[string]interface{}{
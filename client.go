@@ -3,17 +3,29 @@ package blockcache
 //分布式缓存
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	pb "github.com/crypt0walker/BlockCache/pb"
 	"github.com/crypt0walker/BlockCache/registry"
 	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
+// fromPeerMetadataKey 节点间转发的Get请求携带的gRPC元数据
+const fromPeerMetadataKey = "x-blockcache-from-peer"
+
 // client结构体代表了一个到远程节点的连接实例
 type Client struct {
 	// 基础身份信息
@@ -27,6 +39,178 @@ type Client struct {
 	conn *grpc.ClientConn
 	// 4. 功能接口存根 stub
 	grpcCli pb.BlockCacheClient // grpc自动生成的客户端接口实现，是conn的包装，我们一般直接使用它
+	// 5. 熔断器，nil表示未开启
+	breaker *circuitBreaker
+	// 6. 连接与超时配置
+	opts ClientOptions
+	// 7. 由该Client自己创建的证书加载器，Close时一并停止
+	certs *CertReloader
+}
+
+// RetryPolicy gRPC层面的透明重试策略
+// 只应包含请求确定没有被服务端处理的状态码（如UNAVAILABLE），这样对Set/Delete也是安全的
+type RetryPolicy struct {
+	MaxAttempts       int           // 最大尝试次数（含首次），<=1表示不重试
+	InitialBackoff    time.Duration // 首次重试前的退避
+	MaxBackoff        time.Duration // 最大退避
+	BackoffMultiplier float64       // 退避倍数
+	RetryableCodes    []codes.Code  // 可重试的状态码
+}
+
+// ClientOptions 客户端配置
+type ClientOptions struct {
+	DialTimeout   time.Duration // 阻塞拨号的超时
+	Block         bool          // 是否在NewClient中阻塞等待连接建立
+	GetTimeout    time.Duration // 单次Get的超时，<=0表示只受调用方ctx约束
+	SetTimeout    time.Duration // 单次Set的超时
+	DeleteTimeout time.Duration // 单次Delete的超时
+	Retry         RetryPolicy
+	// TLS 节点间TLS/mTLS配置，nil表示明文连接
+	TLS *TLSConfig
+	// Credentials 直接指定传输凭证（如多个Client共享同一个CertReloader），优先于TLS
+	Credentials credentials.TransportCredentials
+	// Auth 在每个请求上自动附加的认证凭证，nil表示不附加
+	Auth ClientAuth
+	// TracerProvider 为每个RPC创建span并传递追踪上下文，nil表示不追踪
+	TracerProvider trace.TracerProvider
+	// External 以外部调用方身份访问，Get不带节点间标记，由对端按哈希环转发给负责的节点
+	External bool
+}
+
+// DefaultClientOptions 默认客户端配置
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		DialTimeout:   10 * time.Second,
+		Block:         true,
+		GetTimeout:    3 * time.Second,
+		SetTimeout:    3 * time.Second,
+		DeleteTimeout: 3 * time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts:       3,
+			InitialBackoff:    100 * time.Millisecond,
+			MaxBackoff:        time.Second,
+			BackoffMultiplier: 2,
+			RetryableCodes:    []codes.Code{codes.Unavailable},
+		},
+	}
+}
+
+// ClientOption 定义客户端选项函数类型
+type ClientOption func(*ClientOptions)
+
+// WithClientDialTimeout 设置阻塞拨号超时
+func WithClientDialTimeout(timeout time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.DialTimeout = timeout
+	}
+}
+
+// WithNonBlockingDial NewClient立即返回，连接在首次RPC时建立
+func WithNonBlockingDial() ClientOption {
+	return func(o *ClientOptions) {
+		o.Block = false
+	}
+}
+
+// WithExternalClient 以外部调用方（如命令行工具）身份访问节点，而不是作为集群中的其他节点
+func WithExternalClient() ClientOption {
+	return func(o *ClientOptions) {
+		o.External = true
+	}
+}
+
+// WithRPCTimeouts 设置Get/Set/Delete的单次超时
+func WithRPCTimeouts(get, set, del time.Duration) ClientOption {
+	return func(o *ClientOptions) {
+		o.GetTimeout = get
+		o.SetTimeout = set
+		o.DeleteTimeout = del
+	}
+}
+
+// WithRetryPolicy 设置gRPC透明重试策略
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(o *ClientOptions) {
+		o.Retry = policy
+	}
+}
+
+// WithClientTLS 使用TLS连接节点，配置了证书时同时出示客户端证书（mTLS）
+func WithClientTLS(cfg TLSConfig) ClientOption {
+	return func(o *ClientOptions) {
+		o.TLS = &cfg
+	}
+}
+
+// WithTransportCredentials 直接指定gRPC传输凭证
+func WithTransportCredentials(creds credentials.TransportCredentials) ClientOption {
+	return func(o *ClientOptions) {
+		o.Credentials = creds
+	}
+}
+
+// WithClientAuth 在每个请求上附加认证凭证（Bearer Token、HMAC签名等）
+func WithClientAuth(auth ClientAuth) ClientOption {
+	return func(o *ClientOptions) {
+		o.Auth = auth
+	}
+}
+
+// serviceConfig 把重试策略转换成gRPC service config
+func (p RetryPolicy) serviceConfig() string {
+	if p.MaxAttempts <= 1 || len(p.RetryableCodes) == 0 {
+		return ""
+	}
+	retryable := make([]string, 0, len(p.RetryableCodes))
+	for _, c := range p.RetryableCodes {
+		retryable = append(retryable, strconv.Quote(statusCodeName(c)))
+	}
+	multiplier := p.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return fmt.Sprintf(`{"methodConfig":[{"name":[{"service":%q}],"retryPolicy":{`+
+		`"maxAttempts":%d,"initialBackoff":"%.3fs","maxBackoff":"%.3fs",`+
+		`"backoffMultiplier":%g,"retryableStatusCodes":[%s]}}]}`,
+		pb.BlockCache_ServiceDesc.ServiceName, p.MaxAttempts,
+		p.InitialBackoff.Seconds(), p.MaxBackoff.Seconds(), multiplier,
+		strings.Join(retryable, ","))
+}
+
+// statusCodeName 把codes.Unavailable转换为service config要求的UNAVAILABLE形式
+func statusCodeName(c codes.Code) string {
+	var b strings.Builder
+	for i, r := range c.String() {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToUpper(b.String())
+}
+
+// withTimeout 在调用方ctx的基础上叠加单次RPC超时，两者取更早的截止时间
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// record 向熔断器汇报一次RPC结果
+func (c *Client) record(method string, start time.Time, err error) {
+	observePeerRPC(c.addr, method, start, err)
+	if c.breaker != nil {
+		c.breaker.Record(err)
+	}
+}
+
+// BreakerState 返回该节点熔断器的状态，未开启熔断时总是closed
+func (c *Client) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	return c.breaker.State()
 }
 
 // GetFromPeer implements [Peer].
@@ -39,9 +223,17 @@ func (c *Client) GetFromPeer(ctx context.Context, key string) ([]byte, error) {
 // 接口名：可以是你自己的 Peer，也可以是标准库的 io.Reader 等。
 // =：赋值。
 // (*实现类结构体)(nil)：构造一个该结构体的空指针。
-var _ Peer = (*Client)(nil)
+var (
+	_ Peer    = (*Client)(nil)
+	_ ttlPeer = (*Client)(nil)
+)
+
+func NewClient(addr string, svcName string, etcdCli *clientv3.Client, opts ...ClientOption) (*Client, error) {
+	options := DefaultClientOptions()
+	for _, opt := range opts {
+		opt(&options)
+	}
 
-func NewClient(addr string, svcName string, etcdCli *clientv3.Client) (*Client, error) {
 	var err error
 	//兜底创建etcd
 	if etcdCli == nil {
@@ -53,17 +245,55 @@ func NewClient(addr string, svcName string, etcdCli *clientv3.Client) (*Client,
 			return nil, err
 		}
 	}
-	//1.创建一个短增的context用于连接超时控制
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	//2.使用DialContext（内部就不需要使用WithTimeout选项了，时间由ctx控制）
-	//gRPC使用的核心知识
-	conn, err := grpc.DialContext(ctx, addr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithBlock(),
+	//传输凭证：显式指定的凭证 > TLS配置 > 明文
+	creds := options.Credentials
+	var certs *CertReloader
+	if creds == nil && options.TLS != nil {
+		certs, err = NewCertReloader(*options.TLS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
+		}
+		creds = certs.ClientCredentials()
+	}
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
 		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
-	)
+	}
+	//追踪在认证之前，认证失败的请求同样留下span
+	if options.TracerProvider != nil {
+		tracer := newTracer(options.TracerProvider)
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(clientTracingUnary(tracer)),
+			grpc.WithChainStreamInterceptor(clientTracingStream(tracer)))
+	}
+	if options.Auth != nil {
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(clientAuthUnary(options.Auth)),
+			grpc.WithChainStreamInterceptor(clientAuthStream(options.Auth)))
+	}
+	if sc := options.Retry.serviceConfig(); sc != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultServiceConfig(sc))
+	}
+
+	var conn *grpc.ClientConn
+	if options.Block {
+		//1.创建一个短增的context用于连接超时控制
+		ctx, cancel := context.WithTimeout(context.Background(), options.DialTimeout)
+		defer cancel()
+		//2.使用DialContext（内部就不需要使用WithTimeout选项了，时间由ctx控制）
+		//gRPC使用的核心知识
+		conn, err = grpc.DialContext(ctx, addr, append(dialOpts, grpc.WithBlock())...)
+	} else {
+		//非阻塞：连接在首次RPC时建立，NewClient立即返回
+		conn, err = grpc.NewClient(addr, dialOpts...)
+	}
 	if err != nil {
+		if certs != nil {
+			certs.Close()
+		}
 		return nil, fmt.Errorf("failed to connect to %s: %v", addr, err)
 	}
 
@@ -75,56 +305,235 @@ func NewClient(addr string, svcName string, etcdCli *clientv3.Client) (*Client,
 		etcdCli: etcdCli,
 		conn:    conn,
 		grpcCli: grpcClient,
+		opts:    options,
+		certs:   certs,
 	}
 	return client, nil
 }
 
+// Get 从远程节点获取数据，调用方ctx的取消和截止时间会一并传递给服务端
 func (c *Client) Get(ctx context.Context, group, key string) ([]byte, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := withTimeout(ctx, c.opts.GetTimeout)
 	defer cancel()
 
+	//标记为节点间请求，对端不会再把它转发给其他节点
+	if !c.opts.External {
+		ctx = metadata.AppendToOutgoingContext(ctx, fromPeerMetadataKey, "1")
+	}
+	start := time.Now()
 	resp, err := c.grpcCli.Get(ctx, &pb.Request{
 		Group: group,
 		Key:   key,
 	})
+	c.record("get", start, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get value from blockcache: %v", err)
+		return nil, fmt.Errorf("failed to get value from blockcache: %w", fromStatus(err))
 	}
 
 	return resp.GetValue(), nil
 }
 
-func (c *Client) Delete(group, key string) (bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// errPeerUnreachable 写请求没有发出：熔断器打开，或截止时间前始终没有建立连接
+var errPeerUnreachable = errors.New("peer unreachable")
+
+// awaitReady 写请求发出前确认连接已建立，否则返回errPeerUnreachable，
+// 调用方据此判断重试写请求是安全的
+func (c *Client) awaitReady(ctx context.Context, method string, start time.Time) error {
+	if c.breaker != nil && c.breaker.State() == BreakerOpen {
+		return fmt.Errorf("%w: circuit breaker for %s is open", errPeerUnreachable, c.addr)
+	}
+	for {
+		state := c.conn.GetState()
+		switch state {
+		case connectivity.Ready:
+			return nil
+		case connectivity.Idle:
+			c.conn.Connect()
+		case connectivity.Shutdown:
+			return fmt.Errorf("%w: connection to %s is closed", errPeerUnreachable, c.addr)
+		}
+		if !c.conn.WaitForStateChange(ctx, state) {
+			err := fmt.Errorf("%w: %s is not connected: %w", errPeerUnreachable, c.addr, ctx.Err())
+			c.record(method, start, err)
+			return err
+		}
+	}
+}
+
+// Delete 删除远程节点上的数据
+func (c *Client) Delete(ctx context.Context, group, key string) (bool, error) {
+	ctx, cancel := withTimeout(ctx, c.opts.DeleteTimeout)
 	defer cancel()
 
+	start := time.Now()
+	if err := c.awaitReady(ctx, "delete", start); err != nil {
+		return false, fmt.Errorf("failed to delete value from blockcache: %w", err)
+	}
 	resp, err := c.grpcCli.Delete(ctx, &pb.Request{
 		Group: group,
 		Key:   key,
 	})
+	c.record("delete", start, err)
 	if err != nil {
-		return false, fmt.Errorf("failed to delete value from blockcache: %v", err)
+		return false, fmt.Errorf("failed to delete value from blockcache: %w", fromStatus(err))
 	}
 
 	return resp.GetValue(), nil
 }
 
+// Set 向远程节点写入数据
 func (c *Client) Set(ctx context.Context, group, key string, value []byte) error {
+	return c.SetWithTTL(ctx, group, key, value, 0)
+}
+
+// SetWithTTL 向远程节点写入数据并指定存活时间，ttl<=0时使用对方组的默认过期时间
+func (c *Client) SetWithTTL(ctx context.Context, group, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := withTimeout(ctx, c.opts.SetTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if err := c.awaitReady(ctx, "set", start); err != nil {
+		return fmt.Errorf("failed to set value to blockcache: %w", err)
+	}
 	resp, err := c.grpcCli.Set(ctx, &pb.Request{
 		Group: group,
 		Key:   key,
 		Value: value,
+		TtlMs: ttlMillis(ttl),
 	})
+	c.record("set", start, err)
 	if err != nil {
-		return fmt.Errorf("failed to set value to blockcache: %v", err)
+		return fmt.Errorf("failed to set value to blockcache: %w", fromStatus(err))
 	}
 	logrus.Infof("grpc set request resp: %+v", resp)
 
 	return nil
 }
 
+// Handoff 把一批key流式迁移到该节点，返回对方实际写入的条数
+func (c *Client) Handoff(ctx context.Context, group string, entries []HandoffEntry) (int, error) {
+	start := time.Now()
+	stream, err := c.grpcCli.Handoff(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open handoff stream: %w", fromStatus(err))
+	}
+
+	for _, e := range entries {
+		if err := stream.Send(&pb.HandoffEntry{
+			Group: group,
+			Key:   e.Key,
+			Value: e.Value,
+			TtlMs: ttlMillis(e.TTL),
+		}); err != nil {
+			return 0, fmt.Errorf("failed to send handoff entry: %w", err)
+		}
+	}
+
+	resp, err := stream.CloseAndRecv()
+	c.record("handoff", start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to finish handoff: %w", fromStatus(err))
+	}
+	return int(resp.GetAccepted()), nil
+}
+
+// ttlMillis 把TTL换成请求中的毫秒数，不足1毫秒时向上取整，避免快过期的key被当成永不过期
+func ttlMillis(ttl time.Duration) int64 {
+	ms := ttl.Milliseconds()
+	if ttl > 0 && ms == 0 {
+		ms = 1
+	}
+	return ms
+}
+
+var _ HandoffPeer = (*Client)(nil)
+
+// ListGroups 返回节点上的所有缓存组
+func (c *Client) ListGroups(ctx context.Context) ([]string, error) {
+	resp, err := c.grpcCli.ListGroups(ctx, &pb.ListGroupsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", fromStatus(err))
+	}
+	return resp.GetGroups(), nil
+}
+
+// Stats 返回节点上某个缓存组的统计信息
+func (c *Client) Stats(ctx context.Context, group string) (*pb.StatsResponse, error) {
+	resp, err := c.grpcCli.Stats(ctx, &pb.StatsRequest{Group: group})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stats: %w", fromStatus(err))
+	}
+	return resp, nil
+}
+
+// CreateGroup 写入集群范围的组定义，所有节点随后创建该组；已存在时返回registry.ErrGroupExists
+func (c *Client) CreateGroup(ctx context.Context, def registry.GroupDefinition) error {
+	if _, err := c.grpcCli.CreateGroup(ctx, definitionToPB(def)); err != nil {
+		return fmt.Errorf("failed to create group: %w", fromStatus(err))
+	}
+	return nil
+}
+
+// ResizeGroup 在线调整组的容量上限（0表示不修改）或存储类型（空表示不修改），返回调整后的定义；
+// 节点开启了组定义注册表时对所有节点生效，否则只调整该节点
+func (c *Client) ResizeGroup(ctx context.Context, group string, maxBytes int64, cacheType string) (registry.GroupDefinition, error) {
+	resp, err := c.grpcCli.ResizeGroup(ctx, &pb.ResizeGroupRequest{Group: group, MaxBytes: maxBytes, CacheType: cacheType})
+	if err != nil {
+		return registry.GroupDefinition{}, fmt.Errorf("failed to resize group: %w", fromStatus(err))
+	}
+	return definitionFromPB(resp), nil
+}
+
+// DestroyGroup 删除组定义，所有节点销毁该组；返回定义是否存在
+func (c *Client) DestroyGroup(ctx context.Context, group string) (bool, error) {
+	resp, err := c.grpcCli.DestroyGroup(ctx, &pb.DestroyGroupRequest{Group: group})
+	if err != nil {
+		return false, fmt.Errorf("failed to destroy group: %w", fromStatus(err))
+	}
+	return resp.GetExisted(), nil
+}
+
+// Peers 返回节点已发现的其他节点
+func (c *Client) Peers(ctx context.Context) (*pb.PeersResponse, error) {
+	resp, err := c.grpcCli.Peers(ctx, &pb.PeersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", fromStatus(err))
+	}
+	return resp, nil
+}
+
+// Dump 流式读取缓存组中的所有项，fn返回错误时停止
+func (c *Client) Dump(ctx context.Context, group string, fn func(HandoffEntry) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.grpcCli.Dump(ctx, &pb.DumpRequest{Group: group})
+	if err != nil {
+		return fmt.Errorf("failed to open dump stream: %w", fromStatus(err))
+	}
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to receive dump entry: %w", fromStatus(err))
+		}
+		if err := fn(HandoffEntry{
+			Key:   entry.GetKey(),
+			Value: entry.GetValue(),
+			TTL:   time.Duration(entry.GetTtlMs()) * time.Millisecond,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
 // 中断该client的TCP conn
 func (c *Client) Close() error {
+	if c.certs != nil {
+		c.certs.Close()
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
@@ -0,0 +1,220 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	pb "github.com/crypt0walker/BlockCache/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// startTestServer 在随机端口上启动不依赖etcd的gRPC服务，返回监听地址
+func startTestServer(t *testing.T, opts ...grpc.ServerOption) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(opts...)
+	pb.RegisterBlockCacheServer(gs, &Server{})
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+// TestClient_PropagatesCancellation 调用方取消后，服务端的Getter能感知到
+func TestClient_PropagatesCancellation(t *testing.T) {
+	canceled := make(chan struct{})
+	g := NewGroup("client-cancel", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}))
+	defer g.Close()
+
+	addr := startTestServer(t)
+	client, err := NewClient(addr, "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := client.Get(ctx, "client-cancel", "k"); err == nil {
+		t.Fatal("expected error after cancel")
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("server-side getter was not canceled")
+	}
+}
+
+// TestClient_RPCTimeout 单次RPC超时短于调用方ctx时以前者为准
+func TestClient_RPCTimeout(t *testing.T) {
+	g := NewGroup("client-timeout", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	defer g.Close()
+
+	addr := startTestServer(t)
+	client, err := NewClient(addr, "test", nil, WithRPCTimeouts(50*time.Millisecond, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	if _, err := client.Get(context.Background(), "client-timeout", "k"); err == nil {
+		t.Fatal("expected timeout")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Get took %v, want about 50ms", elapsed)
+	}
+}
+
+// TestClient_NonBlockingDial 非阻塞拨号时，即使对端不可达NewClient也立即返回
+func TestClient_NonBlockingDial(t *testing.T) {
+	start := time.Now()
+	client, err := NewClient("127.0.0.1:1", "test", nil, WithNonBlockingDial())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("NewClient blocked for %v", elapsed)
+	}
+}
+
+// TestClient_WriteNotSent 对端不可达时写请求不会发出，返回的错误可以安全重试
+func TestClient_WriteNotSent(t *testing.T) {
+	client, err := NewClient("127.0.0.1:1", "test", nil, WithNonBlockingDial(),
+		WithRPCTimeouts(0, 50*time.Millisecond, 50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if err := client.Set(context.Background(), "g", "k", []byte("v")); !errors.Is(err, errPeerUnreachable) {
+		t.Fatalf("set err = %v, want errPeerUnreachable", err)
+	}
+	if _, err := client.Delete(context.Background(), "g", "k"); !errors.Is(err, errPeerUnreachable) {
+		t.Fatalf("delete err = %v, want errPeerUnreachable", err)
+	}
+}
+
+// TestRetryPolicy_ServiceConfig 重试策略转换为gRPC service config
+func TestRetryPolicy_ServiceConfig(t *testing.T) {
+	if got := statusCodeName(codes.DeadlineExceeded); got != "DEADLINE_EXCEEDED" {
+		t.Fatalf("statusCodeName = %s", got)
+	}
+	if sc := (RetryPolicy{MaxAttempts: 1}).serviceConfig(); sc != "" {
+		t.Fatalf("single attempt should disable retries, got %s", sc)
+	}
+
+	sc := DefaultClientOptions().Retry.serviceConfig()
+	conn, err := grpc.NewClient("127.0.0.1:1", grpc.WithDefaultServiceConfig(sc),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("invalid service config %s: %v", sc, err)
+	}
+	conn.Close()
+}
+
+// TestClient_AdminRPCs 带TTL写入、列出组、统计，以及Dump导出后用Handoff导入
+func TestClient_AdminRPCs(t *testing.T) {
+	src := NewGroup("admin-src", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	defer src.Close()
+	dst := NewGroup("admin-dst", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	defer dst.Close()
+
+	client, err := NewClient(startTestServer(t), "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	if err := client.SetWithTTL(ctx, "admin-src", "short", []byte("s"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Set(ctx, "admin-src", "forever", []byte("f")); err != nil {
+		t.Fatal(err)
+	}
+
+	groups, err := client.ListGroups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, g := range groups {
+		if g == "admin-src" || g == "admin-dst" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("groups = %v", groups)
+	}
+
+	stats, err := client.Stats(ctx, "admin-src")
+	if err != nil || stats.GetItems() != 2 {
+		t.Fatalf("Stats = %v, %v", stats, err)
+	}
+	if _, err := client.Stats(ctx, "no-such-group"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("Stats of missing group: %v", err)
+	}
+
+	var entries []HandoffEntry
+	if err := client.Dump(ctx, "admin-src", func(e HandoffEntry) error {
+		entries = append(entries, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("dumped %d entries, want 2", len(entries))
+	}
+	for _, e := range entries {
+		if (e.Key == "short") != (e.TTL > 0 && e.TTL <= time.Hour) {
+			t.Fatalf("entry %s has ttl %v", e.Key, e.TTL)
+		}
+	}
+	if n, err := client.Handoff(ctx, "admin-dst", entries); err != nil || n != 2 {
+		t.Fatalf("restore = %d, %v", n, err)
+	}
+	if v, err := dst.Get(ctx, "forever"); err != nil || v.String() != "f" {
+		t.Fatalf("restored value = %q, %v", v.String(), err)
+	}
+
+	if _, err := client.Peers(ctx); status.Code(err) != codes.Unimplemented {
+		t.Fatalf("Peers without picker: %v", err)
+	}
+
+	// 不足1毫秒的TTL向上取整，快过期的key迁移后不会变成永不过期
+	if _, err := client.Handoff(ctx, "admin-dst", []HandoffEntry{{Key: "dying", Value: []byte("d"), TTL: 500 * time.Microsecond}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	dst.mainCache.Range(func(key string, value ByteView, ttl time.Duration) bool {
+		if key == "dying" {
+			t.Fatalf("sub-millisecond entry still alive with ttl %v", ttl)
+		}
+		return true
+	})
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	blockcache "github.com/crypt0walker/BlockCache"
+	"github.com/crypt0walker/BlockCache/pb"
+	"github.com/crypt0walker/BlockCache/registry"
+)
+
+// usageError 参数不正确，由main输出该命令的用法
+type usageError struct{}
+
+func (usageError) Error() string { return "invalid arguments" }
+
+// parseArgs 解析子命令的参数，要求剩余的位置参数个数在[min, max]之间
+func parseArgs(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	if err := fs.Parse(args); err != nil {
+		return nil, usageError{}
+	}
+	if fs.NArg() < min || fs.NArg() > max {
+		return nil, usageError{}
+	}
+	return fs.Args(), nil
+}
+
+func runGet(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("get", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	value, err := c.Get(ctx, args[0], args[1])
+	if err != nil {
+		return err
+	}
+	out.Write(value)
+	fmt.Fprintln(out)
+	return nil
+}
+
+func runSet(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("set", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", 0, "存活时间，0表示使用组的默认过期时间")
+	args, err := parseArgs(fs, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	value := []byte(args[2])
+	if args[2] == "-" {
+		if value, err = io.ReadAll(os.Stdin); err != nil {
+			return err
+		}
+	}
+	return c.SetWithTTL(ctx, args[0], args[1], value, *ttl)
+}
+
+func runDel(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("del", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	_, err = c.Delete(ctx, args[0], args[1])
+	return err
+}
+
+func runImport(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	ttl := fs.Duration("ttl", 0, "存活时间，0表示使用组的默认过期时间")
+	sep := fs.String("sep", "\t", "key与value之间的分隔符")
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	in, closeIn, err := openInput(args[1])
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	imported := 0
+	err = scanImport(in, *sep, func(key string, value []byte) error {
+		if err := c.SetWithTTL(ctx, args[0], key, value, *ttl); err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+		imported++
+		return nil
+	})
+	fmt.Fprintf(out, "imported %d keys\n", imported)
+	return err
+}
+
+// scanImport 逐行解析 key<sep>value，忽略空行和#开头的注释
+func scanImport(r io.Reader, sep string, fn func(key string, value []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, sep)
+		if !ok || key == "" {
+			return fmt.Errorf("line %d: expected key%svalue", line, strings.ReplaceAll(sep, "\t", `\t`))
+		}
+		if err := fn(key, []byte(value)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func runGroups(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	if _, err := parseArgs(flag.NewFlagSet("groups", flag.ContinueOnError), args, 0, 0); err != nil {
+		return err
+	}
+	groups, err := c.ListGroups(ctx)
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		fmt.Fprintln(out, g)
+	}
+	return nil
+}
+
+func runStats(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("stats", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	s, err := c.Stats(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	printStats(out, s)
+	return nil
+}
+
+func printStats(out io.Writer, s *pb.StatsResponse) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "group\t%s\n", s.GetGroup())
+	fmt.Fprintf(w, "cache type\t%s\n", s.GetCacheType())
+	fmt.Fprintf(w, "items\t%d\n", s.GetItems())
+	fmt.Fprintf(w, "bytes\t%d / %d\n", s.GetBytes(), s.GetMaxBytes())
+	fmt.Fprintf(w, "evictions\t%d\n", s.GetEvictions())
+	fmt.Fprintf(w, "expirations\t%d\n", s.GetExpirations())
+	fmt.Fprintf(w, "local hits/misses\t%d / %d\n", s.GetLocalHits(), s.GetLocalMisses())
+	fmt.Fprintf(w, "peer hits/misses\t%d / %d\n", s.GetPeerHits(), s.GetPeerMisses())
+	fmt.Fprintf(w, "loader hits/errors\t%d / %d\n", s.GetLoaderHits(), s.GetLoaderErrors())
+	fmt.Fprintf(w, "loads\t%d\n", s.GetLoads())
+	fmt.Fprintf(w, "peer retries\t%d\n", s.GetPeerRetries())
+	fmt.Fprintf(w, "hedges (won)\t%d (%d)\n", s.GetHedges(), s.GetHedgeWins())
+	fmt.Fprintln(w, "\nlatency\tcount\tmean\tp50\tp99\tmax")
+	for _, l := range []struct {
+		name string
+		s    *pb.LatencySummary
+	}{{"local hit", s.GetLocalHitLatency()}, {"peer", s.GetPeerLatency()}, {"loader", s.GetLoaderLatency()}} {
+		fmt.Fprintf(w, "%s\t%d\t%v\t%v\t%v\t%v\n", l.name, l.s.GetCount(),
+			time.Duration(l.s.GetMeanNs()), time.Duration(l.s.GetP50Ns()),
+			time.Duration(l.s.GetP99Ns()), time.Duration(l.s.GetMaxNs()))
+	}
+	w.Flush()
+}
+
+func runPeers(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	if _, err := parseArgs(flag.NewFlagSet("peers", flag.ContinueOnError), args, 0, 0); err != nil {
+		return err
+	}
+	resp, err := c.Peers(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ADDR\tBREAKER\tWEIGHT\tZONE\tVERSION")
+	for _, p := range resp.GetPeers() {
+		addr := p.GetAddr()
+		if addr == resp.GetSelf() {
+			addr += " (self)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", addr, p.GetBreaker(), p.GetWeight(), p.GetZone(), p.GetVersion())
+	}
+	return w.Flush()
+}
+
+func runWatch(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("interval", time.Second, "轮询间隔")
+	args, err := parseArgs(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return usageError{}
+	}
+	return watchKey(ctx, c.Get, args[0], args[1], *interval, out)
+}
+
+// watchKey 轮询key，首次读取和值变化（包括出现、消失）时输出一行
+func watchKey(ctx context.Context, get func(ctx context.Context, group, key string) ([]byte, error),
+	group, key string, interval time.Duration, out io.Writer) error {
+	var last []byte
+	present, first := false, true
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		value, err := get(ctx, group, key)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, blockcache.ErrNotFound):
+			if present || first {
+				fmt.Fprintf(out, "%s\t<not found>\n", time.Now().Format(time.RFC3339))
+			}
+			present = false
+		case err != nil:
+			fmt.Fprintf(out, "%s\t<error: %v>\n", time.Now().Format(time.RFC3339), err)
+		case !present || !bytes.Equal(value, last):
+			fmt.Fprintf(out, "%s\t%s\n", time.Now().Format(time.RFC3339), value)
+			last, present = value, true
+		}
+		first = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// dumpRecord dump文件中的一行
+type dumpRecord struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+	TTLMs int64  `json:"ttl_ms,omitempty"`
+}
+
+func runDump(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("dump", flag.ContinueOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	w := out
+	if len(args) == 2 && args[1] != "-" {
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	dumped := 0
+	err = c.Dump(ctx, args[0], func(e blockcache.HandoffEntry) error {
+		dumped++
+		return enc.Encode(dumpRecord{Key: e.Key, Value: e.Value, TTLMs: e.TTL.Milliseconds()})
+	})
+	if err != nil {
+		return err
+	}
+	if w != out {
+		fmt.Fprintf(out, "dumped %d keys\n", dumped)
+	}
+	return bw.Flush()
+}
+
+// restoreBatch 每次Handoff发送的条数
+const restoreBatch = 500
+
+func runRestore(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("restore", flag.ContinueOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	file := "-"
+	if len(args) == 2 {
+		file = args[1]
+	}
+	in, closeIn, err := openInput(file)
+	if err != nil {
+		return err
+	}
+	defer closeIn()
+
+	read, accepted := 0, 0
+	batch := make([]blockcache.HandoffEntry, 0, restoreBatch)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		n, err := c.Handoff(ctx, args[0], batch)
+		accepted += n
+		batch = batch[:0]
+		return err
+	}
+	err = scanDump(in, func(e blockcache.HandoffEntry) error {
+		read++
+		batch = append(batch, e)
+		if len(batch) == restoreBatch {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	fmt.Fprintf(out, "restored %d of %d keys (existing keys are kept)\n", accepted, read)
+	return err
+}
+
+// scanDump 逐行解析dump文件
+func scanDump(r io.Reader, fn func(blockcache.HandoffEntry) error) error {
+	dec := json.NewDecoder(r)
+	for {
+		var rec dumpRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("invalid dump record: %w", err)
+		}
+		if err := fn(blockcache.HandoffEntry{
+			Key:   rec.Key,
+			Value: rec.Value,
+			TTL:   time.Duration(rec.TTLMs) * time.Millisecond,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+// openInput 打开文件，"-"表示标准输入
+func openInput(name string) (io.Reader, func(), error) {
+	if name == "-" {
+		return os.Stdin, func() {}, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, func() { f.Close() }, nil
+}
+
+func runCreateGroup(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("create-group", flag.ContinueOnError)
+	maxBytes := fs.Int64("max-bytes", 0, "每个节点上的容量上限（字节）")
+	ttl := fs.Duration("ttl", 0, "默认过期时间，0表示不过期")
+	cacheType := fs.String("type", "", "缓存类型，lru或lru2")
+	getter := fs.String("getter", "", `数据源配置（JSON，blockcache-server只接受{"origin":"名称"}），以@开头时从文件读取`)
+	args, err := parseArgs(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	def := registry.GroupDefinition{Name: args[0], MaxBytes: *maxBytes, TTL: *ttl, CacheType: *cacheType}
+	if *getter != "" {
+		raw := []byte(*getter)
+		if name, ok := strings.CutPrefix(*getter, "@"); ok {
+			if raw, err = os.ReadFile(name); err != nil {
+				return err
+			}
+		}
+		if !json.Valid(raw) {
+			return errors.New("getter is not valid JSON")
+		}
+		def.Getter = raw
+	}
+	if err := c.CreateGroup(ctx, def); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "group %s created\n", def.Name)
+	return nil
+}
+
+func runResizeGroup(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("resize-group", flag.ContinueOnError)
+	cacheType := fs.String("type", "", "迁移到的缓存类型，lru或lru2")
+	args, err := parseArgs(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	var maxBytes int64
+	if len(args) == 2 {
+		if maxBytes, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+			return usageError{}
+		}
+	}
+	if maxBytes == 0 && *cacheType == "" {
+		return usageError{}
+	}
+	def, err := c.ResizeGroup(ctx, args[0], maxBytes, *cacheType)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "group %s: max bytes %d, cache type %s\n", def.Name, def.MaxBytes, def.CacheType)
+	return nil
+}
+
+func runDestroyGroup(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error {
+	args, err := parseArgs(flag.NewFlagSet("destroy-group", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	existed, err := c.DestroyGroup(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if !existed {
+		return fmt.Errorf("group %s has no definition", args[0])
+	}
+	fmt.Fprintf(out, "group %s destroyed\n", args[0])
+	return nil
+}
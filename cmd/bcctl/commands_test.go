@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	blockcache "github.com/crypt0walker/BlockCache"
+	"github.com/crypt0walker/BlockCache/pb"
+	"google.golang.org/grpc"
+)
+
+// startNode 启动不依赖etcd的节点并返回连接它的Client
+func startNode(t *testing.T) *blockcache.Client {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterBlockCacheServer(gs, &blockcache.Server{})
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	client, err := blockcache.NewClient(lis.Addr().String(), "bcctl", nil,
+		blockcache.WithExternalClient(), blockcache.WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func newGroup(t *testing.T, name string) *blockcache.Group {
+	g := blockcache.NewGroup(name, 1<<20, blockcache.GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, blockcache.ErrNotFound
+	}))
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// TestImportDumpRestore 批量导入后导出，再恢复到另一个组
+func TestImportDumpRestore(t *testing.T) {
+	newGroup(t, "bcctl-src")
+	dst := newGroup(t, "bcctl-dst")
+	client := startNode(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	input := filepath.Join(dir, "input.tsv")
+	os.WriteFile(input, []byte("# users\nalice\t1\n\nbob\t2\tx\n"), 0o644)
+	var out bytes.Buffer
+	if err := runImport(ctx, client, []string{"-ttl", "1h", "bcctl-src", input}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "imported 2 keys") {
+		t.Fatalf("import output %q", out.String())
+	}
+
+	dump := filepath.Join(dir, "dump.jsonl")
+	out.Reset()
+	if err := runDump(ctx, client, []string{"bcctl-src", dump}, &out); err != nil {
+		t.Fatal(err)
+	}
+	out.Reset()
+	if err := runRestore(ctx, client, []string{"bcctl-dst", dump}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "restored 2 of 2") {
+		t.Fatalf("restore output %q", out.String())
+	}
+	// value中的分隔符原样保留
+	if v, err := dst.Get(ctx, "bob"); err != nil || v.String() != "2\tx" {
+		t.Fatalf("restored bob = %q, %v", v.String(), err)
+	}
+
+	out.Reset()
+	if err := runStats(ctx, client, []string{"bcctl-dst"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "items") {
+		t.Fatalf("stats output %q", out.String())
+	}
+}
+
+// TestScanImport_Errors 缺少分隔符时报告行号
+func TestScanImport_Errors(t *testing.T) {
+	err := scanImport(strings.NewReader("a\t1\nbroken\n"), "\t", func(string, []byte) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// TestWatchKey 只在值变化、出现和消失时输出
+func TestWatchKey(t *testing.T) {
+	values := []string{"", "a", "a", "b", ""}
+	i := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	get := func(ctx context.Context, group, key string) ([]byte, error) {
+		v := values[i]
+		i++
+		if i == len(values) {
+			cancel()
+		}
+		if v == "" {
+			return nil, fmt.Errorf("get: %w", blockcache.ErrNotFound)
+		}
+		return []byte(v), nil
+	}
+
+	var out bytes.Buffer
+	if err := watchKey(ctx, get, "g", "k", time.Millisecond, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	var got []string
+	for _, l := range lines {
+		got = append(got, l[strings.Index(l, "\t")+1:])
+	}
+	want := []string{"<not found>", "a", "b"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("watch output = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+// bcctl 通过gRPC访问BlockCache节点的命令行工具
+//
+// 用法：
+//
+//	bcctl [全局参数] <命令> [参数]
+//
+// 命令：
+//
+//	get <group> <key>                         读取
+//	set [-ttl 1m] <group> <key> <value|->     写入，value为-时从标准输入读取
+//	del <group> <key>                         删除
+//	import [-ttl 1m] [-sep TAB] <group> <file|->  批量写入，每行一个 key<sep>value
+//	groups                                    列出节点上的缓存组
+//	stats <group>                             显示组的统计信息
+//	peers                                     显示节点发现的其他节点
+//	watch [-interval 1s] <group> <key>        轮询key，值变化时输出
+//	dump <group> [file]                       以JSON Lines导出组的所有项
+//	restore <group> [file]                    导入dump的结果（不覆盖节点上已有的key）
+//	create-group -max-bytes N [-ttl 0] [-type lru2] [-getter JSON|@file] <group>
+//	                                          在集群中创建缓存组（需要节点开启组定义注册表）
+//	resize-group [-type lru] <group> [maxBytes]
+//	                                          在线调整组的容量上限或存储类型
+//	destroy-group <group>                     在集群中销毁缓存组
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	blockcache "github.com/crypt0walker/BlockCache"
+	"github.com/sirupsen/logrus"
+)
+
+// command 一个子命令
+type command struct {
+	usage string
+	run   func(ctx context.Context, c *blockcache.Client, args []string, out io.Writer) error
+}
+
+var commands = map[string]command{
+	"get":     {"get <group> <key>", runGet},
+	"set":     {"set [-ttl 1m] <group> <key> <value|->", runSet},
+	"del":     {"del <group> <key>", runDel},
+	"import":  {"import [-ttl 1m] [-sep TAB] <group> <file|->", runImport},
+	"groups":  {"groups", runGroups},
+	"stats":   {"stats <group>", runStats},
+	"peers":   {"peers", runPeers},
+	"watch":   {"watch [-interval 1s] <group> <key>", runWatch},
+	"dump":    {"dump <group> [file]", runDump},
+	"restore": {"restore <group> [file]", runRestore},
+
+	"create-group":  {"create-group -max-bytes N [-ttl 0] [-type lru2] [-getter JSON|@file] <group>", runCreateGroup},
+	"resize-group":  {"resize-group [-type lru] <group> [maxBytes]", runResizeGroup},
+	"destroy-group": {"destroy-group <group>", runDestroyGroup},
+}
+
+// commandOrder 帮助中命令的显示顺序
+var commandOrder = []string{"get", "set", "del", "import", "groups", "stats", "peers", "watch", "dump", "restore", "create-group", "resize-group", "destroy-group"}
+
+func main() {
+	addr := flag.String("addr", "localhost:8001", "节点的gRPC地址")
+	timeout := flag.Duration("timeout", 5*time.Second, "连接和单次请求的超时")
+	token := flag.String("token", "", "Bearer Token")
+	hmacID := flag.String("hmac-id", "", "HMAC签名使用的身份")
+	hmacSecret := flag.String("hmac-secret", "", "HMAC签名使用的密钥")
+	caFile := flag.String("ca", "", "校验节点证书的CA文件，设置后使用TLS")
+	certFile := flag.String("cert", "", "客户端证书（mTLS）")
+	keyFile := flag.String("key", "", "客户端私钥（mTLS）")
+	serverName := flag.String("server-name", "", "校验节点证书时使用的名称")
+	verbose := flag.Bool("v", false, "输出客户端日志")
+	flag.Usage = usage
+	flag.Parse()
+
+	if !*verbose {
+		logrus.SetLevel(logrus.WarnLevel)
+	}
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "bcctl: unknown command %q\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	opts := []blockcache.ClientOption{
+		blockcache.WithExternalClient(),
+		blockcache.WithClientDialTimeout(*timeout),
+		blockcache.WithRPCTimeouts(*timeout, *timeout, *timeout),
+	}
+	if *caFile != "" || *certFile != "" {
+		opts = append(opts, blockcache.WithClientTLS(blockcache.TLSConfig{
+			CAFile:     *caFile,
+			CertFile:   *certFile,
+			KeyFile:    *keyFile,
+			ServerName: *serverName,
+		}))
+	}
+	switch {
+	case *token != "":
+		opts = append(opts, blockcache.WithClientAuth(blockcache.BearerToken(*token)))
+	case *hmacID != "":
+		opts = append(opts, blockcache.WithClientAuth(blockcache.HMACSigner(*hmacID, []byte(*hmacSecret))))
+	}
+
+	client, err := blockcache.NewClient(*addr, "bcctl", nil, opts...)
+	if err != nil {
+		fatal(err)
+	}
+	defer client.Close()
+
+	// Ctrl-C结束watch等长时间运行的命令
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := cmd.run(ctx, client, flag.Args()[1:], os.Stdout); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "usage: bcctl %s\n", cmd.usage)
+			os.Exit(2)
+		}
+		client.Close()
+		fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: bcctl [flags] <command> [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(os.Stderr, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nflags:")
+	flag.PrintDefaults()
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "bcctl: %v\n", err)
+	os.Exit(1)
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	blockcache "github.com/crypt0walker/BlockCache"
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/crypt0walker/BlockCache/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 节点配置，按文件扩展名以YAML、TOML或JSON解析
+type Config struct {
+	Addr        string `yaml:"addr" toml:"addr" json:"addr"`                         // gRPC监听地址，同时作为注册到etcd的地址
+	ServiceName string `yaml:"service_name" toml:"service_name" json:"service_name"` // 服务名，同一集群的节点必须一致
+
+	Discovery DiscoveryConfig `yaml:"discovery" toml:"discovery" json:"discovery"`
+	Groups    []GroupConfig   `yaml:"groups" toml:"groups" json:"groups"`
+	// GroupRegistry 开启后节点按etcd中的组定义创建组，并提供CreateGroup/ResizeGroup/DestroyGroup接口，
+	// 必须同时配置auth；定义中的getter字段只能引用origins中的数据源，如{"origin":"users"}
+	GroupRegistry bool `yaml:"group_registry" toml:"group_registry" json:"group_registry"`
+	// Origins 命名的数据源，格式与groups[].getter相同
+	Origins map[string]*GetterConfig `yaml:"origins" toml:"origins" json:"origins"`
+	// Auth 认证与授权，gRPC、HTTP接口和节点间请求都按它校验
+	Auth *AuthConfig `yaml:"auth" toml:"auth" json:"auth"`
+	// Memory 非空时所有组共享一个总内存预算，各组的max_bytes作为各自预算的上限
+	Memory *MemoryConfig `yaml:"memory" toml:"memory" json:"memory"`
+	TLS    *TLSConfig    `yaml:"tls" toml:"tls" json:"tls"`
+
+	MetricsAddr string `yaml:"metrics_addr" toml:"metrics_addr" json:"metrics_addr"` // 非空时提供/metrics
+	HTTPAddr    string `yaml:"http_addr" toml:"http_addr" json:"http_addr"`          // 非空时提供HTTP/JSON接口
+
+	Metadata registry.Metadata `yaml:"metadata" toml:"metadata" json:"metadata"`
+
+	// ShutdownTimeout 收到SIGTERM后等待请求处理完、从etcd注销的最长时间
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout"`
+}
+
+// DiscoveryConfig 服务发现配置
+type DiscoveryConfig struct {
+	Backend     string   `yaml:"backend" toml:"backend" json:"backend"` // 目前只支持etcd
+	Endpoints   []string `yaml:"endpoints" toml:"endpoints" json:"endpoints"`
+	DialTimeout Duration `yaml:"dial_timeout" toml:"dial_timeout" json:"dial_timeout"`
+}
+
+// MemoryConfig 进程范围的内存预算
+type MemoryConfig struct {
+	MaxBytes ByteSize `yaml:"max_bytes" toml:"max_bytes" json:"max_bytes"` // 所有组的总预算
+	Policy   string   `yaml:"policy" toml:"policy" json:"policy"`          // hit_rate（默认）或weight
+	Interval Duration `yaml:"interval" toml:"interval" json:"interval"`    // 重新分配的间隔，默认10s
+	MinBytes ByteSize `yaml:"min_bytes" toml:"min_bytes" json:"min_bytes"` // 每个组至少分到的预算，默认1MB
+}
+
+func (m *MemoryConfig) governorOptions() blockcache.MemoryGovernorOptions {
+	return blockcache.MemoryGovernorOptions{
+		MaxBytes: int64(m.MaxBytes),
+		Policy:   blockcache.MemoryPolicy(m.Policy),
+		Interval: time.Duration(m.Interval),
+		MinBytes: int64(m.MinBytes),
+	}
+}
+
+// AuthConfig 认证与授权，凭证可以引用环境变量，如${ADMIN_TOKEN}
+type AuthConfig struct {
+	Tokens      map[string]string `yaml:"tokens" toml:"tokens" json:"tokens"`                   // 身份到Bearer Token的映射
+	HMACSecrets map[string]string `yaml:"hmac_secrets" toml:"hmac_secrets" json:"hmac_secrets"` // 身份到HMAC密钥的映射
+	// MTLS 以客户端证书的CommonName或SAN作为身份，需要tls.require_client_cert
+	MTLS           bool `yaml:"mtls" toml:"mtls" json:"mtls"`
+	AllowAnonymous bool `yaml:"allow_anonymous" toml:"allow_anonymous" json:"allow_anonymous"`
+	// PeerIdentity 访问其他节点时使用的身份，凭证取自tokens或hmac_secrets；只用mTLS时可以不填
+	PeerIdentity string          `yaml:"peer_identity" toml:"peer_identity" json:"peer_identity"`
+	ACL          []ACLRuleConfig `yaml:"acl" toml:"acl" json:"acl"`
+}
+
+// ACLRuleConfig 一条授权规则，"*"匹配任意身份、组或操作
+type ACLRuleConfig struct {
+	Identity string   `yaml:"identity" toml:"identity" json:"identity"`
+	Groups   []string `yaml:"groups" toml:"groups" json:"groups"`
+	Ops      []string `yaml:"ops" toml:"ops" json:"ops"` // get、set、delete、handoff、admin或*
+}
+
+// build 创建服务端的认证配置和访问其他节点时使用的凭证（可为nil）
+func (a *AuthConfig) build() (blockcache.AuthOptions, blockcache.ClientAuth, error) {
+	var opts blockcache.AuthOptions
+	tokens := make(map[string]string, len(a.Tokens))
+	for identity, token := range a.Tokens {
+		if token = os.ExpandEnv(token); token == "" {
+			return opts, nil, fmt.Errorf("auth: token of %s is empty", identity)
+		}
+		tokens[token] = identity
+	}
+	secrets := make(map[string][]byte, len(a.HMACSecrets))
+	for identity, secret := range a.HMACSecrets {
+		if secret = os.ExpandEnv(secret); secret == "" {
+			return opts, nil, fmt.Errorf("auth: hmac secret of %s is empty", identity)
+		}
+		secrets[identity] = []byte(secret)
+	}
+
+	if len(tokens) > 0 {
+		opts.Authenticators = append(opts.Authenticators, blockcache.NewStaticTokenAuth(tokens))
+	}
+	if len(secrets) > 0 {
+		opts.Authenticators = append(opts.Authenticators, blockcache.NewHMACAuth(secrets, 0))
+	}
+	if a.MTLS {
+		opts.Authenticators = append(opts.Authenticators, blockcache.MTLSAuth{})
+	}
+	opts.AllowAnonymous = a.AllowAnonymous
+	if len(a.ACL) > 0 {
+		opts.ACL = &blockcache.ACL{}
+		for _, r := range a.ACL {
+			rule := blockcache.ACLRule{Identity: r.Identity, Groups: r.Groups}
+			for _, op := range r.Ops {
+				rule.Ops = append(rule.Ops, blockcache.Operation(op))
+			}
+			opts.ACL.Rules = append(opts.ACL.Rules, rule)
+		}
+	}
+
+	var peerAuth blockcache.ClientAuth
+	if id := a.PeerIdentity; id != "" {
+		if token, ok := a.Tokens[id]; ok {
+			peerAuth = blockcache.BearerToken(os.ExpandEnv(token))
+		} else {
+			peerAuth = blockcache.HMACSigner(id, secrets[id])
+		}
+	}
+	return opts, peerAuth, nil
+}
+
+func (a *AuthConfig) validate(tls *TLSConfig) error {
+	if len(a.Tokens) == 0 && len(a.HMACSecrets) == 0 && !a.MTLS {
+		return errors.New("auth: at least one of tokens, hmac_secrets or mtls is required")
+	}
+	if a.MTLS && (tls == nil || !tls.RequireClientCert) {
+		return errors.New("auth: mtls requires tls.require_client_cert")
+	}
+	if a.AllowAnonymous && len(a.ACL) == 0 {
+		return errors.New("auth: allow_anonymous requires acl")
+	}
+	if id := a.PeerIdentity; id != "" {
+		_, hasToken := a.Tokens[id]
+		_, hasSecret := a.HMACSecrets[id]
+		if !hasToken && !hasSecret {
+			return fmt.Errorf("auth: peer_identity %q has no token or hmac secret", id)
+		}
+	} else if !a.MTLS {
+		return errors.New("auth: peer_identity is required unless mtls is enabled")
+	}
+	for i, r := range a.ACL {
+		if r.Identity == "" {
+			return fmt.Errorf("auth: acl[%d]: identity is required", i)
+		}
+		for _, op := range r.Ops {
+			switch blockcache.Operation(op) {
+			case blockcache.OpGet, blockcache.OpSet, blockcache.OpDelete, blockcache.OpHandoff, blockcache.OpAdmin, "*":
+			default:
+				return fmt.Errorf("auth: acl[%d]: unknown op %q", i, op)
+			}
+		}
+	}
+	return nil
+}
+
+// GroupConfig 一个缓存组
+type GroupConfig struct {
+	Name     string   `yaml:"name" toml:"name" json:"name"`
+	MaxBytes ByteSize `yaml:"max_bytes" toml:"max_bytes" json:"max_bytes"`
+	TTL      Duration `yaml:"ttl" toml:"ttl" json:"ttl"`       // 0表示不过期
+	Store    string   `yaml:"store" toml:"store" json:"store"` // lru或lru2，默认lru2
+	// Weight 配置了memory时组在总预算中的权重，默认1
+	Weight float64 `yaml:"weight" toml:"weight" json:"weight"`
+
+	BucketCount     uint16   `yaml:"bucket_count" toml:"bucket_count" json:"bucket_count"`
+	CapPerBucket    uint16   `yaml:"cap_per_bucket" toml:"cap_per_bucket" json:"cap_per_bucket"`
+	Level2Cap       uint16   `yaml:"level2_cap" toml:"level2_cap" json:"level2_cap"`
+	CleanupInterval Duration `yaml:"cleanup_interval" toml:"cleanup_interval" json:"cleanup_interval"`
+
+	// Getter 未命中时的数据源，不配置时组只保存通过Set写入的数据
+	Getter *GetterConfig `yaml:"getter" toml:"getter" json:"getter"`
+}
+
+// GetterConfig 内置数据源，Type决定使用哪些字段
+type GetterConfig struct {
+	Type string `yaml:"type" toml:"type" json:"type"` // http、file或grpc
+
+	// http：URL模板中{key}替换为转义后的key；请求头的值可以引用环境变量，如${ORIGIN_TOKEN}
+	URL     string            `yaml:"url" toml:"url" json:"url"`
+	Headers map[string]string `yaml:"headers" toml:"headers" json:"headers"`
+
+	// file：key为Dir下的相对路径，Extension拼接在key后面
+	Dir       string `yaml:"dir" toml:"dir" json:"dir"`
+	Extension string `yaml:"extension" toml:"extension" json:"extension"`
+
+	// grpc：实现了pb.Origin服务的数据源；元数据的值同样可以引用环境变量
+	Addr     string            `yaml:"addr" toml:"addr" json:"addr"`
+	TLS      *TLSConfig        `yaml:"tls" toml:"tls" json:"tls"`
+	Metadata map[string]string `yaml:"metadata" toml:"metadata" json:"metadata"`
+
+	Timeout  Duration `yaml:"timeout" toml:"timeout" json:"timeout"`       // 单次回源超时
+	MaxBytes ByteSize `yaml:"max_bytes" toml:"max_bytes" json:"max_bytes"` // 单个值的大小上限，默认4MB
+}
+
+// build 按配置创建Getter，返回的closer在节点退出时调用（可为nil）
+func (c *GetterConfig) build(group string) (blockcache.Getter, io.Closer, error) {
+	switch c.Type {
+	case "http":
+		header := make(http.Header, len(c.Headers))
+		for name, value := range c.Headers {
+			header.Set(name, os.ExpandEnv(value))
+		}
+		g, err := blockcache.NewHTTPGetter(blockcache.HTTPGetterOptions{
+			URL:      c.URL,
+			Header:   header,
+			Timeout:  time.Duration(c.Timeout),
+			MaxBytes: int64(c.MaxBytes),
+		})
+		return g, nil, err
+	case "file":
+		g, err := blockcache.NewFileGetter(blockcache.FileGetterOptions{
+			Dir:       c.Dir,
+			Extension: c.Extension,
+			MaxBytes:  int64(c.MaxBytes),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return g, g, nil
+	case "grpc":
+		md := make(map[string]string, len(c.Metadata))
+		for name, value := range c.Metadata {
+			md[name] = os.ExpandEnv(value)
+		}
+		opts := blockcache.GRPCGetterOptions{
+			Addr:     c.Addr,
+			Group:    group,
+			Timeout:  time.Duration(c.Timeout),
+			Metadata: md,
+		}
+		if c.TLS != nil {
+			tls := c.TLS.blockcache()
+			opts.TLS = &tls
+		}
+		g, err := blockcache.NewGRPCGetter(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return g, g, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown getter type %q", c.Type)
+	}
+}
+
+// originRef 组定义中getter字段的格式
+type originRef struct {
+	Origin string `json:"origin"`
+}
+
+// getterFactory 按组定义引用的命名数据源创建Getter，供组定义注册表使用；
+// 定义来自远程调用方，只能选择origins中配置好的数据源，不能自带URL、目录、请求头或环境变量
+func (c *Config) getterFactory(def registry.GroupDefinition) (blockcache.Getter, error) {
+	if len(def.Getter) == 0 {
+		return notFoundGetter, nil
+	}
+	var ref originRef
+	dec := json.NewDecoder(bytes.NewReader(def.Getter))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ref); err != nil {
+		return nil, fmt.Errorf(`invalid getter, want {"origin": name}: %w`, err)
+	}
+	origin, ok := c.Origins[ref.Origin]
+	if !ok {
+		return nil, fmt.Errorf("unknown origin %q", ref.Origin)
+	}
+	// 实现了io.Closer的数据源由注册表在组销毁时关闭
+	g, _, err := origin.build(def.Name)
+	return g, err
+}
+
+func (c *GetterConfig) validate() error {
+	switch c.Type {
+	case "http":
+		if c.URL == "" {
+			return errors.New("http getter requires url")
+		}
+	case "file":
+		if c.Dir == "" {
+			return errors.New("file getter requires dir")
+		}
+	case "grpc":
+		if c.Addr == "" {
+			return errors.New("grpc getter requires addr")
+		}
+	default:
+		return fmt.Errorf("unknown getter type %q, want http, file or grpc", c.Type)
+	}
+	return nil
+}
+
+// TLSConfig 节点间TLS，同时用于Server和连接其他节点的Client
+type TLSConfig struct {
+	CertFile          string   `yaml:"cert_file" toml:"cert_file" json:"cert_file"`
+	KeyFile           string   `yaml:"key_file" toml:"key_file" json:"key_file"`
+	CAFile            string   `yaml:"ca_file" toml:"ca_file" json:"ca_file"`
+	ServerName        string   `yaml:"server_name" toml:"server_name" json:"server_name"`
+	RequireClientCert bool     `yaml:"require_client_cert" toml:"require_client_cert" json:"require_client_cert"`
+	AllowedSANs       []string `yaml:"allowed_sans" toml:"allowed_sans" json:"allowed_sans"`
+	ReloadInterval    Duration `yaml:"reload_interval" toml:"reload_interval" json:"reload_interval"`
+}
+
+func (c *TLSConfig) blockcache() blockcache.TLSConfig {
+	return blockcache.TLSConfig{
+		CertFile:          c.CertFile,
+		KeyFile:           c.KeyFile,
+		CAFile:            c.CAFile,
+		ServerName:        c.ServerName,
+		RequireClientCert: c.RequireClientCert,
+		AllowedSANs:       c.AllowedSANs,
+		ReloadInterval:    time.Duration(c.ReloadInterval),
+	}
+}
+
+// cacheOptions 在默认配置上覆盖配置文件中给出的字段
+func (g GroupConfig) cacheOptions() blockcache.CacheOptions {
+	opts := blockcache.DefaultCacheOptions()
+	opts.CacheType = store.CacheType(g.Store)
+	opts.MaxBytes = int64(g.MaxBytes)
+	if g.BucketCount > 0 {
+		opts.BucketCount = g.BucketCount
+	}
+	if g.CapPerBucket > 0 {
+		opts.CapPerBucket = g.CapPerBucket
+	}
+	if g.Level2Cap > 0 {
+		opts.Level2Cap = g.Level2Cap
+	}
+	if g.CleanupInterval > 0 {
+		opts.CleanupTime = time.Duration(g.CleanupInterval)
+	}
+	return opts
+}
+
+// Duration 以"30s"、"5m"这样的字符串书写的时间
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// ByteSize 字节数，可以写成整数或带单位的字符串，如"64MB"、"1GiB"
+type ByteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	n      int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(strings.TrimSpace(string(text)))
+	mult := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.n
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid size %q", text)
+	}
+	*b = ByteSize(n * mult)
+	return nil
+}
+
+// UnmarshalJSON JSON中既可以写数字也可以写字符串
+func (b *ByteSize) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return b.UnmarshalText([]byte(s))
+	}
+	return b.UnmarshalText(data)
+}
+
+// UnmarshalTOML TOML中既可以写整数也可以写字符串
+func (b *ByteSize) UnmarshalTOML(v any) error {
+	switch v := v.(type) {
+	case int64:
+		if v < 0 {
+			return fmt.Errorf("invalid size %d", v)
+		}
+		*b = ByteSize(v)
+		return nil
+	case string:
+		return b.UnmarshalText([]byte(v))
+	default:
+		return fmt.Errorf("invalid size %v", v)
+	}
+}
+
+// LoadConfig 读取并校验配置文件
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("parse %s: unknown field %q", path, undecoded[0].String())
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q, want .yaml, .toml or .json", ext)
+	}
+	cfg.setDefaults()
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) setDefaults() {
+	if c.ServiceName == "" {
+		c.ServiceName = "block-cache"
+	}
+	if c.Discovery.Backend == "" {
+		c.Discovery.Backend = "etcd"
+	}
+	if len(c.Discovery.Endpoints) == 0 {
+		c.Discovery.Endpoints = registry.DefaultConfig.Endpoints
+	}
+	if c.Discovery.DialTimeout == 0 {
+		c.Discovery.DialTimeout = Duration(registry.DefaultConfig.DialTimeout)
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = Duration(15 * time.Second)
+	}
+	for i := range c.Groups {
+		if c.Groups[i].Store == "" {
+			c.Groups[i].Store = string(store.LRU2)
+		}
+	}
+}
+
+func (c *Config) validate() error {
+	if c.Addr == "" {
+		return errors.New("addr is required")
+	}
+	if c.Discovery.Backend != "etcd" {
+		return fmt.Errorf("unsupported discovery backend %q", c.Discovery.Backend)
+	}
+	if len(c.Groups) == 0 {
+		return errors.New("at least one group is required")
+	}
+	seen := make(map[string]bool, len(c.Groups))
+	for i, g := range c.Groups {
+		switch {
+		case g.Name == "":
+			return fmt.Errorf("groups[%d]: name is required", i)
+		case seen[g.Name]:
+			return fmt.Errorf("groups[%d]: duplicate group %q", i, g.Name)
+		case g.MaxBytes <= 0:
+			return fmt.Errorf("group %q: max_bytes must be positive", g.Name)
+		case g.TTL < 0:
+			return fmt.Errorf("group %q: ttl must not be negative", g.Name)
+		case g.Weight < 0:
+			return fmt.Errorf("group %q: weight must not be negative", g.Name)
+		case g.Store != string(store.LRU) && g.Store != string(store.LRU2):
+			return fmt.Errorf("group %q: unknown store %q", g.Name, g.Store)
+		}
+		if g.Getter != nil {
+			if err := g.Getter.validate(); err != nil {
+				return fmt.Errorf("group %q: %w", g.Name, err)
+			}
+		}
+		seen[g.Name] = true
+	}
+	for name, o := range c.Origins {
+		if o == nil {
+			return fmt.Errorf("origin %q: type is required", name)
+		}
+		if err := o.validate(); err != nil {
+			return fmt.Errorf("origin %q: %w", name, err)
+		}
+	}
+	if c.Auth != nil {
+		if err := c.Auth.validate(c.TLS); err != nil {
+			return err
+		}
+	}
+	if c.GroupRegistry && c.Auth == nil {
+		return errors.New("group_registry requires auth: the group management RPCs must not be open to anyone who can reach the node")
+	}
+	if c.Memory != nil {
+		if c.Memory.MaxBytes <= 0 {
+			return errors.New("memory: max_bytes must be positive")
+		}
+		if p := blockcache.MemoryPolicy(c.Memory.Policy); p != "" && p != blockcache.PolicyHitRate && p != blockcache.PolicyWeight {
+			return fmt.Errorf("memory: unknown policy %q", c.Memory.Policy)
+		}
+	}
+	if c.TLS != nil && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return errors.New("tls: cert_file and key_file are required")
+	}
+	return nil
+}
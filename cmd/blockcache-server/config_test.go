@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/crypt0walker/BlockCache/store"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestLoadConfig_Formats 三种格式解析出相同的配置
+func TestLoadConfig_Formats(t *testing.T) {
+	files := map[string]string{
+		"node.yaml": `
+addr: 127.0.0.1:8001
+discovery:
+  endpoints: [etcd-1:2379, etcd-2:2379]
+groups:
+  - name: users
+    max_bytes: 64MB
+    ttl: 10m
+  - name: raw
+    max_bytes: 4096
+    store: lru
+`,
+		"node.toml": `
+addr = "127.0.0.1:8001"
+[discovery]
+endpoints = ["etcd-1:2379", "etcd-2:2379"]
+[[groups]]
+name = "users"
+max_bytes = "64MB"
+ttl = "10m"
+[[groups]]
+name = "raw"
+max_bytes = 4096
+store = "lru"
+`,
+		"node.json": `{
+  "addr": "127.0.0.1:8001",
+  "discovery": {"endpoints": ["etcd-1:2379", "etcd-2:2379"]},
+  "groups": [
+    {"name": "users", "max_bytes": "64MB", "ttl": "10m"},
+    {"name": "raw", "max_bytes": 4096, "store": "lru"}
+  ]
+}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ServiceName != "block-cache" || cfg.Discovery.Backend != "etcd" || len(cfg.Discovery.Endpoints) != 2 {
+				t.Fatalf("config = %+v", cfg)
+			}
+			users, raw := cfg.Groups[0], cfg.Groups[1]
+			if users.MaxBytes != 64<<20 || time.Duration(users.TTL) != 10*time.Minute || users.Store != string(store.LRU2) {
+				t.Fatalf("users = %+v", users)
+			}
+			if raw.MaxBytes != 4096 || raw.TTL != 0 || raw.cacheOptions().CacheType != store.LRU {
+				t.Fatalf("raw = %+v", raw)
+			}
+		})
+	}
+}
+
+// TestLoadConfig_Example 仓库中的示例配置可以通过校验
+func TestLoadConfig_Example(t *testing.T) {
+	if _, err := LoadConfig("example.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLoadConfig_Invalid 配置错误在启动前被发现
+func TestLoadConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"no addr", "a.yaml", "groups: [{name: g, max_bytes: 1KB}]", "addr is required"},
+		{"no groups", "a.yaml", "addr: :8001", "at least one group"},
+		{"duplicate", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: 1}, {name: g, max_bytes: 1}]", "duplicate group"},
+		{"zero size", "a.yaml", "addr: :8001\ngroups: [{name: g}]", "max_bytes must be positive"},
+		{"bad size", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: lots}]", "invalid size"},
+		{"bad store", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: 1, store: arc}]", "unknown store"},
+		{"backend", "a.yaml", "addr: :8001\ndiscovery: {backend: consul}\ngroups: [{name: g, max_bytes: 1}]", "unsupported discovery backend"},
+		{"unknown yaml field", "a.yaml", "addr: :8001\nmax_byte: 1", "not found"},
+		{"unknown toml field", "a.toml", "addr = \":8001\"\nlisten = \":1\"", "unknown field"},
+		{"unknown json field", "a.json", `{"addr": ":8001", "listen": ":1"}`, "unknown field"},
+		{"getter type", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: 1, getter: {type: s3}}]", "unknown getter type"},
+		{"getter url", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: 1, getter: {type: http}}]", "requires url"},
+		{"memory size", "a.yaml", "addr: :8001\nmemory: {policy: weight}\ngroups: [{name: g, max_bytes: 1}]", "memory: max_bytes"},
+		{"memory policy", "a.yaml", "addr: :8001\nmemory: {max_bytes: 1MB, policy: lfu}\ngroups: [{name: g, max_bytes: 1}]", "unknown policy"},
+		{"weight", "a.yaml", "addr: :8001\ngroups: [{name: g, max_bytes: 1, weight: -1}]", "weight must not be negative"},
+		{"format", "a.ini", "addr=:8001", "unsupported config format"},
+		{"registry without auth", "a.yaml", "addr: :8001\ngroup_registry: true\ngroups: [{name: g, max_bytes: 1}]", "group_registry requires auth"},
+		{"origin", "a.yaml", "addr: :8001\norigins: {web: {type: http}}\ngroups: [{name: g, max_bytes: 1}]", `origin "web"`},
+		{"auth empty", "a.yaml", "addr: :8001\nauth: {peer_identity: p}\ngroups: [{name: g, max_bytes: 1}]", "at least one of tokens"},
+		{"auth peer", "a.yaml", "addr: :8001\nauth: {tokens: {a: t}, peer_identity: p}\ngroups: [{name: g, max_bytes: 1}]", "has no token"},
+		{"auth op", "a.yaml", "addr: :8001\nauth: {tokens: {a: t}, peer_identity: a, acl: [{identity: a, ops: [read]}]}\ngroups: [{name: g, max_bytes: 1}]", "unknown op"},
+		{"auth mtls", "a.yaml", "addr: :8001\nauth: {mtls: true}\ngroups: [{name: g, max_bytes: 1}]", "require_client_cert"},
+		{"auth anonymous", "a.yaml", "addr: :8001\nauth: {tokens: {a: t}, peer_identity: a, allow_anonymous: true}\ngroups: [{name: g, max_bytes: 1}]", "requires acl"},
+	}
+	for _, tt := range tests {
+		_, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+// TestGetterConfig_Build 按配置创建的Getter可以回源
+func TestGetterConfig_Build(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "k.txt"), []byte("from file"), 0o644)
+	t.Setenv("ORIGIN_TOKEN", "secret")
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization") + " " + r.URL.Path))
+	}))
+	defer origin.Close()
+
+	path := writeConfig(t, "node.yaml", fmt.Sprintf(`
+addr: :8001
+groups:
+  - name: files
+    max_bytes: 1MB
+    getter: {type: file, dir: %q, extension: .txt}
+  - name: web
+    max_bytes: 1MB
+    getter:
+      type: http
+      url: %s/v1/{key}
+      headers: {Authorization: "Bearer ${ORIGIN_TOKEN}"}
+`, dir, origin.URL))
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"files": "from file", "web": "Bearer secret /v1/k"}
+	for _, gc := range cfg.Groups {
+		getter, closer, err := gc.Getter.build(gc.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if closer != nil {
+			defer closer.Close()
+		}
+		if v, err := getter.Get(context.Background(), "k"); err != nil || string(v) != want[gc.Name] {
+			t.Fatalf("%s: Get = %q, %v", gc.Name, v, err)
+		}
+	}
+}
+
+// TestGetterFactory 组定义只能按名字引用origins中的数据源
+func TestGetterFactory(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "k"), []byte("v"), 0o644)
+	cfg := &Config{Origins: map[string]*GetterConfig{
+		"files": {Type: "file", Dir: dir, MaxBytes: 1024},
+	}}
+
+	getter, err := cfg.getterFactory(registry.GroupDefinition{Name: "defined", Getter: json.RawMessage(`{"origin":"files"}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer getter.(io.Closer).Close()
+	if v, err := getter.Get(context.Background(), "k"); err != nil || string(v) != "v" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+
+	if _, err := cfg.getterFactory(registry.GroupDefinition{Name: "none"}); err != nil {
+		t.Fatal(err)
+	}
+	// 定义不能自带数据源配置，也不能引用未配置的数据源
+	for _, raw := range []string{
+		`{"origin":"web"}`,
+		fmt.Sprintf(`{"type":"file","dir":%q}`, dir),
+		`{"type":"http","url":"http://127.0.0.1/{key}","headers":{"X":"${HOME}"}}`,
+		`{"origin":"files","dir":"/"}`,
+	} {
+		if _, err := cfg.getterFactory(registry.GroupDefinition{Name: "bad", Getter: json.RawMessage(raw)}); err == nil {
+			t.Errorf("%s: expected error", raw)
+		}
+	}
+}
+
+// TestAuthConfig_Build 凭证从环境变量展开，节点间请求使用peer_identity的凭证
+func TestAuthConfig_Build(t *testing.T) {
+	t.Setenv("TEST_PEER_TOKEN", "peer-secret")
+	a := &AuthConfig{
+		Tokens:       map[string]string{"peer": "${TEST_PEER_TOKEN}"},
+		PeerIdentity: "peer",
+		ACL:          []ACLRuleConfig{{Identity: "peer", Groups: []string{"*"}, Ops: []string{"get"}}},
+	}
+	if err := a.validate(nil); err != nil {
+		t.Fatal(err)
+	}
+	opts, peerAuth, err := a.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.Authenticators) != 1 || opts.ACL == nil || len(opts.ACL.Rules) != 1 || peerAuth == nil {
+		t.Fatalf("opts = %+v, peerAuth = %v", opts, peerAuth)
+	}
+
+	t.Setenv("TEST_PEER_TOKEN", "")
+	if _, _, err := a.build(); err == nil {
+		t.Fatal("expected error for empty token")
+	}
+}
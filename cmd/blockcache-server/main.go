@@ -0,0 +1,224 @@
+// blockcache-server 按配置文件启动一个BlockCache节点
+//
+// 用法：
+//
+//	blockcache-server -config blockcache.yaml
+//
+// 配置文件可以是YAML、TOML或JSON（按扩展名区分），示例见example.yaml。
+// 节点启动后注册到etcd并发现其他节点；收到SIGTERM或SIGINT时停止接收新请求，
+// 等待进行中的请求完成、从etcd注销后退出。
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	blockcache "github.com/crypt0walker/BlockCache"
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	configPath := flag.String("config", "blockcache.yaml", "配置文件路径（.yaml、.toml或.json）")
+	check := flag.Bool("check", false, "只校验配置文件，不启动")
+	flag.Parse()
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		logrus.Fatalf("load config: %v", err)
+	}
+	if *check {
+		fmt.Printf("%s: ok\n", *configPath)
+		return
+	}
+	if err := run(cfg); err != nil {
+		logrus.Fatal(err)
+	}
+}
+
+// node 按配置创建的服务器、节点选择器和缓存组
+type node struct {
+	server  *blockcache.Server
+	picker  *blockcache.ClientPicker
+	groups  []*blockcache.Group
+	memory  *blockcache.MemoryGovernor // 未配置memory时为nil
+	getters []io.Closer                // 需要在退出时关闭的数据源
+	stopped chan struct{}              // 从etcd注销完成后关闭
+}
+
+// newNode 创建节点但不启动
+func newNode(cfg *Config) (*node, error) {
+	var pickerOpts []blockcache.PickerOption
+	pickerOpts = append(pickerOpts,
+		blockcache.WithServiceName(cfg.ServiceName),
+		blockcache.WithPickerEtcdEndpoints(cfg.Discovery.Endpoints, time.Duration(cfg.Discovery.DialTimeout)),
+	)
+	if cfg.TLS != nil {
+		pickerOpts = append(pickerOpts, blockcache.WithPickerTLS(cfg.TLS.blockcache()))
+	}
+	var serverOpts []blockcache.ServerOption
+	if cfg.Auth != nil {
+		authOpts, peerAuth, err := cfg.Auth.build()
+		if err != nil {
+			return nil, err
+		}
+		serverOpts = append(serverOpts, blockcache.WithAuth(authOpts))
+		if peerAuth != nil {
+			pickerOpts = append(pickerOpts, blockcache.WithClientOptions(blockcache.WithClientAuth(peerAuth)))
+		}
+	}
+	picker, err := blockcache.NewClientPicker(cfg.Addr, pickerOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("create peer picker: %w", err)
+	}
+
+	n := &node{picker: picker, stopped: make(chan struct{})}
+	if cfg.Memory != nil {
+		if n.memory, err = blockcache.NewMemoryGovernor(cfg.Memory.governorOptions()); err != nil {
+			picker.Close()
+			return nil, fmt.Errorf("create memory governor: %w", err)
+		}
+	}
+	serverOpts = append(serverOpts,
+		blockcache.WithEtcdEndpoints(cfg.Discovery.Endpoints),
+		blockcache.WithDialTimeout(time.Duration(cfg.Discovery.DialTimeout)),
+		blockcache.WithMetadata(cfg.Metadata),
+		blockcache.WithRegistrationHandler(n.onRegistrationChange),
+	)
+	if cfg.TLS != nil {
+		serverOpts = append(serverOpts, blockcache.WithServerTLS(cfg.TLS.blockcache()))
+	}
+	if cfg.MetricsAddr != "" {
+		serverOpts = append(serverOpts, blockcache.WithMetrics(cfg.MetricsAddr, picker))
+	}
+	if cfg.HTTPAddr != "" {
+		serverOpts = append(serverOpts, blockcache.WithHTTP(cfg.HTTPAddr, picker))
+	}
+	if cfg.GroupRegistry {
+		regOpts := blockcache.GroupRegistryOptions{
+			Peers:   picker,
+			Getters: cfg.getterFactory,
+		}
+		if n.memory != nil {
+			regOpts.GroupOptions = append(regOpts.GroupOptions, blockcache.WithMemoryGovernor(n.memory, 1))
+		}
+		serverOpts = append(serverOpts, blockcache.WithGroupRegistry(regOpts))
+	}
+	n.server, err = blockcache.NewServer(cfg.Addr, cfg.ServiceName, serverOpts...)
+	if err != nil {
+		picker.Close()
+		return nil, fmt.Errorf("create server: %w", err)
+	}
+
+	for _, gc := range cfg.Groups {
+		var getter blockcache.Getter = notFoundGetter
+		if gc.Getter != nil {
+			var closer io.Closer
+			if getter, closer, err = gc.Getter.build(gc.Name); err != nil {
+				n.close()
+				return nil, fmt.Errorf("group %s: create getter: %w", gc.Name, err)
+			}
+			if closer != nil {
+				n.getters = append(n.getters, closer)
+			}
+		}
+		groupOpts := []blockcache.GroupOption{
+			blockcache.WithCacheOptions(gc.cacheOptions()),
+			blockcache.WithExpiration(time.Duration(gc.TTL)),
+			blockcache.WIthPeers(picker),
+		}
+		if n.memory != nil {
+			groupOpts = append(groupOpts, blockcache.WithMemoryGovernor(n.memory, gc.Weight))
+		}
+		g := blockcache.NewGroup(gc.Name, int64(gc.MaxBytes), getter, groupOpts...)
+		n.groups = append(n.groups, g)
+	}
+	return n, nil
+}
+
+// notFoundGetter 没有配置数据源的组，数据只能通过Set写入
+var notFoundGetter = blockcache.GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+	return nil, blockcache.ErrNotFound
+})
+
+func (n *node) onRegistrationChange(state registry.State, err error) {
+	if err != nil {
+		logrus.Warnf("registration %s: %v", state, err)
+	} else {
+		logrus.Infof("registration %s", state)
+	}
+	if state == registry.StateStopped {
+		close(n.stopped)
+	}
+}
+
+// shutdown 停止服务并等待从etcd注销，超时后直接返回
+func (n *node) shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		n.server.Stop()
+		close(done)
+	}()
+
+	deadline := time.After(timeout)
+	select {
+	case <-done:
+	case <-deadline:
+		logrus.Warnf("server did not stop within %v", timeout)
+	}
+	select {
+	case <-n.stopped:
+	case <-deadline:
+		logrus.Warnf("deregistration did not finish within %v", timeout)
+	}
+
+	n.close()
+}
+
+// close 关闭节点选择器、内存预算、缓存组和数据源
+func (n *node) close() {
+	n.picker.Close()
+	if n.memory != nil {
+		n.memory.Close()
+	}
+	for _, g := range n.groups {
+		g.Close()
+	}
+	for _, c := range n.getters {
+		c.Close()
+	}
+}
+
+func run(cfg *Config) error {
+	n, err := newNode(cfg)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- n.server.Start()
+	}()
+	logrus.Infof("blockcache-server %s serving %d groups as %s", cfg.Addr, len(n.groups), cfg.ServiceName)
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		n.picker.Close()
+		return fmt.Errorf("server exited: %w", err)
+	case sig := <-sigCh:
+		logrus.Infof("received %v, shutting down", sig)
+	}
+	n.shutdown(time.Duration(cfg.ShutdownTimeout))
+	logrus.Info("shutdown complete")
+	return nil
+}
@@ -0,0 +1,233 @@
+package consistenthash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultEpsilon 有界负载的默认松弛系数，节点负载上限为(1+ε)·平均负载
+const DefaultEpsilon = 0.25
+
+// LoadTracker 由需要感知在途请求数的路由器实现
+// 调用方在向Get选出的节点发请求前调用Inc，请求结束后调用Done
+type LoadTracker interface {
+	Inc(node string)
+	Done(node string)
+}
+
+// BoundedLoad 有界负载一致性哈希（Mirrokni, Thorup, Zadimoghaddam）
+// 环本身不随负载变化：key先定位到顺时针第一个虚拟节点，若该节点在途负载已达上限，
+// 则继续顺时针寻找下一个未满的节点。负载回落后key自动回到原节点，不会打乱环
+type BoundedLoad struct {
+	mu      sync.RWMutex
+	config  *Config
+	epsilon float64
+	// 哈希环
+	keys []int
+	// 哈希环到节点的映射
+	hashMap map[int]string
+	// 节点权重
+	weights map[string]int
+	// 权重之和
+	totalWeight int
+	// 节点在途请求数
+	loads map[string]*int64
+	// 所有节点的在途请求总数
+	totalLoad int64
+}
+
+var (
+	_ Router      = (*BoundedLoad)(nil)
+	_ LoadTracker = (*BoundedLoad)(nil)
+	_ MultiRouter = (*BoundedLoad)(nil)
+)
+
+// NewBoundedLoad 创建有界负载路由器，epsilon<=0时使用DefaultEpsilon
+func NewBoundedLoad(epsilon float64, opts ...Option) *BoundedLoad {
+	if epsilon <= 0 {
+		epsilon = DefaultEpsilon
+	}
+	// 复用Map的选项来读取配置
+	m := &Map{config: DefaultConfig}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return &BoundedLoad{
+		config:  m.config,
+		epsilon: epsilon,
+		hashMap: make(map[int]string),
+		weights: make(map[string]int),
+		loads:   make(map[string]*int64),
+	}
+}
+
+// Add 以权重1添加节点
+func (b *BoundedLoad) Add(nodes ...string) error {
+	if len(nodes) == 0 {
+		return errors.New("no nodes provided")
+	}
+	for _, node := range nodes {
+		if node == "" {
+			continue
+		}
+		if err := b.AddWithWeight(node, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWithWeight 按权重添加节点，权重同时决定虚拟节点数和负载上限
+func (b *BoundedLoad) AddWithWeight(node string, weight int) error {
+	if node == "" {
+		return errors.New("invalid node")
+	}
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.weights[node]; exists {
+		b.removeNode(node)
+	}
+	for i := 0; i < b.config.DefaultReplicas*weight; i++ {
+		hash := int(b.config.HashFunc([]byte(fmt.Sprintf("%s-%d", node, i))))
+		b.keys = append(b.keys, hash)
+		b.hashMap[hash] = node
+	}
+	sort.Ints(b.keys)
+
+	b.weights[node] = weight
+	b.totalWeight += weight
+	if _, ok := b.loads[node]; !ok {
+		b.loads[node] = new(int64)
+	}
+	return nil
+}
+
+// Remove 移除节点
+func (b *BoundedLoad) Remove(node string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.weights[node]; !exists {
+		return fmt.Errorf("node %s not found", node)
+	}
+	b.removeNode(node)
+	// 已移除节点上的在途请求不再计入总负载
+	if load, ok := b.loads[node]; ok {
+		atomic.AddInt64(&b.totalLoad, -atomic.LoadInt64(load))
+		delete(b.loads, node)
+	}
+	return nil
+}
+
+// removeNode 移除节点的虚拟节点和权重，调用者需持有写锁
+func (b *BoundedLoad) removeNode(node string) {
+	kept := b.keys[:0]
+	for _, hash := range b.keys {
+		if b.hashMap[hash] == node {
+			delete(b.hashMap, hash)
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	b.keys = kept
+	b.totalWeight -= b.weights[node]
+	delete(b.weights, node)
+}
+
+// Get 返回key所属的、负载未超过上限的节点
+// 它只负责选择，不会增加负载；实际发出请求时需调用Inc/Done
+func (b *BoundedLoad) Get(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.keys) == 0 {
+		return ""
+	}
+
+	hash := int(b.config.HashFunc([]byte(key)))
+	start := sort.Search(len(b.keys), func(i int) bool {
+		return b.keys[i] >= hash
+	})
+
+	// 顺时针遍历，跳过已满的节点；每个节点只检查一次
+	total := atomic.LoadInt64(&b.totalLoad)
+	checked := make(map[string]struct{}, len(b.weights))
+	for i := 0; i < len(b.keys) && len(checked) < len(b.weights); i++ {
+		node := b.hashMap[b.keys[(start+i)%len(b.keys)]]
+		if _, ok := checked[node]; ok {
+			continue
+		}
+		checked[node] = struct{}{}
+		if atomic.LoadInt64(b.loads[node])+1 <= b.capacity(node, total) {
+			return node
+		}
+	}
+
+	// 理论上上限之和不小于总负载+1，一定能找到节点；兜底返回环上的原始归属
+	return b.hashMap[b.keys[start%len(b.keys)]]
+}
+
+// GetN 返回key在环上顺时针的前n个不同节点（不考虑负载）
+func (b *BoundedLoad) GetN(key string, n int) []string {
+	if key == "" {
+		return nil
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return walkRing(b.keys, b.hashMap, int(b.config.HashFunc([]byte(key))), n)
+}
+
+// capacity 节点负载上限：ceil((1+ε)·(总负载+1)·w/W)，调用者需持有读锁
+func (b *BoundedLoad) capacity(node string, total int64) int64 {
+	share := float64(b.weights[node]) / float64(b.totalWeight)
+	return int64(math.Ceil((1 + b.epsilon) * float64(total+1) * share))
+}
+
+// Inc 记录节点新增一个在途请求
+func (b *BoundedLoad) Inc(node string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if load, ok := b.loads[node]; ok {
+		atomic.AddInt64(load, 1)
+		atomic.AddInt64(&b.totalLoad, 1)
+	}
+}
+
+// Done 记录节点完成一个在途请求
+func (b *BoundedLoad) Done(node string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if load, ok := b.loads[node]; ok && atomic.AddInt64(load, -1) >= 0 {
+		atomic.AddInt64(&b.totalLoad, -1)
+	} else if ok {
+		// 节点在请求进行中被移除后重新加入，计数不能为负
+		atomic.AddInt64(load, 1)
+	}
+}
+
+// Loads 返回各节点当前的在途请求数
+func (b *BoundedLoad) Loads() map[string]int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	loads := make(map[string]int64, len(b.loads))
+	for node, load := range b.loads {
+		loads[node] = atomic.LoadInt64(load)
+	}
+	return loads
+}
@@ -0,0 +1,92 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestBoundedLoad_NoLoadMatchesRing 没有在途请求时，路由结果与普通哈希环一致
+func TestBoundedLoad_NoLoadMatchesRing(t *testing.T) {
+	b := NewBoundedLoad(DefaultEpsilon)
+	ring := New()
+	for i := 0; i < 5; i++ {
+		node := fmt.Sprintf("node-%d", i)
+		b.Add(node)
+		ring.Add(node)
+	}
+
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+	for _, key := range testKeys(1000) {
+		if got, want := b.Get(key), ring.locate(key); got != want {
+			t.Fatalf("key %s: bounded %s, ring %s", key, got, want)
+		}
+	}
+}
+
+// TestBoundedLoad_RespectsBound 持续占用请求时，任何节点的负载都不超过(1+ε)·平均值
+func TestBoundedLoad_RespectsBound(t *testing.T) {
+	const eps = 0.25
+	b := NewBoundedLoad(eps)
+	nodes := 4
+	for i := 0; i < nodes; i++ {
+		b.Add(fmt.Sprintf("node-%d", i))
+	}
+
+	// 所有请求都打到同一个热点key，且都不结束
+	const inflight = 400
+	for i := 0; i < inflight; i++ {
+		node := b.Get("hot-key")
+		if node == "" {
+			t.Fatal("no node returned")
+		}
+		b.Inc(node)
+	}
+
+	limit := int64(math.Ceil((1 + eps) * inflight / float64(nodes)))
+	for node, load := range b.Loads() {
+		if load > limit {
+			t.Errorf("node %s load %d exceeds bound %d", node, load, limit)
+		}
+	}
+}
+
+// TestBoundedLoad_ReturnsToOwner 负载回落后key回到原节点，环没有被修改
+func TestBoundedLoad_ReturnsToOwner(t *testing.T) {
+	b := NewBoundedLoad(DefaultEpsilon)
+	b.Add("a", "b", "c")
+
+	owner := b.Get("k")
+	for i := 0; i < 10; i++ {
+		b.Inc(owner)
+	}
+	if b.Get("k") == owner {
+		t.Fatal("overloaded owner should be skipped")
+	}
+	for i := 0; i < 10; i++ {
+		b.Done(owner)
+	}
+	if got := b.Get("k"); got != owner {
+		t.Fatalf("key should return to %s, got %s", owner, got)
+	}
+}
+
+// TestBoundedLoad_Weighted 权重越大的节点负载上限越高
+func TestBoundedLoad_Weighted(t *testing.T) {
+	b := NewBoundedLoad(0.1)
+	b.AddWithWeight("small", 1)
+	b.AddWithWeight("big", 3)
+
+	for i := 0; i < 400; i++ {
+		b.Inc(b.Get(fmt.Sprintf("key-%d", i%7)))
+	}
+	loads := b.Loads()
+	if loads["big"] <= loads["small"] {
+		t.Fatalf("big node should carry more load: %v", loads)
+	}
+	// 运行时浮点运算可能让上限向上取整多出1
+	if limit := int64(math.Ceil(1.1*400*0.25)) + 1; loads["small"] > limit {
+		t.Fatalf("small node load %d exceeds %d", loads["small"], limit)
+	}
+}
@@ -0,0 +1,81 @@
+package consistenthash
+
+import (
+	"maps"
+	"slices"
+)
+
+// Cloner 能复制出一份成员、权重和配置都相同的独立路由器
+// 成员变化前保存一份副本，订阅者可以用它判断key原来的归属
+type Cloner interface {
+	Clone() Router
+}
+
+var (
+	_ Cloner = (*Map)(nil)
+	_ Cloner = (*Rendezvous)(nil)
+	_ Cloner = (*Jump)(nil)
+	_ Cloner = (*BoundedLoad)(nil)
+)
+
+// Clone 复制当前的环（包括自动调整后的虚拟节点），副本不统计负载、不自动调整
+func (m *Map) Clone() Router {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c := &Map{
+		config:       m.config,
+		keys:         slices.Clone(m.keys),
+		hashMap:      maps.Clone(m.hashMap),
+		nodeReplicas: maps.Clone(m.nodeReplicas),
+		nodeWeights:  maps.Clone(m.nodeWeights),
+		nodeCounts:   make(map[string]*int64, len(m.nodeCounts)),
+		stopCh:       make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for node := range m.nodeCounts {
+		c.nodeCounts[node] = new(int64)
+	}
+	close(c.done)
+	return c
+}
+
+// Clone 复制当前的节点和权重
+func (r *Rendezvous) Clone() Router {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return &Rendezvous{
+		weights: maps.Clone(r.weights),
+		nodes:   slices.Clone(r.nodes),
+	}
+}
+
+// Clone 复制当前的桶分配
+func (j *Jump) Clone() Router {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return &Jump{
+		buckets: slices.Clone(j.buckets),
+		weights: maps.Clone(j.weights),
+	}
+}
+
+// Clone 复制当前的环和ε，副本的在途负载从0开始
+func (b *BoundedLoad) Clone() Router {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	c := &BoundedLoad{
+		config:      b.config,
+		epsilon:     b.epsilon,
+		keys:        slices.Clone(b.keys),
+		hashMap:     maps.Clone(b.hashMap),
+		weights:     maps.Clone(b.weights),
+		totalWeight: b.totalWeight,
+		loads:       make(map[string]*int64, len(b.loads)),
+	}
+	for node := range b.loads {
+		c.loads[node] = new(int64)
+	}
+	return c
+}
@@ -21,26 +21,42 @@ type Map struct {
 	hashMap map[int]string
 	// 节点到虚拟节点数量的映射
 	nodeReplicas map[string]int
-	// 节点负载统计
-	nodeCounts map[string]int64
+	// 节点权重，虚拟节点数 = DefaultReplicas * 权重
+	nodeWeights map[string]int
+	// 节点负载统计，map本身只在写锁下修改，计数器通过原子操作更新，Get只需读锁
+	nodeCounts map[string]*int64
 	// 总请求数
 	totalRequests int64
+	// 关闭后台负载均衡协程
+	stopCh    chan struct{}
+	closeOnce sync.Once
+	done      chan struct{}
 }
 
+// minRebalanceSamples 样本少于该值时不进行调整
+const minRebalanceSamples = 1000
+
 // New 创建一致性哈希实例
 func New(opts ...Option) *Map {
 	m := &Map{
 		config:       DefaultConfig,
 		hashMap:      make(map[int]string),
 		nodeReplicas: make(map[string]int),
-		nodeCounts:   make(map[string]int64),
+		nodeWeights:  make(map[string]int),
+		nodeCounts:   make(map[string]*int64),
+		stopCh:       make(chan struct{}),
+		done:         make(chan struct{}),
 	}
 
 	for _, opt := range opts {
 		opt(m)
 	}
 
-	m.startBalancer() // 启动负载均衡器
+	if m.config.AutoRebalance {
+		go m.runBalancer() // 启动负载均衡器
+	} else {
+		close(m.done)
+	}
 	return m
 }
 
@@ -54,6 +70,18 @@ func WithConfig(config *Config) Option {
 	}
 }
 
+// WithAutoRebalance 启用按请求数自动调整虚拟节点，interval<=0时使用配置中的间隔
+func WithAutoRebalance(interval time.Duration) Option {
+	return func(m *Map) {
+		cfg := *m.config
+		cfg.AutoRebalance = true
+		if interval > 0 {
+			cfg.RebalanceInterval = interval
+		}
+		m.config = &cfg
+	}
+}
+
 // Add 添加节点
 func (m *Map) Add(nodes ...string) error {
 	if len(nodes) == 0 {
@@ -71,7 +99,7 @@ func (m *Map) Add(nodes ...string) error {
 		}
 
 		// 为节点添加虚拟节点
-		m.addNode(node, m.config.DefaultReplicas)
+		m.setNode(node, 1)
 	}
 
 	// 重新排序
@@ -79,6 +107,43 @@ func (m *Map) Add(nodes ...string) error {
 	return nil
 }
 
+// AddWithWeight 按权重添加节点，虚拟节点数与权重成正比
+// 节点已存在时按新权重重建其虚拟节点
+func (m *Map) AddWithWeight(node string, weight int) error {
+	if node == "" {
+		return errors.New("invalid node")
+	}
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.setNode(node, weight)
+	sort.Ints(m.keys)
+	return nil
+}
+
+// Weight 返回节点权重，节点不存在时返回0
+func (m *Map) Weight(node string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.nodeWeights[node]
+}
+
+// setNode 以指定权重（重新）放置节点，调用者需持有写锁并负责排序
+func (m *Map) setNode(node string, weight int) {
+	if m.nodeReplicas[node] > 0 {
+		m.removeNode(node)
+	}
+	m.addNode(node, m.config.DefaultReplicas*weight)
+	m.nodeWeights[node] = weight
+	if _, ok := m.nodeCounts[node]; !ok {
+		m.nodeCounts[node] = new(int64)
+	}
+}
+
 // Remove 移除节点
 func (m *Map) Remove(node string) error {
 	if node == "" {
@@ -88,14 +153,25 @@ func (m *Map) Remove(node string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	replicas := m.nodeReplicas[node]
-	if replicas == 0 {
+	if m.nodeReplicas[node] == 0 {
 		return fmt.Errorf("node %s not found", node)
 	}
+	m.removeNode(node)
+	//删除节点相关的统计信息
+	delete(m.nodeWeights, node)
+	delete(m.nodeCounts, node)
+	return nil
+}
 
-	// 移除节点的所有虚拟节点
+// removeNode 移除节点的所有虚拟节点（数量由加入时的权重决定），调用者需持有写锁
+func (m *Map) removeNode(node string) {
+	replicas := m.nodeReplicas[node]
 	for i := 0; i < replicas; i++ {
 		hash := int(m.config.HashFunc([]byte(fmt.Sprintf("%s-%d", node, i))))
+		//哈希冲突时该位置可能属于别的节点，不能误删
+		if m.hashMap[hash] != node {
+			continue
+		}
 		//map是引用传递，所以不需要重新赋值
 		delete(m.hashMap, hash)
 		for j := 0; j < len(m.keys); j++ {
@@ -106,10 +182,7 @@ func (m *Map) Remove(node string) error {
 			}
 		}
 	}
-	//删除节点相关的统计信息
 	delete(m.nodeReplicas, node)
-	delete(m.nodeCounts, node)
-	return nil
 }
 
 // Get 获取节点
@@ -121,6 +194,22 @@ func (m *Map) Get(key string) string {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
+	node := m.locate(key)
+	if node == "" {
+		return ""
+	}
+	// 读锁下只做原子自增，不修改map本身
+	// 在 Go 语言中，普通 Map 的并发写入被设计为直接 Panic（崩溃），而且这种 Panic 是**无法恢复（Unrecoverable）**的 fatal error。
+	if count, ok := m.nodeCounts[node]; ok {
+		atomic.AddInt64(count, 1)
+	}
+	atomic.AddInt64(&m.totalRequests, 1)
+
+	return node
+}
+
+// locate 在哈希环上查找key所属节点，不记录负载，调用者需持有读锁
+func (m *Map) locate(key string) string {
 	if len(m.keys) == 0 {
 		return ""
 	}
@@ -137,15 +226,17 @@ func (m *Map) Get(key string) string {
 		idx = 0
 	}
 
-	node := m.hashMap[m.keys[idx]]
-	count := m.nodeCounts[node]
-	// 在RLock中进行了写操作，不符合go的并发规范
-	// 在 Go 语言中，普通 Map 的并发写入被设计为直接 Panic（崩溃），而且这种 Panic 是**无法恢复（Unrecoverable）**的 fatal error。
-	// to do: 拆分读写锁逻辑
-	m.nodeCounts[node] = count + 1
-	atomic.AddInt64(&m.totalRequests, 1)
+	return m.hashMap[m.keys[idx]]
+}
 
-	return node
+// GetN 返回key顺时针方向的前n个不同节点，不记录负载
+func (m *Map) GetN(key string, n int) []string {
+	if key == "" {
+		return nil
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return walkRing(m.keys, m.hashMap, int(m.config.HashFunc([]byte(key))), n)
 }
 
 // addNode 添加节点的虚拟节点
@@ -164,40 +255,52 @@ func (m *Map) addNode(node string, replicas int) {
 }
 
 // checkAndRebalance 检查并重新平衡虚拟节点
+// 整个过程持有写锁：读取计数、重建虚拟节点、重置计数必须是一个原子步骤
 func (m *Map) checkAndRebalance() {
-	if atomic.LoadInt64(&m.totalRequests) < 1000 {
+	if atomic.LoadInt64(&m.totalRequests) < minRebalanceSamples {
 		return // 样本太少，不进行调整
 	}
 
-	// 计算负载情况
-	avgLoad := float64(m.totalRequests) / float64(len(m.nodeReplicas))
-	var maxDiff float64
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	for _, count := range m.nodeCounts {
-		diff := math.Abs(float64(count) - avgLoad)
-		if diff/avgLoad > maxDiff {
-			maxDiff = diff / avgLoad
+	total := atomic.LoadInt64(&m.totalRequests)
+	if total < minRebalanceSamples || len(m.nodeReplicas) == 0 {
+		return
+	}
+
+	// 计算负载情况，按权重折算每个节点的期望负载
+	var maxDiff float64
+	for node, count := range m.nodeCounts {
+		expected := m.expectedLoad(node, total)
+		diff := math.Abs(float64(atomic.LoadInt64(count)) - expected)
+		if diff/expected > maxDiff {
+			maxDiff = diff / expected
 		}
 	}
 
 	// 如果负载不均衡度超过阈值，调整虚拟节点
 	if maxDiff > m.config.LoadBalanceThreshold {
-		m.rebalanceNodes()
+		m.rebalanceNodes(total)
 	}
 }
 
-// rebalanceNodes 重新平衡节点
-func (m *Map) rebalanceNodes() {
-	// 加锁保护
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	avgLoad := float64(m.totalRequests) / float64(len(m.nodeReplicas))
+// expectedLoad 按权重计算节点的期望请求数，调用者需持有锁
+func (m *Map) expectedLoad(node string, total int64) float64 {
+	totalWeight := 0
+	for _, w := range m.nodeWeights {
+		totalWeight += w
+	}
+	return float64(total) * float64(m.nodeWeights[node]) / float64(totalWeight)
+}
 
+// rebalanceNodes 重新平衡节点，调用者需持有写锁
+func (m *Map) rebalanceNodes(total int64) {
 	// 调整每个节点的虚拟节点数量
 	for node, count := range m.nodeCounts {
 		currentReplicas := m.nodeReplicas[node]
-		loadRatio := float64(count) / avgLoad
+		weight := m.nodeWeights[node]
+		loadRatio := float64(atomic.LoadInt64(count)) / m.expectedLoad(node, total)
 
 		var newReplicas int
 		if loadRatio > 1 {
@@ -208,27 +311,24 @@ func (m *Map) rebalanceNodes() {
 			newReplicas = int(float64(currentReplicas) * (2 - loadRatio))
 		}
 
-		// 确保在限制范围内
-		if newReplicas < m.config.MinReplicas {
-			newReplicas = m.config.MinReplicas
+		// 确保在限制范围内，上下限随权重缩放
+		if newReplicas < m.config.MinReplicas*weight {
+			newReplicas = m.config.MinReplicas * weight
 		}
-		if newReplicas > m.config.MaxReplicas {
-			newReplicas = m.config.MaxReplicas
+		if newReplicas > m.config.MaxReplicas*weight {
+			newReplicas = m.config.MaxReplicas * weight
 		}
 
 		if newReplicas != currentReplicas {
-			// 重新添加节点的虚拟节点
-			// Remove中也有加锁操作，这里调用会死锁，需要优化？
-			if err := m.Remove(node); err != nil {
-				continue // 如果移除失败，跳过这个节点
-			}
+			// 已持有写锁，直接操作内部结构，不能再调用会加锁的Remove
+			m.removeNode(node)
 			m.addNode(node, newReplicas)
 		}
 	}
 
 	// 重置计数器
-	for node := range m.nodeCounts {
-		m.nodeCounts[node] = 0
+	for _, count := range m.nodeCounts {
+		atomic.StoreInt64(count, 0)
 	}
 	atomic.StoreInt64(&m.totalRequests, 0)
 
@@ -248,19 +348,37 @@ func (m *Map) GetStats() map[string]float64 {
 	}
 
 	for node, count := range m.nodeCounts {
-		stats[node] = float64(count) / float64(total)
+		stats[node] = float64(atomic.LoadInt64(count)) / float64(total)
 	}
 	return stats
 }
 
-// 将checkAndRebalance移到单独的goroutine中
-func (m *Map) startBalancer() {
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
+// runBalancer 在单独的goroutine中周期性执行checkAndRebalance，直到Close
+func (m *Map) runBalancer() {
+	defer close(m.done)
 
-		for range ticker.C {
+	interval := m.config.RebalanceInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
 			m.checkAndRebalance()
+		case <-m.stopCh:
+			return
 		}
-	}()
+	}
+}
+
+// Close 停止后台负载均衡协程，可重复调用
+func (m *Map) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stopCh)
+	})
+	<-m.done
+	return nil
 }
@@ -0,0 +1,94 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// ownership 统计一批key在各节点上的分布，使用locate避免触发负载统计
+func ownership(m *Map, n int) map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	owned := make(map[string]int)
+	for i := 0; i < n; i++ {
+		owned[m.locate(fmt.Sprintf("key-%d", i))]++
+	}
+	return owned
+}
+
+// TestAddWithWeight_ProportionalShare key的分布应与权重成正比
+func TestAddWithWeight_ProportionalShare(t *testing.T) {
+	m := New()
+	weights := map[string]int{"10.0.0.1:8001": 1, "10.0.0.2:8001": 2, "10.0.0.3:8001": 4}
+	totalWeight := 0
+	for node, w := range weights {
+		if err := m.AddWithWeight(node, w); err != nil {
+			t.Fatal(err)
+		}
+		totalWeight += w
+	}
+
+	const samples = 200000
+	owned := ownership(m, samples)
+	for node, w := range weights {
+		want := float64(w) / float64(totalWeight)
+		got := float64(owned[node]) / samples
+		if math.Abs(got-want)/want > 0.25 {
+			t.Errorf("node %s weight %d: share %.3f, want %.3f±25%%", node, w, got, want)
+		}
+	}
+}
+
+// TestAddWithWeight_Replicas 虚拟节点数随权重缩放，重复添加会按新权重重建
+func TestAddWithWeight_Replicas(t *testing.T) {
+	m := New()
+	if err := m.AddWithWeight("a", 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(m.keys), 3*m.config.DefaultReplicas; got != want {
+		t.Fatalf("replicas = %d, want %d", got, want)
+	}
+
+	if err := m.AddWithWeight("a", 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(m.keys), m.config.DefaultReplicas; got != want {
+		t.Fatalf("replicas after reweight = %d, want %d", got, want)
+	}
+	if m.Weight("a") != 1 {
+		t.Fatalf("weight = %d, want 1", m.Weight("a"))
+	}
+
+	if err := m.AddWithWeight("a", 0); err == nil {
+		t.Fatal("expected error for zero weight")
+	}
+}
+
+// TestRemove_Weighted 移除带权节点时要清理全部虚拟节点
+func TestRemove_Weighted(t *testing.T) {
+	m := New()
+	m.Add("a")
+	if err := m.AddWithWeight("b", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove("b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(m.keys), m.config.DefaultReplicas; got != want {
+		t.Fatalf("ring size = %d, want %d", got, want)
+	}
+	for _, node := range m.hashMap {
+		if node == "b" {
+			t.Fatal("virtual node of removed node still on ring")
+		}
+	}
+	if m.Weight("b") != 0 {
+		t.Fatal("weight of removed node should be cleared")
+	}
+	if owned := ownership(m, 1000); owned["a"] != 1000 {
+		t.Fatalf("all keys should map to a, got %v", owned)
+	}
+}
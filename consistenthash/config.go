@@ -1,6 +1,9 @@
 package consistenthash
 
-import "hash/crc32"
+import (
+	"hash/crc32"
+	"time"
+)
 
 // Config 一致性哈希配置
 type Config struct {
@@ -14,6 +17,10 @@ type Config struct {
 	HashFunc func(data []byte) uint32
 	// 负载均衡阈值，超过此值触发虚拟节点调整
 	LoadBalanceThreshold float64
+	// 是否启用按请求数自动调整虚拟节点，默认关闭：调整会打乱key的归属
+	AutoRebalance bool
+	// 自动调整的检查间隔
+	RebalanceInterval time.Duration
 }
 
 // DefaultConfig 默认配置
@@ -23,4 +30,5 @@ var DefaultConfig = &Config{
 	MaxReplicas:          200,
 	HashFunc:             crc32.ChecksumIEEE,
 	LoadBalanceThreshold: 0.25, // 25% 的负载不均衡度触发调整
+	RebalanceInterval:    time.Second,
 }
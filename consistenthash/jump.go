@@ -0,0 +1,111 @@
+package consistenthash
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Jump jump一致性哈希（Lamping & Veach）
+// 不需要虚拟节点，内存为O(桶数)且分布非常均匀；但只对在末尾增删桶是最优的，
+// 移除中间节点会使其后的桶整体前移，移动的key比哈希环多。
+// 桶按节点名排序排列，与添加顺序无关，成员相同的路由器对同一个key总是选出同一个节点
+type Jump struct {
+	mu sync.RWMutex
+	// 桶到节点的映射，按节点名排序，权重为w的节点占据w个连续的桶
+	buckets []string
+	// 节点到权重的映射
+	weights map[string]int
+}
+
+// NewJump 创建jump哈希路由器
+func NewJump() *Jump {
+	return &Jump{
+		weights: make(map[string]int),
+	}
+}
+
+// Add 以权重1添加节点
+func (j *Jump) Add(nodes ...string) error {
+	if len(nodes) == 0 {
+		return errors.New("no nodes provided")
+	}
+	for _, node := range nodes {
+		if node == "" {
+			continue
+		}
+		if err := j.AddWithWeight(node, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWithWeight 按权重添加节点或修改已有节点的权重
+func (j *Jump) AddWithWeight(node string, weight int) error {
+	if node == "" {
+		return errors.New("invalid node")
+	}
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.weights[node] = weight
+	j.rebuild()
+	return nil
+}
+
+// Remove 移除节点
+func (j *Jump) Remove(node string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if _, exists := j.weights[node]; !exists {
+		return fmt.Errorf("node %s not found", node)
+	}
+	delete(j.weights, node)
+	j.rebuild()
+	return nil
+}
+
+// rebuild 按节点名顺序重新排列所有桶，调用者需持有写锁
+func (j *Jump) rebuild() {
+	buckets := make([]string, 0, len(j.buckets))
+	for _, node := range slices.Sorted(maps.Keys(j.weights)) {
+		for range j.weights[node] {
+			buckets = append(buckets, node)
+		}
+	}
+	j.buckets = buckets
+}
+
+// Get 返回key所属的节点
+func (j *Jump) Get(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	if len(j.buckets) == 0 {
+		return ""
+	}
+	return j.buckets[jumpHash(hash64(key), len(j.buckets))]
+}
+
+// jumpHash 论文中的原始算法，返回[0, numBuckets)内的桶号
+func jumpHash(key uint64, numBuckets int) int {
+	var b, next int64 = -1, 0
+	for next < int64(numBuckets) {
+		b = next
+		key = key*2862933555777941757 + 1
+		next = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
@@ -0,0 +1,120 @@
+package consistenthash
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRebalance_Concurrent 在自动再平衡运行时并发Get/Add/Remove，配合 go test -race 使用
+func TestRebalance_Concurrent(t *testing.T) {
+	m := New(WithAutoRebalance(time.Millisecond))
+	defer m.Close()
+
+	for i := 0; i < 4; i++ {
+		m.Add(fmt.Sprintf("node-%d", i))
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// 读：热点key制造负载不均，促使再平衡触发
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				key := "hot"
+				if i%4 == 0 {
+					key = fmt.Sprintf("key-%d-%d", g, i)
+				}
+				m.Get(key)
+			}
+		}(g)
+	}
+
+	// 写：节点上下线
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			node := fmt.Sprintf("flap-%d", i%3)
+			m.AddWithWeight(node, 1+i%2)
+			m.GetStats()
+			m.Remove(node)
+		}
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	// 再平衡之后环仍然完整：每个存活节点都有虚拟节点，且key能被路由
+	for i := 0; i < 4; i++ {
+		if m.Weight(fmt.Sprintf("node-%d", i)) != 1 {
+			t.Fatalf("node-%d lost from ring", i)
+		}
+	}
+	if m.Get("any") == "" {
+		t.Fatal("ring is empty after rebalancing")
+	}
+}
+
+// TestRebalance_AdjustsReplicas 负载不均时调整虚拟节点数，并保持在上下限内
+func TestRebalance_AdjustsReplicas(t *testing.T) {
+	m := New()
+	defer m.Close()
+	m.Add("a", "b")
+
+	// 手动制造不均衡：所有请求都落在a上
+	for i := 0; i < 2*minRebalanceSamples; i++ {
+		m.mu.RLock()
+		node := m.locate("hot")
+		m.mu.RUnlock()
+		*m.nodeCounts[node]++
+		m.totalRequests++
+	}
+	hot := m.Get("hot")
+	before := m.nodeReplicas[hot]
+
+	m.checkAndRebalance()
+
+	after := m.nodeReplicas[hot]
+	if after >= before {
+		t.Fatalf("hot node replicas should shrink: before %d, after %d", before, after)
+	}
+	if after < m.config.MinReplicas {
+		t.Fatalf("replicas %d below minimum %d", after, m.config.MinReplicas)
+	}
+	if m.totalRequests != 0 {
+		t.Fatal("counters should be reset after rebalancing")
+	}
+}
+
+// TestClose_Idempotent Close可以重复调用，未启用自动再平衡时也能立即返回
+func TestClose_Idempotent(t *testing.T) {
+	for _, m := range []*Map{New(), New(WithAutoRebalance(time.Millisecond))} {
+		done := make(chan struct{})
+		go func() {
+			m.Close()
+			m.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Close blocked")
+		}
+	}
+}
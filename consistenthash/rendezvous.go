@@ -0,0 +1,135 @@
+package consistenthash
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Rendezvous 最高随机权重（HRW）哈希
+// 每个key对所有节点打分，得分最高者负责；节点变化时只有归属于该节点的key会移动
+type Rendezvous struct {
+	mu sync.RWMutex
+	// 节点到权重的映射
+	weights map[string]int
+	// 有序节点列表，保证打分平局时结果确定
+	nodes []string
+}
+
+// NewRendezvous 创建rendezvous哈希路由器
+func NewRendezvous() *Rendezvous {
+	return &Rendezvous{
+		weights: make(map[string]int),
+	}
+}
+
+// Add 以权重1添加节点
+func (r *Rendezvous) Add(nodes ...string) error {
+	if len(nodes) == 0 {
+		return errors.New("no nodes provided")
+	}
+	for _, node := range nodes {
+		if node == "" {
+			continue
+		}
+		if err := r.AddWithWeight(node, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddWithWeight 按权重添加节点
+func (r *Rendezvous) AddWithWeight(node string, weight int) error {
+	if node == "" {
+		return errors.New("invalid node")
+	}
+	if weight <= 0 {
+		return fmt.Errorf("invalid weight %d for node %s", weight, node)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.weights[node]; !exists {
+		r.nodes = append(r.nodes, node)
+		sort.Strings(r.nodes)
+	}
+	r.weights[node] = weight
+	return nil
+}
+
+// Remove 移除节点
+func (r *Rendezvous) Remove(node string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.weights[node]; !exists {
+		return fmt.Errorf("node %s not found", node)
+	}
+	delete(r.weights, node)
+	for i, n := range r.nodes {
+		if n == node {
+			r.nodes = append(r.nodes[:i], r.nodes[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// Get 返回得分最高的节点
+func (r *Rendezvous) Get(key string) string {
+	if key == "" {
+		return ""
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best string
+	bestScore := math.Inf(-1)
+	for _, node := range r.nodes {
+		if score := r.score(node, key); score > bestScore {
+			best, bestScore = node, score
+		}
+	}
+	return best
+}
+
+// score 加权HRW打分：w / -ln(u)，u为(0,1)上的均匀分布
+// 这样节点胜出的概率与权重成正比
+func (r *Rendezvous) score(node, key string) float64 {
+	u := (float64(hash64(node, key)>>11) + 0.5) / (1 << 53)
+	return float64(r.weights[node]) / -math.Log(u)
+}
+
+// GetN 按得分从高到低返回前n个节点
+func (r *Rendezvous) GetN(key string, n int) []string {
+	if key == "" || n <= 0 {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	type scored struct {
+		node  string
+		score float64
+	}
+	all := make([]scored, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		all = append(all, scored{node: node, score: r.score(node, key)})
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+
+	if n > len(all) {
+		n = len(all)
+	}
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = all[i].node
+	}
+	return nodes
+}
@@ -0,0 +1,146 @@
+package consistenthash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// Router 是路由算法的公共接口：根据key选出负责的节点
+// 哈希环(Map)、rendezvous哈希、jump哈希都实现了该接口，ClientPicker可以在它们之间切换
+type Router interface {
+	// Add 以权重1添加节点
+	Add(nodes ...string) error
+	// AddWithWeight 按权重添加节点，节点已存在时更新权重
+	AddWithWeight(node string, weight int) error
+	// Remove 移除节点
+	Remove(node string) error
+	// Get 返回key所属的节点，没有节点时返回空字符串
+	Get(key string) string
+}
+
+// MultiRouter 能按优先级返回多个候选节点的路由器，用于对冲请求选择下一个副本
+type MultiRouter interface {
+	// GetN 返回key的前n个不同候选节点，第一个与Get一致
+	GetN(key string, n int) []string
+}
+
+// Algorithm 路由算法名称
+type Algorithm string
+
+const (
+	AlgRing       Algorithm = "ring"       // 带虚拟节点的一致性哈希环
+	AlgRendezvous Algorithm = "rendezvous" // 最高随机权重（HRW）哈希
+	AlgJump       Algorithm = "jump"       // jump一致性哈希
+	AlgBounded    Algorithm = "bounded"    // 有界负载一致性哈希
+)
+
+var (
+	_ Router = (*Map)(nil)
+	_ Router = (*Rendezvous)(nil)
+	_ Router = (*Jump)(nil)
+
+	_ MultiRouter = (*Map)(nil)
+	_ MultiRouter = (*Rendezvous)(nil)
+)
+
+// NewRouter 按算法名称创建路由器，空名称使用哈希环
+func NewRouter(alg Algorithm) (Router, error) {
+	switch alg {
+	case "", AlgRing:
+		return New(), nil
+	case AlgRendezvous:
+		return NewRendezvous(), nil
+	case AlgJump:
+		return NewJump(), nil
+	case AlgBounded:
+		return NewBoundedLoad(DefaultEpsilon), nil
+	default:
+		return nil, fmt.Errorf("unknown routing algorithm %q", alg)
+	}
+}
+
+// hash64 为rendezvous/jump提供64位哈希，crc32的分布不足以支撑HRW打分
+func hash64(parts ...string) uint64 {
+	h := fnv.New64a()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return mix64(h.Sum64())
+}
+
+// mix64 splitmix64的终结函数，打散fnv低位的相关性
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Assign 记录一批key在路由器上的归属，用于比较不同算法
+func Assign(r Router, keys []string) map[string]string {
+	owners := make(map[string]string, len(keys))
+	for _, key := range keys {
+		owners[key] = r.Get(key)
+	}
+	return owners
+}
+
+// Movement 返回两次分配之间归属发生变化的key比例
+func Movement(before, after map[string]string) float64 {
+	if len(before) == 0 {
+		return 0
+	}
+	moved := 0
+	for key, owner := range before {
+		if after[key] != owner {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(before))
+}
+
+// LoadSkew 返回分配结果中最忙节点的负载与平均负载之比，1表示完全均衡
+func LoadSkew(owners map[string]string) float64 {
+	loads := make(map[string]int)
+	for _, owner := range owners {
+		loads[owner]++
+	}
+	if len(loads) == 0 {
+		return 0
+	}
+
+	maxLoad := 0
+	for _, n := range loads {
+		if n > maxLoad {
+			maxLoad = n
+		}
+	}
+	avg := float64(len(owners)) / float64(len(loads))
+	return float64(maxLoad) / avg
+}
+
+// walkRing 从hash所在位置顺时针遍历环，收集前n个不同节点
+func walkRing(keys []int, hashMap map[int]string, hash int, n int) []string {
+	if len(keys) == 0 || n <= 0 {
+		return nil
+	}
+	start := sort.Search(len(keys), func(i int) bool {
+		return keys[i] >= hash
+	})
+
+	nodes := make([]string, 0, n)
+	seen := make(map[string]struct{}, n)
+	for i := 0; i < len(keys) && len(nodes) < n; i++ {
+		node := hashMap[keys[(start+i)%len(keys)]]
+		if _, ok := seen[node]; ok {
+			continue
+		}
+		seen[node] = struct{}{}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
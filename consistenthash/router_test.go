@@ -0,0 +1,187 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// newTestRouters 创建参与比较的路由器
+func newTestRouters(t *testing.T) map[Algorithm]Router {
+	t.Helper()
+	return map[Algorithm]Router{
+		AlgRing:       New(),
+		AlgRendezvous: NewRendezvous(),
+		AlgJump:       NewJump(),
+	}
+}
+
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("user:%d", i)
+	}
+	return keys
+}
+
+// TestRouters_AddNodeMovement 新增一个节点时，各算法移动的key应接近1/(n+1)
+func TestRouters_AddNodeMovement(t *testing.T) {
+	keys := testKeys(50000)
+	for alg, r := range newTestRouters(t) {
+		for i := 0; i < 4; i++ {
+			r.Add(fmt.Sprintf("node-%d", i))
+		}
+		before := Assign(r, keys)
+		r.Add("node-4")
+		after := Assign(r, keys)
+
+		moved := Movement(before, after)
+		t.Logf("%s: moved %.3f, skew %.3f", alg, moved, LoadSkew(after))
+		if moved < 0.1 || moved > 0.3 {
+			t.Errorf("%s: moved %.3f of keys, want about 0.2", alg, moved)
+		}
+		// 移动的key只能流向新节点
+		for key, owner := range after {
+			if owner != before[key] && owner != "node-4" {
+				t.Fatalf("%s: key %s moved from %s to %s", alg, key, before[key], owner)
+			}
+		}
+	}
+}
+
+// TestRouters_RemoveNodeMovement 移除节点时，哈希环和rendezvous只移动该节点的key
+func TestRouters_RemoveNodeMovement(t *testing.T) {
+	keys := testKeys(50000)
+	for alg, r := range newTestRouters(t) {
+		for i := 0; i < 5; i++ {
+			r.Add(fmt.Sprintf("node-%d", i))
+		}
+		before := Assign(r, keys)
+		r.Remove("node-2")
+		after := Assign(r, keys)
+
+		t.Logf("%s: moved %.3f", alg, Movement(before, after))
+		if alg == AlgJump {
+			// jump哈希移除中间桶会移动更多key，只要求没有key留在已删除节点上
+			for _, owner := range after {
+				if owner == "node-2" {
+					t.Fatalf("%s: key still routed to removed node", alg)
+				}
+			}
+			continue
+		}
+		for key, owner := range before {
+			if owner != "node-2" && after[key] != owner {
+				t.Fatalf("%s: key %s moved although its owner %s stayed", alg, key, owner)
+			}
+		}
+	}
+}
+
+// TestRouters_LoadSkew 各算法在等权节点上的负载偏斜应在合理范围内
+func TestRouters_LoadSkew(t *testing.T) {
+	keys := testKeys(100000)
+	for alg, r := range newTestRouters(t) {
+		for i := 0; i < 8; i++ {
+			r.Add(fmt.Sprintf("10.0.0.%d:8001", i))
+		}
+		skew := LoadSkew(Assign(r, keys))
+		t.Logf("%s: skew %.3f", alg, skew)
+		if skew > 1.5 {
+			t.Errorf("%s: skew %.3f exceeds 1.5", alg, skew)
+		}
+	}
+}
+
+// TestRendezvous_Weighted rendezvous的key份额与权重成正比
+func TestRendezvous_Weighted(t *testing.T) {
+	r := NewRendezvous()
+	r.AddWithWeight("a", 1)
+	r.AddWithWeight("b", 3)
+
+	owners := Assign(r, testKeys(100000))
+	counts := map[string]int{}
+	for _, owner := range owners {
+		counts[owner]++
+	}
+	share := float64(counts["b"]) / float64(len(owners))
+	if math.Abs(share-0.75) > 0.02 {
+		t.Fatalf("share of b = %.3f, want 0.75", share)
+	}
+}
+
+// TestNewRouter 未知算法返回错误
+func TestNewRouter(t *testing.T) {
+	if _, err := NewRouter("bogus"); err == nil {
+		t.Fatal("expected error for unknown algorithm")
+	}
+	if r, err := NewRouter(""); err != nil || r == nil {
+		t.Fatalf("default router: %v", err)
+	}
+}
+
+// TestGetN 候选列表的第一个与Get一致，且节点不重复
+func TestGetN(t *testing.T) {
+	routers := map[Algorithm]MultiRouter{AlgRing: New(), AlgRendezvous: NewRendezvous(), AlgBounded: NewBoundedLoad(0)}
+	for alg, r := range routers {
+		r.(Router).Add("a", "b", "c")
+		for _, key := range testKeys(200) {
+			nodes := r.GetN(key, 5)
+			if len(nodes) != 3 {
+				t.Fatalf("%s: GetN returned %v", alg, nodes)
+			}
+			if nodes[0] != r.(Router).Get(key) {
+				t.Fatalf("%s: first candidate %s differs from Get %s", alg, nodes[0], r.(Router).Get(key))
+			}
+			if nodes[0] == nodes[1] || nodes[1] == nodes[2] || nodes[0] == nodes[2] {
+				t.Fatalf("%s: duplicated candidates %v", alg, nodes)
+			}
+		}
+	}
+}
+
+// TestClone 副本的路由与原路由器一致，且之后原路由器的成员变化不影响副本
+func TestClone(t *testing.T) {
+	routers := newTestRouters(t)
+	routers[AlgBounded] = NewBoundedLoad(DefaultEpsilon)
+	keys := testKeys(1000)
+	for alg, r := range routers {
+		for i := 0; i < 4; i++ {
+			r.AddWithWeight(fmt.Sprintf("node-%d", i), i+1)
+		}
+		before := Assign(r, keys)
+		clone := r.(Cloner).Clone()
+
+		r.Remove("node-0")
+		r.Add("node-9")
+		if m := Movement(before, Assign(clone, keys)); m != 0 {
+			t.Errorf("%s: clone moved %.2f of keys", alg, m)
+		}
+	}
+}
+
+// TestJump_OrderIndependent 成员和权重相同时，添加顺序不影响key的归属
+func TestJump_OrderIndependent(t *testing.T) {
+	a, b := NewJump(), NewJump()
+	a.Add("10.0.0.1:8001", "10.0.0.2:8001", "10.0.0.3:8001")
+	a.AddWithWeight("10.0.0.4:8001", 2)
+	b.AddWithWeight("10.0.0.4:8001", 3)
+	b.Add("10.0.0.3:8001", "10.0.0.1:8001", "10.0.0.2:8001")
+	b.AddWithWeight("10.0.0.4:8001", 2)
+
+	keys := testKeys(10000)
+	before, other := Assign(a, keys), Assign(b, keys)
+	for _, key := range keys {
+		if before[key] != other[key] {
+			t.Fatalf("key %s: %s vs %s", key, before[key], other[key])
+		}
+	}
+
+	// 修改权重只移动涉及该节点的key
+	a.AddWithWeight("10.0.0.4:8001", 3)
+	for key, owner := range Assign(a, keys) {
+		if owner != before[key] && owner != "10.0.0.4:8001" {
+			t.Fatalf("key %s moved from %s to %s", key, before[key], owner)
+		}
+	}
+}
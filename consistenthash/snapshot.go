@@ -0,0 +1,187 @@
+package consistenthash
+
+import (
+	"sort"
+	"time"
+)
+
+// keySpace 哈希空间大小，HashFunc返回uint32
+const keySpace = float64(1 << 32)
+
+// Point 环上的一个虚拟节点
+type Point struct {
+	Hash uint32
+	Node string
+}
+
+// NodeInfo 节点在环上的概况
+type NodeInfo struct {
+	Node      string
+	Weight    int
+	Replicas  int     // 实际落在环上的虚拟节点数
+	Ownership float64 // 负责的哈希空间比例，所有节点之和为1
+}
+
+// Snapshot 某一时刻哈希环的不可变快照，可以安全地在goroutine间传递
+type Snapshot struct {
+	TakenAt time.Time
+	Nodes   []NodeInfo // 按节点名排序
+	Points  []Point    // 按哈希值排序
+}
+
+// Snapshotter 由基于哈希环的路由器实现
+type Snapshotter interface {
+	Snapshot() Snapshot
+}
+
+var (
+	_ Snapshotter = (*Map)(nil)
+	_ Snapshotter = (*BoundedLoad)(nil)
+)
+
+// Snapshot 返回当前哈希环的快照
+func (m *Map) Snapshot() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return buildSnapshot(m.keys, m.hashMap, m.nodeWeights)
+}
+
+// Snapshot 返回当前哈希环的快照（不包含在途负载）
+func (b *BoundedLoad) Snapshot() Snapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return buildSnapshot(b.keys, b.hashMap, b.weights)
+}
+
+// buildSnapshot 根据有序的环拷贝出快照，调用者需持有读锁
+func buildSnapshot(keys []int, hashMap map[int]string, weights map[string]int) Snapshot {
+	snap := Snapshot{
+		TakenAt: time.Now(),
+		Points:  make([]Point, 0, len(keys)),
+	}
+	for _, hash := range keys {
+		// 哈希冲突时keys中可能有重复值，只保留一个
+		if n := len(snap.Points); n > 0 && snap.Points[n-1].Hash == uint32(hash) {
+			continue
+		}
+		snap.Points = append(snap.Points, Point{Hash: uint32(hash), Node: hashMap[hash]})
+	}
+
+	replicas := make(map[string]int)
+	ownership := make(map[string]float64)
+	for i, p := range snap.Points {
+		replicas[p.Node]++
+		// 虚拟节点负责(前一个点, 当前点]这段弧
+		ownership[p.Node] += arc(snap.Points, i)
+	}
+
+	for node, w := range weights {
+		snap.Nodes = append(snap.Nodes, NodeInfo{
+			Node:      node,
+			Weight:    w,
+			Replicas:  replicas[node],
+			Ownership: ownership[node] / keySpace,
+		})
+	}
+	sort.Slice(snap.Nodes, func(i, j int) bool { return snap.Nodes[i].Node < snap.Nodes[j].Node })
+	return snap
+}
+
+// arc 返回第i个点负责的弧长，第一个点的弧跨越0，用uint32减法自然回绕
+func arc(points []Point, i int) float64 {
+	if len(points) == 1 {
+		return keySpace
+	}
+	prev := points[(i-1+len(points))%len(points)].Hash
+	return float64(points[i].Hash - prev)
+}
+
+// Owner 返回哈希值h在快照中的负责节点
+func (s Snapshot) Owner(h uint32) string {
+	if len(s.Points) == 0 {
+		return ""
+	}
+	idx := sort.Search(len(s.Points), func(i int) bool {
+		return s.Points[i].Hash >= h
+	})
+	if idx == len(s.Points) {
+		idx = 0
+	}
+	return s.Points[idx].Node
+}
+
+// Node 返回指定节点的概况
+func (s Snapshot) Node(name string) (NodeInfo, bool) {
+	for _, n := range s.Nodes {
+		if n.Node == name {
+			return n, true
+		}
+	}
+	return NodeInfo{}, false
+}
+
+// RingDiff 两个快照之间的差异
+type RingDiff struct {
+	Added   []string // 新增的节点
+	Removed []string // 移除的节点
+	// Moved 归属发生变化的哈希空间比例，即均匀分布的key中需要迁移的比例
+	Moved float64
+	// MovedTo 各节点新接手的哈希空间比例
+	MovedTo map[string]float64
+}
+
+// Diff 比较两个快照，估算成员变化导致的key迁移量
+func Diff(before, after Snapshot) RingDiff {
+	d := RingDiff{MovedTo: make(map[string]float64)}
+
+	beforeNodes := make(map[string]bool, len(before.Nodes))
+	for _, n := range before.Nodes {
+		beforeNodes[n.Node] = true
+	}
+	afterNodes := make(map[string]bool, len(after.Nodes))
+	for _, n := range after.Nodes {
+		afterNodes[n.Node] = true
+		if !beforeNodes[n.Node] {
+			d.Added = append(d.Added, n.Node)
+		}
+	}
+	for _, n := range before.Nodes {
+		if !afterNodes[n.Node] {
+			d.Removed = append(d.Removed, n.Node)
+		}
+	}
+
+	// 合并两个环的所有边界点，相邻边界之间的每一段在两个环中都只有一个负责节点
+	bounds := make([]uint32, 0, len(before.Points)+len(after.Points))
+	for _, p := range before.Points {
+		bounds = append(bounds, p.Hash)
+	}
+	for _, p := range after.Points {
+		bounds = append(bounds, p.Hash)
+	}
+	if len(bounds) == 0 {
+		return d
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var moved float64
+	for i, h := range bounds {
+		prev := bounds[(i-1+len(bounds))%len(bounds)]
+		length := float64(uint32(h - prev))
+		if len(bounds) == 1 || (i == 0 && prev == h) {
+			length = keySpace
+		}
+		if length == 0 {
+			continue
+		}
+		from, to := before.Owner(h), after.Owner(h)
+		if from != to {
+			moved += length
+			if to != "" {
+				d.MovedTo[to] += length / keySpace
+			}
+		}
+	}
+	d.Moved = moved / keySpace
+	return d
+}
@@ -0,0 +1,86 @@
+package consistenthash
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// TestSnapshot_Ownership 各节点负责的哈希空间之和为1，且与采样分布一致
+func TestSnapshot_Ownership(t *testing.T) {
+	m := New()
+	m.AddWithWeight("a", 1)
+	m.AddWithWeight("b", 2)
+	m.AddWithWeight("c", 1)
+
+	snap := m.Snapshot()
+	if len(snap.Nodes) != 3 || len(snap.Points) != 4*m.config.DefaultReplicas {
+		t.Fatalf("unexpected snapshot: %d nodes, %d points", len(snap.Nodes), len(snap.Points))
+	}
+
+	var sum float64
+	owned := ownership(m, 100000)
+	for _, n := range snap.Nodes {
+		sum += n.Ownership
+		sampled := float64(owned[n.Node]) / 100000
+		if math.Abs(sampled-n.Ownership) > 0.02 {
+			t.Errorf("node %s: ownership %.3f, sampled %.3f", n.Node, n.Ownership, sampled)
+		}
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Fatalf("ownership sums to %f", sum)
+	}
+
+	// 快照与后续修改隔离
+	m.Remove("b")
+	if _, ok := snap.Node("b"); !ok {
+		t.Fatal("snapshot should not change after Remove")
+	}
+}
+
+// TestDiff_AddNode 新增节点时迁移比例等于新节点负责的空间，且全部流向新节点
+func TestDiff_AddNode(t *testing.T) {
+	m := New()
+	for i := 0; i < 4; i++ {
+		m.Add(fmt.Sprintf("node-%d", i))
+	}
+	before := m.Snapshot()
+	m.Add("node-4")
+	after := m.Snapshot()
+
+	d := Diff(before, after)
+	if len(d.Added) != 1 || d.Added[0] != "node-4" || len(d.Removed) != 0 {
+		t.Fatalf("unexpected membership diff: %+v", d)
+	}
+	info, _ := after.Node("node-4")
+	if math.Abs(d.Moved-info.Ownership) > 1e-9 {
+		t.Fatalf("moved %.4f, want ownership of new node %.4f", d.Moved, info.Ownership)
+	}
+	if len(d.MovedTo) != 1 || math.Abs(d.MovedTo["node-4"]-d.Moved) > 1e-9 {
+		t.Fatalf("all moved space should go to node-4: %v", d.MovedTo)
+	}
+
+	// 采样验证估算值
+	moved := 0
+	for i := 0; i < 50000; i++ {
+		h := m.config.HashFunc([]byte(fmt.Sprintf("key-%d", i)))
+		if before.Owner(h) != after.Owner(h) {
+			moved++
+		}
+	}
+	if sampled := float64(moved) / 50000; math.Abs(sampled-d.Moved) > 0.02 {
+		t.Fatalf("estimated %.3f, sampled %.3f", d.Moved, sampled)
+	}
+}
+
+// TestDiff_Identical 相同的环没有迁移
+func TestDiff_Identical(t *testing.T) {
+	m := New()
+	m.Add("a", "b")
+	if d := Diff(m.Snapshot(), m.Snapshot()); d.Moved != 0 {
+		t.Fatalf("moved %.4f, want 0", d.Moved)
+	}
+	if d := Diff(Snapshot{}, Snapshot{}); d.Moved != 0 {
+		t.Fatal("empty snapshots should not move")
+	}
+}
@@ -0,0 +1,141 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/crypt0walker/BlockCache/registry"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 缓存错误与gRPC状态码的映射
+// 服务端把这些错误转换成对应的状态码，并在ErrorInfo详情中带上原因；
+// Client收到后还原成同样的错误，调用方可以用errors.Is判断，
+// 也可以用status.Code取得状态码。
+
+var (
+	// ErrNotFound key不存在，Getter在数据源中找不到key时应返回（或包装）该错误
+	ErrNotFound = errors.New("key not found")
+	// ErrGroupNotFound 节点上没有该缓存组
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupClosed 缓存组已关闭
+	ErrGroupClosed = errors.New("group is closed")
+	// ErrInvalidArgument key或value为空等非法参数
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrBackend 回源（Getter）失败
+	ErrBackend = errors.New("backend failure")
+)
+
+// errorDomain ErrorInfo中的domain
+const errorDomain = "blockcache"
+
+// cacheErrors 错误、状态码和ErrorInfo原因的对应关系
+var cacheErrors = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{ErrNotFound, codes.NotFound, "KEY_NOT_FOUND"},
+	{ErrGroupNotFound, codes.NotFound, "GROUP_NOT_FOUND"},
+	{ErrGroupClosed, codes.FailedPrecondition, "GROUP_CLOSED"},
+	{ErrInvalidArgument, codes.InvalidArgument, "INVALID_ARGUMENT"},
+	// 回源失败不用UNAVAILABLE：它在gRPC透明重试和对冲重试的列表中，数据源故障时每个请求都会被放大成多次回源，
+	// UNAVAILABLE只留给连接失败、节点下线等传输层错误
+	{ErrBackend, codes.Internal, "BACKEND_FAILURE"},
+	{registry.ErrGroupExists, codes.AlreadyExists, "GROUP_EXISTS"},
+	{registry.ErrGroupUndefined, codes.NotFound, "GROUP_UNDEFINED"},
+}
+
+// toStatus 服务端把错误转换为带详情的gRPC状态
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	// 已经是gRPC状态（如拦截器返回的认证错误）时原样返回
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	for _, e := range cacheErrors {
+		if !errors.Is(err, e.err) {
+			continue
+		}
+		st, detailErr := status.New(e.code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+			Reason: e.reason,
+			Domain: errorDomain,
+		})
+		if detailErr != nil {
+			return status.Error(e.code, err.Error())
+		}
+		return st.Err()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// statusError 保留gRPC状态的缓存错误，errors.Is可以匹配到对应的哨兵错误
+type statusError struct {
+	st  *status.Status
+	err error
+}
+
+func (e *statusError) Error() string              { return e.st.Message() }
+func (e *statusError) Unwrap() error              { return e.err }
+func (e *statusError) GRPCStatus() *status.Status { return e.st }
+
+// fromStatus Client把服务端返回的状态还原成缓存错误，无法识别时原样返回
+func fromStatus(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() == codes.OK {
+		return err
+	}
+	for _, d := range st.Details() {
+		info, ok := d.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != errorDomain {
+			continue
+		}
+		for _, e := range cacheErrors {
+			if e.reason == info.GetReason() {
+				return &statusError{st: st, err: e.err}
+			}
+		}
+	}
+	return err
+}
+
+// isPeerFailure 判断错误是否说明节点本身有问题（用于熔断）
+// 节点正常给出的业务结果（key不存在、参数错误、回源失败、无权限）不计入；
+// 服务端返回的Unknown是处理器自己的错误（如旧版本节点未分类的回源失败），节点本身是可达的，
+// 只有本地产生的非gRPC错误才按失败计
+func isPeerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, e := range cacheErrors {
+		if errors.Is(err, e.err) {
+			return false
+		}
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return true
+	}
+	switch st.Code() {
+	case codes.NotFound, codes.InvalidArgument, codes.FailedPrecondition, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.OutOfRange, codes.Unknown:
+		return false
+	}
+	return true
+}
+
+// groupNotFound 返回指定组不存在的错误
+func groupNotFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrGroupNotFound, name)
+}
@@ -0,0 +1,132 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestStatusRoundTrip 服务端的缓存错误经gRPC状态还原后仍能用errors.Is匹配
+func TestStatusRoundTrip(t *testing.T) {
+	for _, e := range cacheErrors {
+		wrapped := fmt.Errorf("context: %w", e.err)
+		st := toStatus(wrapped)
+		if status.Code(st) != e.code {
+			t.Fatalf("%v: code = %v, want %v", e.err, status.Code(st), e.code)
+		}
+		back := fromStatus(st)
+		if !errors.Is(back, e.err) {
+			t.Fatalf("%v: restored error %v does not match", e.err, back)
+		}
+		if status.Code(back) != e.code {
+			t.Fatalf("%v: restored code = %v, want %v", e.err, status.Code(back), e.code)
+		}
+	}
+
+	if code := status.Code(toStatus(context.DeadlineExceeded)); code != codes.DeadlineExceeded {
+		t.Fatalf("deadline code = %v", code)
+	}
+	if code := status.Code(toStatus(errors.New("boom"))); code != codes.Unknown {
+		t.Fatalf("unknown error code = %v", code)
+	}
+}
+
+// TestClient_TypedErrors 通过Client调用时能区分各类错误
+func TestClient_TypedErrors(t *testing.T) {
+	g := NewGroup("typed-errors", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		switch key {
+		case "missing":
+			return nil, fmt.Errorf("no row for %s: %w", key, ErrNotFound)
+		case "broken":
+			return nil, errors.New("connection refused")
+		}
+		return []byte("v"), nil
+	}))
+	defer g.Close()
+
+	client, err := NewClient(startTestServer(t), "test", nil,
+		WithClientDialTimeout(time.Second), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		call  func() error
+		want  error
+		code  codes.Code
+		extra string
+	}{
+		{"key not found", func() error { _, err := client.Get(ctx, "typed-errors", "missing"); return err }, ErrNotFound, codes.NotFound, ""},
+		{"backend failure", func() error { _, err := client.Get(ctx, "typed-errors", "broken"); return err }, ErrBackend, codes.Internal, "connection refused"},
+		{"group not found", func() error { _, err := client.Get(ctx, "no-such-group", "k"); return err }, ErrGroupNotFound, codes.NotFound, "no-such-group"},
+		{"invalid argument", func() error { return client.Set(ctx, "typed-errors", "k", nil) }, ErrInvalidArgument, codes.InvalidArgument, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if status.Code(err) != tt.code {
+				t.Fatalf("code = %v, want %v", status.Code(err), tt.code)
+			}
+			// 还原出的错误同时排除其他类型
+			if tt.want != ErrNotFound && errors.Is(err, ErrNotFound) {
+				t.Fatalf("%v should not match ErrNotFound", err)
+			}
+			if tt.extra != "" && !strings.Contains(err.Error(), tt.extra) {
+				t.Fatalf("err %q lost detail %q", err, tt.extra)
+			}
+		})
+	}
+
+	g.Close()
+	if _, err := client.Get(ctx, "typed-errors", "k"); !errors.Is(err, ErrGroupNotFound) {
+		// 关闭后的组已从全局映射中移除
+		t.Fatalf("closed group: err = %v", err)
+	}
+}
+
+// TestBreaker_IgnoresApplicationErrors key不存在等业务错误不会让熔断器打开
+func TestBreaker_IgnoresApplicationErrors(t *testing.T) {
+	b := newCircuitBreaker(BreakerOptions{ConsecutiveFailures: 2, OpenTimeout: time.Minute})
+	notFound := fromStatus(toStatus(ErrNotFound))
+	for i := 0; i < 5; i++ {
+		b.Allow()
+		b.Record(notFound)
+	}
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %v after not-found responses", b.State())
+	}
+
+	for i := 0; i < 2; i++ {
+		b.Allow()
+		b.Record(status.Error(codes.Unavailable, "down"))
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %v after transport failures", b.State())
+	}
+}
+
+// TestToStatus_BackendNotRetried 回源失败的状态码不在任何重试列表中
+func TestToStatus_BackendNotRetried(t *testing.T) {
+	err := toStatus(fmt.Errorf("%w: db down", ErrBackend))
+	for _, c := range DefaultClientOptions().Retry.RetryableCodes {
+		if status.Code(err) == c {
+			t.Fatalf("backend failure %v is retried by the gRPC retry policy", c)
+		}
+	}
+	s := &peerPolicyState{policy: DefaultPeerPolicy()}
+	if s.retryable("get", err) {
+		t.Fatalf("backend failure %v is retried by the peer policy", status.Code(err))
+	}
+}
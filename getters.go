@@ -0,0 +1,262 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 内置Getter
+// 不写Go代码也能让节点作为读穿透缓存使用：未命中时从HTTP源站、本地目录或实现了Origin服务的gRPC数据源加载。
+// 数据源明确表示key不存在时返回ErrNotFound，其余失败由Group归为ErrBackend。
+
+// defaultMaxValueBytes 单个值的默认大小上限，与gRPC默认的最大消息大小一致
+const defaultMaxValueBytes = 4 << 20
+
+// HTTPGetterOptions HTTP源站配置
+type HTTPGetterOptions struct {
+	// URL 地址模板，{key}替换为路径转义后的key；{rawkey}替换为原样的key，用于把key中的"/"作为路径分隔符，
+	// 此时key只能包含字母、数字、"-._~/"，且不能有"."或".."路径段
+	URL string
+	// Header 每个请求附带的请求头
+	Header http.Header
+	// Timeout 单次请求超时，0表示只受调用方ctx控制
+	Timeout time.Duration
+	// MaxBytes 响应体大小上限，0表示4MB
+	MaxBytes int64
+	// Client 发送请求的HTTP客户端，nil表示http.DefaultClient
+	Client *http.Client
+}
+
+// HTTPGetter 从HTTP源站GET数据，404和410视为key不存在
+type HTTPGetter struct {
+	opts HTTPGetterOptions
+}
+
+// NewHTTPGetter 创建HTTP源站Getter
+func NewHTTPGetter(opts HTTPGetterOptions) (*HTTPGetter, error) {
+	if !strings.Contains(opts.URL, "{key}") && !strings.Contains(opts.URL, "{rawkey}") {
+		return nil, fmt.Errorf("%w: url template %q has no {key}", ErrInvalidArgument, opts.URL)
+	}
+	if _, err := url.Parse(strings.NewReplacer("{key}", "k", "{rawkey}", "k").Replace(opts.URL)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxValueBytes
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	return &HTTPGetter{opts: opts}, nil
+}
+
+// Get 实现Getter
+func (h *HTTPGetter) Get(ctx context.Context, key string) ([]byte, error) {
+	if h.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.opts.Timeout)
+		defer cancel()
+	}
+	if strings.Contains(h.opts.URL, "{rawkey}") && !rawKeySafe(key) {
+		return nil, fmt.Errorf("%w: key %q cannot be used in {rawkey}", ErrInvalidArgument, key)
+	}
+	target := strings.NewReplacer("{key}", url.PathEscape(key), "{rawkey}", key).Replace(h.opts.URL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range h.opts.Header {
+		req.Header[name] = values
+	}
+	resp, err := h.opts.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return nil, fmt.Errorf("%w: origin returned %s", ErrNotFound, resp.Status)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, fmt.Errorf("origin returned %s", resp.Status)
+	}
+	return readLimited(resp.Body, h.opts.MaxBytes)
+}
+
+// rawKeySafe 判断key能否原样放进URL路径：客户端传来的key中的"?"、"#"、"%"、".."等会改写发给源站的
+// 路径或查询参数，甚至让请求落到模板之外的接口上
+func rawKeySafe(key string) bool {
+	for _, r := range key {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case strings.ContainsRune("-._~/", r):
+		default:
+			return false
+		}
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "." || seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// FileGetterOptions 本地目录配置
+type FileGetterOptions struct {
+	// Dir 数据目录，key是其中的相对路径
+	Dir string
+	// Extension 拼接在key后面的扩展名，如".json"
+	Extension string
+	// MaxBytes 单个文件大小上限，0表示4MB
+	MaxBytes int64
+}
+
+// FileGetter 从本地目录读取文件，key为相对路径；
+// 不能通过".."或符号链接读取目录之外的文件
+type FileGetter struct {
+	root *os.Root
+	opts FileGetterOptions
+}
+
+// NewFileGetter 打开数据目录，用完需要Close
+func NewFileGetter(opts FileGetterOptions) (*FileGetter, error) {
+	root, err := os.OpenRoot(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultMaxValueBytes
+	}
+	return &FileGetter{root: root, opts: opts}, nil
+}
+
+// Get 实现Getter
+func (f *FileGetter) Get(ctx context.Context, key string) ([]byte, error) {
+	name := filepath.FromSlash(key + f.opts.Extension)
+	if !filepath.IsLocal(name) {
+		return nil, fmt.Errorf("%w: key %q is not a relative path", ErrInvalidArgument, key)
+	}
+	file, err := f.root.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err == nil && info.IsDir() {
+		return nil, fmt.Errorf("%w: %s is a directory", ErrNotFound, key)
+	}
+	return readLimited(file, f.opts.MaxBytes)
+}
+
+// Close 关闭数据目录
+func (f *FileGetter) Close() error {
+	return f.root.Close()
+}
+
+// GRPCGetterOptions gRPC数据源配置
+type GRPCGetterOptions struct {
+	// Addr 实现了pb.Origin服务的数据源地址
+	Addr string
+	// Group 放在LoadRequest中的组名，数据源可以据此区分不同的组
+	Group string
+	// Timeout 单次请求超时，0表示只受调用方ctx控制
+	Timeout time.Duration
+	// TLS 连接数据源使用的TLS配置，nil表示明文
+	TLS *TLSConfig
+	// Metadata 每个请求附带的gRPC元数据（如认证信息）
+	Metadata map[string]string
+}
+
+// GRPCGetter 通过pb.Origin服务回源，NOT_FOUND视为key不存在
+type GRPCGetter struct {
+	opts   GRPCGetterOptions
+	conn   *grpc.ClientConn
+	origin pb.OriginClient
+	md     metadata.MD
+	certs  *CertReloader
+}
+
+// NewGRPCGetter 创建gRPC数据源Getter，连接在首次请求时建立，用完需要Close
+func NewGRPCGetter(opts GRPCGetterOptions) (*GRPCGetter, error) {
+	creds := insecure.NewCredentials()
+	var certs *CertReloader
+	if opts.TLS != nil {
+		var err error
+		if certs, err = NewCertReloader(*opts.TLS); err != nil {
+			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
+		}
+		creds = certs.ClientCredentials()
+	}
+	conn, err := grpc.NewClient(opts.Addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		if certs != nil {
+			certs.Close()
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %v", opts.Addr, err)
+	}
+	return &GRPCGetter{
+		opts:   opts,
+		conn:   conn,
+		origin: pb.NewOriginClient(conn),
+		md:     metadata.New(opts.Metadata),
+		certs:  certs,
+	}, nil
+}
+
+// Get 实现Getter
+func (g *GRPCGetter) Get(ctx context.Context, key string) ([]byte, error) {
+	if g.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.opts.Timeout)
+		defer cancel()
+	}
+	if len(g.md) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, g.md)
+	}
+	resp, err := g.origin.Load(ctx, &pb.LoadRequest{Group: g.opts.Group, Key: key})
+	if status.Code(err) == codes.NotFound {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, status.Convert(err).Message())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return resp.GetValue(), nil
+}
+
+// Close 关闭到数据源的连接
+func (g *GRPCGetter) Close() error {
+	if g.certs != nil {
+		g.certs.Close()
+	}
+	return g.conn.Close()
+}
+
+// readLimited 读取至多limit字节，超过时报错而不是截断
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("value exceeds %d bytes", limit)
+	}
+	return data, nil
+}
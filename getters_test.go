@@ -0,0 +1,162 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crypt0walker/BlockCache/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TestHTTPGetter 按模板拼接URL，404映射为ErrNotFound，其余非2xx为普通错误
+func TestHTTPGetter(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/items/a%2Fb":
+			w.Write([]byte("value:" + r.URL.Path))
+		case "/items/big":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/items/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer origin.Close()
+
+	getter, err := NewHTTPGetter(HTTPGetterOptions{
+		URL:      origin.URL + "/items/{key}",
+		Header:   http.Header{"X-Token": {"t"}},
+		MaxBytes: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if v, err := getter.Get(ctx, "a/b"); err != nil || string(v) != "value:/items/a/b" {
+		t.Fatalf("Get(a/b) = %q, %v", v, err)
+	}
+	if _, err := getter.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) err = %v", err)
+	}
+	for _, key := range []string{"broken", "big"} {
+		if _, err := getter.Get(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Fatalf("Get(%s) err = %v", key, err)
+		}
+	}
+
+	if _, err := NewHTTPGetter(HTTPGetterOptions{URL: origin.URL + "/items"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("template without {key}: err = %v", err)
+	}
+
+	// {rawkey}保留key中的"/"，但不允许改写路径或查询参数的字符
+	raw, err := NewHTTPGetter(HTTPGetterOptions{URL: origin.URL + "/items/{rawkey}", Header: http.Header{"X-Token": {"t"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Get(ctx, "a/b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(a/b) via {rawkey} err = %v", err)
+	}
+	for _, key := range []string{"../admin", "a/./b", "big?x=1", "big#frag", "a%2Fb", "a b", "名字"} {
+		if _, err := raw.Get(ctx, key); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("Get(%q) via {rawkey} err = %v, want ErrInvalidArgument", key, err)
+		}
+	}
+}
+
+// TestFileGetter 读取目录下的文件，不允许访问目录之外
+func TestFileGetter(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "users"), 0o755)
+	os.WriteFile(filepath.Join(dir, "users", "1.json"), []byte(`{"id":1}`), 0o644)
+	os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.json"), []byte("secret"), 0o644)
+	defer os.Remove(filepath.Join(filepath.Dir(dir), "secret.json"))
+
+	getter, err := NewFileGetter(FileGetterOptions{Dir: dir, Extension: ".json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer getter.Close()
+	ctx := context.Background()
+
+	if v, err := getter.Get(ctx, "users/1"); err != nil || string(v) != `{"id":1}` {
+		t.Fatalf("Get(users/1) = %q, %v", v, err)
+	}
+	if _, err := getter.Get(ctx, "users/2"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(users/2) err = %v", err)
+	}
+	for _, key := range []string{"../secret", "/etc/passwd"} {
+		if v, err := getter.Get(ctx, key); !errors.Is(err, ErrInvalidArgument) {
+			t.Fatalf("Get(%s) = %q, %v, want ErrInvalidArgument", key, v, err)
+		}
+	}
+}
+
+// testOrigin 测试用的gRPC数据源
+type testOrigin struct {
+	pb.UnimplementedOriginServer
+}
+
+func (testOrigin) Load(ctx context.Context, req *pb.LoadRequest) (*pb.LoadResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if got := md.Get("x-token"); len(got) != 1 || got[0] != "t" {
+		return nil, status.Error(codes.Unauthenticated, "missing token")
+	}
+	if req.GetKey() == "missing" {
+		return nil, status.Error(codes.NotFound, "no such key")
+	}
+	return &pb.LoadResponse{Value: []byte(req.GetGroup() + ":" + req.GetKey())}, nil
+}
+
+// TestGRPCGetter 通过Origin服务回源，NOT_FOUND映射为ErrNotFound
+func TestGRPCGetter(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterOriginServer(gs, testOrigin{})
+	go gs.Serve(lis)
+	defer gs.Stop()
+
+	getter, err := NewGRPCGetter(GRPCGetterOptions{
+		Addr:     lis.Addr().String(),
+		Group:    "origin-group",
+		Metadata: map[string]string{"x-token": "t"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer getter.Close()
+
+	// 经过Group时，数据源的结果与Getter函数一致
+	g := NewGroup("grpc-getter", 1<<20, getter)
+	defer g.Close()
+	ctx := context.Background()
+	if v, err := g.Get(ctx, "k"); err != nil || v.String() != "origin-group:k" {
+		t.Fatalf("Get(k) = %q, %v", v.String(), err)
+	}
+	if _, err := g.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) err = %v", err)
+	}
+
+	noAuth, _ := NewGRPCGetter(GRPCGetterOptions{Addr: lis.Addr().String()})
+	defer noAuth.Close()
+	if _, err := noAuth.Get(ctx, "k"); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Get without metadata err = %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package blockcache
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 进程范围的内存预算
+// 每个组默认有自己固定的容量，组多了以后要么整体超配，要么忙的组被饿死。
+// 加入MemoryGovernor的组共享一个总预算：governor定期按权重或单位字节命中率给各组分配预算，
+// 正在淘汰数据的组可以分到更多，用不满的组多出的部分让给其他组。
+// 所有组的预算之和不超过总预算；重新分配时先缩小价值最低的组（按LRU顺序淘汰），再扩大其他组，
+// 因此总占用始终不超过总预算。
+
+// MemoryPolicy 预算分配策略
+type MemoryPolicy string
+
+const (
+	// PolicyHitRate 按单位字节的命中率（乘以权重）分配，命中多、占用少的组优先
+	PolicyHitRate MemoryPolicy = "hit_rate"
+	// PolicyWeight 只按权重分配
+	PolicyWeight MemoryPolicy = "weight"
+)
+
+// MemoryGovernorOptions 内存预算配置
+type MemoryGovernorOptions struct {
+	MaxBytes int64        // 所有受管组的总预算
+	Policy   MemoryPolicy // 分配策略，默认PolicyHitRate
+	Interval time.Duration
+	// MinBytes 每个组至少分到的预算，默认1MB；组太多时按总预算均分
+	MinBytes int64
+}
+
+// MemoryGovernor 在多个组之间分配进程范围的内存预算
+type MemoryGovernor struct {
+	opts    MemoryGovernorOptions
+	mu      sync.Mutex
+	members map[*Group]*governedGroup
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// governedGroup 受管组的分配状态
+type governedGroup struct {
+	weight float64
+	limit  int64 // 组自身的容量上限（NewGroup的cacheBytes或SetMaxBytes），预算不超过它，0表示不限制
+	budget int64
+	score  float64
+	// hitRate 每秒本地命中数的指数移动平均
+	hitRate       float64
+	lastHits      int64
+	lastEvictions int64
+	lastAt        time.Time
+}
+
+// hitRateAlpha 命中率移动平均中最新一次采样的权重
+const hitRateAlpha = 0.3
+
+// NewMemoryGovernor 创建内存预算并开始定期重新分配，不再使用时调用Close
+func NewMemoryGovernor(opts MemoryGovernorOptions) (*MemoryGovernor, error) {
+	if opts.MaxBytes <= 0 {
+		return nil, fmt.Errorf("%w: memory governor max bytes must be positive", ErrInvalidArgument)
+	}
+	switch opts.Policy {
+	case "":
+		opts.Policy = PolicyHitRate
+	case PolicyHitRate, PolicyWeight:
+	default:
+		return nil, fmt.Errorf("%w: unknown memory policy %q", ErrInvalidArgument, opts.Policy)
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.MinBytes <= 0 {
+		opts.MinBytes = 1 << 20
+	}
+
+	m := &MemoryGovernor{
+		opts:    opts,
+		members: make(map[*Group]*governedGroup),
+		stop:    make(chan struct{}),
+	}
+	go m.loop()
+	return m, nil
+}
+
+// WithMemoryGovernor 让组的容量由gov在总预算内动态分配，weight为组的权重（<=0时为1）；
+// NewGroup的cacheBytes和之后的SetMaxBytes作为该组预算的上限
+func WithMemoryGovernor(gov *MemoryGovernor, weight float64) GroupOption {
+	return func(g *Group) {
+		if weight <= 0 {
+			weight = 1
+		}
+		g.governor = gov
+		g.governorWeight = weight
+	}
+}
+
+func (m *MemoryGovernor) loop() {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			m.mu.Lock()
+			m.rebalance()
+			m.mu.Unlock()
+		}
+	}
+}
+
+// Close 停止重新分配，各组保留最后一次分到的预算
+func (m *MemoryGovernor) Close() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// SetMaxBytes 调整总预算并立即重新分配
+func (m *MemoryGovernor) SetMaxBytes(maxBytes int64) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("%w: memory governor max bytes must be positive", ErrInvalidArgument)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.opts.MaxBytes = maxBytes
+	m.rebalance()
+	return nil
+}
+
+// Rebalance 立即重新分配一次预算
+func (m *MemoryGovernor) Rebalance() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rebalance()
+}
+
+// add 组加入时立即重新分配，新组的预算不会让总占用超出总预算
+func (m *MemoryGovernor) add(g *Group, weight float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.members[g] = &governedGroup{
+		weight:        weight,
+		limit:         g.mainCache.StoreStats().MaxBytes,
+		lastHits:      atomic.LoadInt64(&g.stats.localHits),
+		lastEvictions: g.mainCache.StoreStats().Evictions,
+		lastAt:        time.Now(),
+	}
+	m.rebalance()
+}
+
+// remove 组关闭后把它的预算分给其他组
+func (m *MemoryGovernor) remove(g *Group) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.members[g]; !ok {
+		return
+	}
+	delete(m.members, g)
+	m.rebalance()
+}
+
+// setLimit 修改组的容量上限并重新分配
+func (m *MemoryGovernor) setLimit(g *Group, limit int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if gg, ok := m.members[g]; ok {
+		gg.limit = limit
+		m.rebalance()
+	}
+}
+
+// limitOf 返回组的容量上限，组不受管时ok为false
+func (m *MemoryGovernor) limitOf(g *Group) (limit int64, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	gg, ok := m.members[g]
+	if !ok {
+		return 0, false
+	}
+	return gg.limit, true
+}
+
+// allocation 一次分配中单个组的计算结果
+type allocation struct {
+	g      *Group
+	state  *governedGroup
+	score  float64
+	limit  int64 // 本轮预算上限，组没有容量上限时为总预算
+	demand int64 // 本轮希望得到的预算
+	budget int64
+}
+
+// rebalance 重新计算并应用所有组的预算，调用方持有m.mu
+func (m *MemoryGovernor) rebalance() {
+	if len(m.members) == 0 {
+		return
+	}
+	now := time.Now()
+	total := m.opts.MaxBytes
+	// 预算为0在存储层表示不限制，所以至少分1字节
+	floor := max(min(m.opts.MinBytes, total/int64(len(m.members))), 1)
+
+	allocs := make([]*allocation, 0, len(m.members))
+	remaining := total
+	for g, gg := range m.members {
+		cache := g.mainCache.StoreStats()
+		hits := atomic.LoadInt64(&g.stats.localHits)
+		if elapsed := now.Sub(gg.lastAt).Seconds(); elapsed > 0 {
+			rate := float64(hits-gg.lastHits) / elapsed
+			gg.hitRate = hitRateAlpha*rate + (1-hitRateAlpha)*gg.hitRate
+		}
+		evicting := cache.Evictions > gg.lastEvictions
+		gg.lastHits, gg.lastEvictions, gg.lastAt = hits, cache.Evictions, now
+
+		gg.score = gg.weight
+		if m.opts.Policy == PolicyHitRate {
+			// 加1让还没有命中的组也能分到预算
+			gg.score = gg.weight * (gg.hitRate + 1) / float64(max(cache.Bytes, m.opts.MinBytes))
+		}
+
+		limit := gg.limit
+		if limit <= 0 || limit > total {
+			limit = total
+		}
+		// 正在淘汰或快要用满的组希望得到尽可能多的预算，其余的组（包括刚加入的空组）
+		// 只需要当前占用加上一些余量，多出的预算在第二轮按score分配
+		demand := cache.Bytes + cache.Bytes/4
+		if evicting || (gg.budget > 0 && cache.Bytes >= gg.budget-gg.budget/10) {
+			demand = limit
+		}
+		a := &allocation{g: g, state: gg, score: gg.score, limit: limit, demand: min(demand, limit)}
+		a.budget = min(floor, limit)
+		remaining -= a.budget
+		allocs = append(allocs, a)
+	}
+
+	// 先满足各组的需求，再把剩下的预算分给还没到上限的组，让它们在下一轮之前可以增长
+	remaining = allocate(allocs, remaining, func(a *allocation) int64 { return a.demand })
+	allocate(allocs, remaining, func(a *allocation) int64 { return a.limit })
+
+	// 先缩小价值最低的组，腾出空间后再扩大其他组
+	sort.Slice(allocs, func(i, j int) bool { return allocs[i].score < allocs[j].score })
+	for _, a := range allocs {
+		if a.budget < a.g.mainCache.StoreStats().MaxBytes {
+			a.g.mainCache.SetMaxBytes(a.budget)
+		}
+	}
+	for _, a := range allocs {
+		if a.budget > a.g.mainCache.StoreStats().MaxBytes {
+			a.g.mainCache.SetMaxBytes(a.budget)
+		}
+		if a.budget != a.state.budget {
+			logrus.Debugf("memory governor: group %s budget %d -> %d", a.g.name, a.state.budget, a.budget)
+		}
+		a.state.budget = a.budget
+	}
+}
+
+// allocate 按score比例把remaining分给预算低于capOf的组，分满的组退出后剩余部分继续在其他组间分配，
+// 返回分不出去的预算
+func allocate(allocs []*allocation, remaining int64, capOf func(*allocation) int64) int64 {
+	active := make([]*allocation, 0, len(allocs))
+	for _, a := range allocs {
+		if a.budget < capOf(a) {
+			active = append(active, a)
+		}
+	}
+	for remaining > 0 && len(active) > 0 {
+		var scores float64
+		for _, a := range active {
+			scores += a.score
+		}
+		var given int64
+		next := active[:0]
+		for _, a := range active {
+			share := int64(float64(remaining) * a.score / scores)
+			if room := capOf(a) - a.budget; share >= room {
+				share = room
+			} else {
+				next = append(next, a)
+			}
+			a.budget += share
+			given += share
+		}
+		remaining -= given
+		// 没有组分满时剩下的只是取整误差
+		if len(next) == len(active) {
+			break
+		}
+		active = next
+	}
+	return remaining
+}
+
+// GovernorStats 内存预算的分配情况
+type GovernorStats struct {
+	MaxBytes  int64                `json:"maxBytes"`
+	UsedBytes int64                `json:"usedBytes"`
+	Groups    []GovernedGroupStats `json:"groups"`
+}
+
+// GovernedGroupStats 单个受管组的分配情况
+type GovernedGroupStats struct {
+	Group   string  `json:"group"`
+	Weight  float64 `json:"weight"`
+	HitRate float64 `json:"hitRate"` // 每秒本地命中数（移动平均）
+	Score   float64 `json:"score"`   // 最近一次分配时的价值，越高分到的预算越多
+	Budget  int64   `json:"budget"`
+	Bytes   int64   `json:"bytes"`
+	Limit   int64   `json:"limit"`
+}
+
+// Stats 返回各组的预算和占用，按组名排序
+func (m *MemoryGovernor) Stats() GovernorStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := GovernorStats{MaxBytes: m.opts.MaxBytes, Groups: make([]GovernedGroupStats, 0, len(m.members))}
+	for g, gg := range m.members {
+		used := g.mainCache.StoreStats().Bytes
+		stats.UsedBytes += used
+		stats.Groups = append(stats.Groups, GovernedGroupStats{
+			Group:   g.name,
+			Weight:  gg.weight,
+			HitRate: gg.hitRate,
+			Score:   gg.score,
+			Budget:  gg.budget,
+			Bytes:   used,
+			Limit:   gg.limit,
+		})
+	}
+	sort.Slice(stats.Groups, func(i, j int) bool { return stats.Groups[i].Group < stats.Groups[j].Group })
+	return stats
+}
@@ -0,0 +1,155 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newGovernedGroup(t *testing.T, gov *MemoryGovernor, name string, weight float64) *Group {
+	t.Helper()
+	g := NewGroup(name, 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithMemoryGovernor(gov, weight))
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+func newTestGovernor(t *testing.T, policy MemoryPolicy) *MemoryGovernor {
+	t.Helper()
+	gov, err := NewMemoryGovernor(MemoryGovernorOptions{MaxBytes: 1000, MinBytes: 100, Policy: policy, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(gov.Close)
+	return gov
+}
+
+// fill 写入n个约100字节的项
+func fill(g *Group, prefix string, n int) {
+	for i := range n {
+		g.Set(context.Background(), fmt.Sprintf("%s%02d", prefix, i), make([]byte, 96))
+	}
+}
+
+// budgets 返回各组当前的容量上限
+func budgets(groups ...*Group) []int64 {
+	out := make([]int64, len(groups))
+	for i, g := range groups {
+		out[i] = g.mainCache.StoreStats().MaxBytes
+	}
+	return out
+}
+
+// TestMemoryGovernor_Weights 按权重分配，总占用不超过总预算
+func TestMemoryGovernor_Weights(t *testing.T) {
+	gov := newTestGovernor(t, PolicyWeight)
+	a := newGovernedGroup(t, gov, "gov-weight-a", 3)
+	b := newGovernedGroup(t, gov, "gov-weight-b", 1)
+
+	// 两个组都在增长，按3:1分配最低预算之外的部分
+	if got := budgets(a, b); got[0] != 700 || got[1] != 300 {
+		t.Fatalf("budgets = %v, want [700 300]", got)
+	}
+	fill(a, "a", 20)
+	fill(b, "b", 20)
+	stats := gov.Stats()
+	if stats.UsedBytes > stats.MaxBytes || len(stats.Groups) != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// 关闭的组把预算让出来
+	b.Close()
+	if got := budgets(a); got[0] != 1000 {
+		t.Fatalf("budget after close = %v", got)
+	}
+}
+
+// TestMemoryGovernor_ReplaceGroup 同名组被替换后旧组不再占用预算
+func TestMemoryGovernor_ReplaceGroup(t *testing.T) {
+	gov := newTestGovernor(t, PolicyWeight)
+	a := newGovernedGroup(t, gov, "gov-replace-a", 1)
+	newGovernedGroup(t, gov, "gov-replace-b", 1)
+	b := newGovernedGroup(t, gov, "gov-replace-b", 1)
+
+	if stats := gov.Stats(); len(stats.Groups) != 2 {
+		t.Fatalf("governed groups = %+v", stats.Groups)
+	}
+	if got := budgets(a, b); got[0] != 500 || got[1] != 500 {
+		t.Fatalf("budgets = %v, want [500 500]", got)
+	}
+}
+
+// TestMemoryGovernor_IdleGroupYields 用不满预算的组把多出的部分让给正在淘汰的组
+func TestMemoryGovernor_IdleGroupYields(t *testing.T) {
+	gov := newTestGovernor(t, PolicyWeight)
+	busy := newGovernedGroup(t, gov, "gov-busy", 1)
+	idle := newGovernedGroup(t, gov, "gov-idle", 1)
+	fill(busy, "k", 20)
+	fill(idle, "k", 1)
+
+	// 空闲组只保留当前占用加上余量
+	gov.Rebalance()
+	idleBytes := idle.mainCache.StoreStats().Bytes
+	if got := budgets(busy, idle); got[1] > idleBytes+idleBytes/4 || got[0]+got[1] != 1000 {
+		t.Fatalf("budgets = %v with idle using %d bytes", got, idleBytes)
+	}
+}
+
+// TestMemoryGovernor_HitRate 命中多的组分到更多预算，超出总预算时从价值最低的组淘汰
+func TestMemoryGovernor_HitRate(t *testing.T) {
+	gov := newTestGovernor(t, PolicyHitRate)
+	hot := newGovernedGroup(t, gov, "gov-hot", 1)
+	cold := newGovernedGroup(t, gov, "gov-cold", 1)
+	fill(hot, "k", 4)
+	fill(cold, "k", 4)
+	ctx := context.Background()
+	for range 1000 {
+		hot.Get(ctx, "k00")
+	}
+
+	gov.Rebalance()
+	got := budgets(hot, cold)
+	if got[0] <= got[1] || got[0]+got[1] > 1000 {
+		t.Fatalf("budgets = %v, want hot > cold", got)
+	}
+	fill(hot, "n", 10)
+	if used := gov.Stats().UsedBytes; used > 1000 {
+		t.Fatalf("used %d bytes over the 1000 byte budget", used)
+	}
+
+	// 缩小总预算时先淘汰价值低的组
+	if err := gov.SetMaxBytes(400); err != nil {
+		t.Fatal(err)
+	}
+	if stats := gov.Stats(); stats.UsedBytes > 400 {
+		t.Fatalf("used %d bytes after shrinking to 400", stats.UsedBytes)
+	}
+	if got := budgets(hot, cold); got[0] <= got[1] || cold.mainCache.StoreStats().Evictions == 0 {
+		t.Fatalf("budgets = %v, want the cold group shrunk first", got)
+	}
+}
+
+// TestMemoryGovernor_GroupLimit 受管组的SetMaxBytes设置的是预算上限
+func TestMemoryGovernor_GroupLimit(t *testing.T) {
+	gov := newTestGovernor(t, PolicyWeight)
+	a := newGovernedGroup(t, gov, "gov-limit-a", 1)
+	b := newGovernedGroup(t, gov, "gov-limit-b", 1)
+
+	if err := a.SetMaxBytes(200); err != nil {
+		t.Fatal(err)
+	}
+	if got := budgets(a, b); got[0] != 200 || got[1] != 800 {
+		t.Fatalf("budgets = %v, want [200 800]", got)
+	}
+}
+
+func TestNewMemoryGovernor_Invalid(t *testing.T) {
+	for _, opts := range []MemoryGovernorOptions{{}, {MaxBytes: 1, Policy: "lfu"}} {
+		if _, err := NewMemoryGovernor(opts); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%+v: err = %v", opts, err)
+		}
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/crypt0walker/BlockCache/store"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/singleflight"
 )
 
@@ -30,8 +33,19 @@ type Group struct {
 	peers      PeerPicker
 	loader     *singleflight.Group
 	expiration time.Duration
+	//getter和expiration可以被组定义注册表在线修改，创建之后的读写都要持有它
+	settingsMu sync.RWMutex
 	closed     int32
 	stats      groupStats
+	//哈希环变化时的key迁移，nil表示未开启
+	handoff *handoffState
+	//访问其他节点的重试与对冲策略，nil表示只请求一次
+	peerPolicy *peerPolicyState
+	//追踪，未开启时为no-op
+	tracer trace.Tracer
+	//进程范围的内存预算，nil表示容量固定
+	governor       *MemoryGovernor
+	governorWeight float64
 }
 
 // groupStats 保存组的统计信息
@@ -44,6 +58,13 @@ type groupStats struct {
 	loaderHits   int64 // 从加载器获取成功次数
 	loaderErrors int64 // 从加载器获取失败次数
 	loadDuration int64 // 加载总耗时（纳秒）
+	peerRetries  int64 // 访问其他节点的重试次数
+	hedges       int64 // 发出的对冲请求数
+	hedgeWins    int64 // 对冲请求先于原请求返回的次数
+	//延迟分布
+	localHitLatency latencyRecorder
+	peerLatency     latencyRecorder
+	loaderLatency   latencyRecorder
 }
 
 // 需要有一个回源查询接口
@@ -84,25 +105,69 @@ func NewGroup(name string, cacheBytes int64, getter Getter, opts ...GroupOption)
 		name:      name,
 		// 它的意思是：使用 singleflight 包里的 Group 结构体，创建一个新对象
 		loader: &singleflight.Group{},
+		tracer: noopTracer,
 	}
+	g.stats.initLatencies(0)
 
 	//函数选项模式
 	for _, opt := range opts {
 		opt(g)
 	}
+	//通过WIthPeers传入的选择器也需要订阅环变化
+	if g.peers != nil {
+		g.subscribeHandoff(g.peers)
+	}
+	//加入内存预算后容量由governor分配，cacheBytes作为上限
+	if g.governor != nil {
+		g.governor.add(g, g.governorWeight)
+	}
 	//注册到全局映射,写锁来保证并发安全
 	groupsMu.Lock()
-	defer groupsMu.Unlock()
-
-	if _, exists := groups[name]; exists {
+	old, exists := groups[name]
+	if exists {
 		logrus.Warnf("Group with name %s already exists, will be replaced", name)
 	}
 	groups[name] = g
+	groupsMu.Unlock()
+	// 被替换的组不再参与预算分配，否则它会一直占着一份预算
+	if exists && old.governor != nil {
+		old.governor.remove(old)
+	}
 	logrus.Infof("Group %s created, with cacheBytes=%d, expiration=%v", name, cacheBytes, g.expiration)
 
 	return g
 }
 
+// defaultTTL 返回没有指定TTL的数据使用的过期时间
+func (g *Group) defaultTTL() time.Duration {
+	g.settingsMu.RLock()
+	defer g.settingsMu.RUnlock()
+	return g.expiration
+}
+
+// source 返回当前的数据源
+func (g *Group) source() Getter {
+	g.settingsMu.RLock()
+	defer g.settingsMu.RUnlock()
+	return g.getter
+}
+
+// setExpiration 修改默认过期时间，只影响之后写入的数据
+func (g *Group) setExpiration(expiration time.Duration) {
+	g.settingsMu.Lock()
+	defer g.settingsMu.Unlock()
+	g.expiration = expiration
+}
+
+// swapGetter 替换数据源并返回原来的数据源，正在进行的加载仍使用原来的数据源
+func (g *Group) swapGetter(getter Getter) Getter {
+	g.settingsMu.Lock()
+	defer g.settingsMu.Unlock()
+	old := g.getter
+	g.getter = getter
+	return old
+}
+
 // 几个用于创建函数选项模式的选项函数
 func WithExpiration(expiration time.Duration) GroupOption {
 	return func(g *Group) {
@@ -127,6 +192,7 @@ func (g *Group) RegisterPeerPicker(peers PeerPicker) {
 		panic("nil peer picker")
 	}
 	g.peers = peers
+	g.subscribeHandoff(peers)
 	logrus.Infof("Peer picker for group %s registered", g.name)
 }
 
@@ -148,29 +214,40 @@ func GetGroup(name string) *Group {
 }
 
 // 从缓存中获取数据，返回值使用ByteView而不是[]byte的原因是其是只读视图，否则[]byte返回的是引用，其就能够修改其中的值，不安全
-func (g *Group) Get(ctx context.Context, key string) (ByteView, error) {
+func (g *Group) Get(ctx context.Context, key string) (view ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, "Group.Get", trace.WithAttributes(
+		attribute.String("blockcache.group", g.name), keyAttribute(key)))
+	defer func() { endSpan(span, err) }()
+
 	//检查group是否已经关闭,原子判断
 	if atomic.LoadInt32(&g.closed) == 1 {
-		return ByteView{}, errors.New("group is closed")
+		return ByteView{}, ErrGroupClosed
 	}
 
 	if key == "" {
-		return ByteView{}, errors.New("key is empty")
+		return ByteView{}, fmt.Errorf("%w: key is empty", ErrInvalidArgument)
 	}
 
 	//先尝试从本地缓存中获取数据
+	start := time.Now()
 	if val, ok := g.mainCache.Get(ctx, key); ok {
 		//统计数据记录
 		atomic.AddInt64(&g.stats.localHits, 1)
+		g.stats.localHitLatency.observe(time.Since(start))
+		span.SetAttributes(attribute.Bool("blockcache.cache_hit", true))
 		return val, nil
 	}
+	span.SetAttributes(attribute.Bool("blockcache.cache_hit", false))
 	//本地缓存未命中，尝试从对等节点获取
 	return g.load(ctx, key)
 }
 
 // 从远端节点获取数据
 // 从远端节点获取数据
-func (g *Group) load(ctx context.Context, key string) (ByteView, error) {
+func (g *Group) load(ctx context.Context, key string) (view ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, "Group.load")
+	defer func() { endSpan(span, err) }()
+
 	// 使用singlefilight，确保并发请求只加载一次
 	startTime := time.Now()
 
@@ -180,13 +257,14 @@ func (g *Group) load(ctx context.Context, key string) (ByteView, error) {
 	})
 
 	var viewi interface{}
-	var err error
 
 	select {
 	case <-ctx.Done():
 		return ByteView{}, ctx.Err()
 	case ret := <-ch:
 		viewi, err = ret.Val, ret.Err
+		//与其他并发请求共享了同一次加载
+		span.SetAttributes(attribute.Bool("blockcache.shared", ret.Shared))
 	}
 
 	//记录加载时间与次数
@@ -203,11 +281,11 @@ func (g *Group) load(ctx context.Context, key string) (ByteView, error) {
 	}
 
 	//类型断言
-	view := viewi.(ByteView)
+	view = viewi.(ByteView)
 
 	//设置到本地缓存
-	if g.expiration > 0 {
-		g.mainCache.AddWithExpiration(key, view, time.Now().Add(g.expiration))
+	if expiration := g.defaultTTL(); expiration > 0 {
+		g.mainCache.AddWithExpiration(key, view, time.Now().Add(expiration))
 	} else {
 		g.mainCache.Add(key, view)
 	}
@@ -215,27 +293,58 @@ func (g *Group) load(ctx context.Context, key string) (ByteView, error) {
 }
 
 // 实际加载数据的方法
-func (g *Group) loadData(ctx context.Context, key string) (ByteView, error) {
+func (g *Group) loadData(ctx context.Context, key string) (view ByteView, err error) {
+	ctx, span := g.tracer.Start(ctx, "Group.loadData")
+	defer func() { endSpan(span, err) }()
+
 	//尝试从远端节点获取（此前已经尝试过本地缓存）
-	if g.peers != nil {
+	//来自其他节点的请求不再转发，避免各节点路由不一致时请求来回转发
+	if g.peers != nil && ctx.Value("from_peer") == nil {
 		peer, ok, isSelf := g.peers.PickPeer(key)
+		if ok && isSelf {
+			//路由到自己时本地回源也计入自己的在途负载
+			if tracker, ok := g.peers.(localLoadTracker); ok {
+				defer tracker.trackLocal()()
+			}
+		}
 		if ok && !isSelf {
 			//正常且不是数据存储节点不是自己，则从对等节点获取数据
-			value, err := peer.Get(ctx, g.name, key)
+			span.SetAttributes(attribute.String("blockcache.source", "peer"))
+			start := time.Now()
+			value, err := g.getFromPeer(ctx, peer, key)
+			elapsed := time.Since(start)
+			g.stats.peerLatency.observe(elapsed)
+			loadDurationSeconds.WithLabelValues(g.name, "peer").Observe(elapsed.Seconds())
 			if err == nil {
 				//统计数据记录
 				atomic.AddInt64(&g.stats.peerHits, 1)
-				return ByteView{data: value}, nil
+				return value, nil
 			}
 			//统计数据记录
 			atomic.AddInt64(&g.stats.peerMisses, 1)
+			//负责节点已经确认数据源中没有该key，不必再回源一次
+			if errors.Is(err, ErrNotFound) {
+				return ByteView{}, err
+			}
 			logrus.Errorf("Failed to get from peer: %v", err)
+			span.RecordError(err)
 		}
 	}
 	// 本地节点尝试从数据源加载
-	bytes, err := g.getter.Get(ctx, key)
+	span.SetAttributes(attribute.String("blockcache.source", "getter"))
+	getterCtx, getterSpan := g.tracer.Start(ctx, "Getter.Get")
+	start := time.Now()
+	bytes, err := g.source().Get(getterCtx, key)
+	endSpan(getterSpan, err)
+	elapsed := time.Since(start)
+	g.stats.loaderLatency.observe(elapsed)
+	loadDurationSeconds.WithLabelValues(g.name, "getter").Observe(elapsed.Seconds())
 	if err != nil {
-		return ByteView{}, fmt.Errorf("failed to get from getter: %v", err)
+		//key不存在或调用方取消时保留原错误，其余归为回源失败
+		if errors.Is(err, ErrNotFound) || ctx.Err() != nil {
+			return ByteView{}, fmt.Errorf("failed to get from getter: %w", err)
+		}
+		return ByteView{}, fmt.Errorf("%w: %w", ErrBackend, err)
 	}
 	//统计数据记录
 	atomic.AddInt64(&g.stats.loaderHits, 1)
@@ -243,9 +352,14 @@ func (g *Group) loadData(ctx context.Context, key string) (ByteView, error) {
 	return ByteView{data: cloneBytes(bytes)}, nil
 }
 
-// getFromPeer 从其他节点获取数据
+// getFromPeer 按重试与对冲策略从其他节点获取数据
 func (g *Group) getFromPeer(ctx context.Context, peer Peer, key string) (ByteView, error) {
-	bytes, err := peer.Get(ctx, g.name, key)
+	var bytes []byte
+	err := g.callPeer(ctx, "get", func(ctx context.Context) error {
+		var err error
+		bytes, err = g.hedgedGet(ctx, peer, key)
+		return err
+	})
 	if err != nil {
 		return ByteView{}, fmt.Errorf("failed to get from peer: %w", err)
 	}
@@ -254,14 +368,19 @@ func (g *Group) getFromPeer(ctx context.Context, peer Peer, key string) (ByteVie
 
 // 若cache功能不仅限于读取，还涉及更新于设置等，以下是扩展方法
 func (g *Group) Set(ctx context.Context, key string, value []byte) error {
+	return g.SetWithTTL(ctx, key, value, 0)
+}
+
+// SetWithTTL 写入并指定存活时间，ttl<=0时使用组的默认过期时间
+func (g *Group) SetWithTTL(ctx context.Context, key string, value []byte, ttl time.Duration) error {
 	//不能简单的：g.mainCache.Add(key, value)，而是要考虑很多问题
 	// 1. 防御性编程：原子检查group是否已关闭 & 参数是否为空
 	if atomic.LoadInt32(&g.closed) == 1 {
 		//如果group已关闭，直接返回错误
-		return errors.New("cache group is closed")
+		return ErrGroupClosed
 	}
 	if key == "" || len(value) == 0 {
-		return errors.New("key is empty or value is empty")
+		return fmt.Errorf("%w: key is empty or value is empty", ErrInvalidArgument)
 	}
 	// 2. 分布式缓存中需要防止死循环&更新的广播风暴（将广播再广播）
 	// 判断是否来自peer节点的set请求
@@ -271,8 +390,11 @@ func (g *Group) Set(ctx context.Context, key string, value []byte) error {
 	view := ByteView{data: cloneBytes(value)}
 
 	// 4. 设置到本地缓存，包含过期时间处理逻辑
-	if g.expiration > 0 {
-		g.mainCache.AddWithExpiration(key, view, time.Now().Add(g.expiration))
+	if ttl <= 0 {
+		ttl = g.defaultTTL()
+	}
+	if ttl > 0 {
+		g.mainCache.AddWithExpiration(key, view, time.Now().Add(ttl))
 	} else {
 		g.mainCache.Add(key, view)
 	}
@@ -280,11 +402,11 @@ func (g *Group) Set(ctx context.Context, key string, value []byte) error {
 	// 2. 防止广播风暴，只有不是来自peer节点set，同时有peerpicker才同步
 	if !isPeerRequest && g.peers != nil {
 		// 开启一个异步协程进行节点同步
-		go g.syncToPeers(ctx, "set", key, value)
+		go g.syncToPeers(ctx, "set", key, value, ttl)
 	}
 	return nil
 }
-func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []byte) {
+func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []byte, ttl time.Duration) {
 	//1. 前置检查：是否有节点选择器
 	if g.peers == nil {
 		return
@@ -302,15 +424,23 @@ func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []
 	//3. 创建同步的上下文标记
 	syncCtx := context.WithValue(context.Background(), "from_peer", true)
 
-	//4. 执行远程调用
-	var err error
-	//两种情况
-	switch op {
-	case "set":
-		err = peer.Set(syncCtx, g.name, key, value)
-	case "delete":
-		_, err = peer.Delete(g.name, key)
-	}
+	//4. 执行远程调用，按策略重试
+	err := g.callPeer(syncCtx, op, func(ctx context.Context) error {
+		var err error
+		//两种情况
+		switch op {
+		case "set":
+			//支持TTL的节点带上存活时间，否则由对方按自己的默认过期时间处理
+			if tp, ok := peer.(ttlPeer); ok && ttl > 0 {
+				err = tp.SetWithTTL(ctx, g.name, key, value, ttl)
+			} else {
+				err = peer.Set(ctx, g.name, key, value)
+			}
+		case "delete":
+			_, err = peer.Delete(ctx, g.name, key)
+		}
+		return err
+	})
 
 	if err != nil {
 		logrus.Errorf("Failed to sync %s to peer: %v", op, err)
@@ -319,12 +449,12 @@ func (g *Group) syncToPeers(ctx context.Context, op string, key string, value []
 func (g *Group) Delete(ctx context.Context, key string) error {
 	//1. 前置检查：group是否已经关闭
 	if atomic.LoadInt32(&g.closed) == 1 {
-		return errors.New("cache group is closed")
+		return ErrGroupClosed
 	}
 
 	//2. 参数检查
 	if key == "" {
-		return errors.New("key is empty")
+		return fmt.Errorf("%w: key is empty", ErrInvalidArgument)
 	}
 
 	//3. 从本地缓存删除
@@ -334,7 +464,7 @@ func (g *Group) Delete(ctx context.Context, key string) error {
 	isPeerRequest := ctx.Value("from_peer") != nil
 	if !isPeerRequest && g.peers != nil {
 		//开启一个异步协程对指定的节点同步
-		go g.syncToPeers(ctx, "delete", key, nil)
+		go g.syncToPeers(ctx, "delete", key, nil, 0)
 	}
 	return nil
 }
@@ -350,6 +480,47 @@ func (g *Group) Clear() {
 	logrus.Infof("[KamaCache] cleared cache for group [%s]", g.name)
 }
 
+// SetMaxBytes 在线调整本地缓存的容量上限，缩小时按LRU顺序淘汰，扩大时立即生效；
+// 加入了MemoryGovernor的组调整的是预算上限
+func (g *Group) SetMaxBytes(maxBytes int64) error {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ErrGroupClosed
+	}
+	if maxBytes <= 0 {
+		return fmt.Errorf("%w: max bytes must be positive", ErrInvalidArgument)
+	}
+	//受内存预算管理的组只调整预算上限，实际容量由governor重新分配
+	if g.governor != nil {
+		g.governor.setLimit(g, maxBytes)
+		return nil
+	}
+	g.mainCache.SetMaxBytes(maxBytes)
+	logrus.Infof("[KamaCache] group [%s] resized to %d bytes", g.name, maxBytes)
+	return nil
+}
+
+// maxBytes 返回SetMaxBytes设置的容量上限：受内存预算管理的组是预算上限，而不是当前分到的预算
+func (g *Group) maxBytes() int64 {
+	if g.governor != nil {
+		if limit, ok := g.governor.limitOf(g); ok {
+			return limit
+		}
+	}
+	return g.mainCache.StoreStats().MaxBytes
+}
+
+// SetCacheType 在线切换本地缓存的存储类型，已有数据迁移到新存储
+func (g *Group) SetCacheType(cacheType store.CacheType) error {
+	if atomic.LoadInt32(&g.closed) == 1 {
+		return ErrGroupClosed
+	}
+	if cacheType != store.LRU && cacheType != store.LRU2 {
+		return fmt.Errorf("%w: unknown cache type %q", ErrInvalidArgument, cacheType)
+	}
+	g.mainCache.SetCacheType(cacheType)
+	return nil
+}
+
 // Close 关闭组并释放资源
 func (g *Group) Close() error {
 	// 如果已经关闭，直接返回
@@ -358,14 +529,24 @@ func (g *Group) Close() error {
 		return nil
 	}
 
+	// 不再接收环变化通知
+	g.unsubscribeHandoff()
+
+	// 把预算让给其他组
+	if g.governor != nil {
+		g.governor.remove(g)
+	}
+
 	// 关闭本地缓存（级联关闭，下属也得关闭）
 	if g.mainCache != nil {
 		g.mainCache.Close()
 	}
 
-	// 从全局组映射中移除
+	// 从全局组映射中移除，同名的组已被替换时不影响新组
 	groupsMu.Lock()
-	delete(groups, g.name)
+	if groups[g.name] == g {
+		delete(groups, g.name)
+	}
 	groupsMu.Unlock()
 
 	logrus.Infof("[KamaCache] closed cache group [%s]", g.name)
@@ -386,42 +567,48 @@ func ListGroups() []string {
 }
 
 // DestroyGroup 销毁指定名称的缓存组
+// Close会再次获取groupsMu，所以先在锁内摘除，再在锁外关闭
 func DestroyGroup(name string) bool {
 	groupsMu.Lock()
-	defer groupsMu.Unlock()
+	g, exists := groups[name]
+	delete(groups, name)
+	groupsMu.Unlock()
 
-	if g, exists := groups[name]; exists {
-		g.Close()
-		delete(groups, name)
-		logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
-		return true
+	if !exists {
+		return false
 	}
-
-	return false
+	g.Close()
+	logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
+	return true
 }
 
 // DestroyAllGroups 销毁所有缓存组
 func DestroyAllGroups() {
 	groupsMu.Lock()
-	defer groupsMu.Unlock()
+	all := groups
+	groups = make(map[string]*Group)
+	groupsMu.Unlock()
 
-	for name, g := range groups {
+	for name, g := range all {
 		g.Close()
-		delete(groups, name)
 		logrus.Infof("[KamaCache] destroyed cache group [%s]", name)
 	}
 }
 
 // GroupStats 导出的统计信息结构
 type GroupStats struct {
-	Loads        int64 // 加载次数
-	LocalHits    int64 // 本地缓存命中次数
-	LocalMisses  int64 // 本地缓存未命中次数
-	PeerHits     int64 // 从对等节点获取成功次数
-	PeerMisses   int64 // 从对等节点获取失败次数
-	LoaderHits   int64 // 从加载器获取成功次数
-	LoaderErrors int64 // 从加载器获取失败次数
-	LoadDuration int64 // 加载总耗时（纳秒）
+	Loads        int64          `json:"loads"`        // 加载次数
+	LocalHits    int64          `json:"localHits"`    // 本地缓存命中次数
+	LocalMisses  int64          `json:"localMisses"`  // 本地缓存未命中次数
+	PeerHits     int64          `json:"peerHits"`     // 从对等节点获取成功次数
+	PeerMisses   int64          `json:"peerMisses"`   // 从对等节点获取失败次数
+	LoaderHits   int64          `json:"loaderHits"`   // 从加载器获取成功次数
+	LoaderErrors int64          `json:"loaderErrors"` // 从加载器获取失败次数
+	LoadDuration int64          `json:"loadDuration"` // 加载总耗时（纳秒），分布见Latency
+	PeerRetries  int64          `json:"peerRetries"`  // 访问其他节点的重试次数
+	Hedges       int64          `json:"hedges"`       // 发出的对冲请求数
+	HedgeWins    int64          `json:"hedgeWins"`    // 对冲请求先于原请求返回的次数
+	Latency      GroupLatencies `json:"latency"`
 }
 
 // Stats 返回组的统计信息
@@ -435,5 +622,9 @@ func (g *Group) Stats() GroupStats {
 		LoaderHits:   atomic.LoadInt64(&g.stats.loaderHits),
 		LoaderErrors: atomic.LoadInt64(&g.stats.loaderErrors),
 		LoadDuration: atomic.LoadInt64(&g.stats.loadDuration),
+		PeerRetries:  atomic.LoadInt64(&g.stats.peerRetries),
+		Hedges:       atomic.LoadInt64(&g.stats.hedges),
+		HedgeWins:    atomic.LoadInt64(&g.stats.hedgeWins),
+		Latency:      g.stats.latencies(),
 	}
 }
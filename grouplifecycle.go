@@ -0,0 +1,322 @@
+package blockcache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/pb"
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/crypt0walker/BlockCache/store"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 集群范围的组生命周期
+// 管理接口只修改发现后端中的组定义，每个节点监听定义的变化并在本地创建、调整容量或销毁组；
+// 新节点启动时先按已有定义建好所有组再开始服务，不会因为缺少组而返回group not found。
+// 处理管理请求的节点写入定义后立即在本地应用一次，其余节点在收到变化通知后应用，重复应用是幂等的。
+
+// GroupFactory 为按定义创建的组提供数据源；返回的Getter实现了io.Closer时，组销毁时会被关闭
+type GroupFactory func(def registry.GroupDefinition) (Getter, error)
+
+// GroupRegistryOptions 组定义注册表配置
+type GroupRegistryOptions struct {
+	// Store 组定义的存储，nil表示使用Server的etcd客户端和服务名
+	Store registry.GroupStore
+	// Peers 按定义创建的组使用的节点选择器，nil表示只在本地加载
+	Peers PeerPicker
+	// Getters 按定义创建数据源，nil表示组只保存Set写入的数据
+	Getters GroupFactory
+	// GroupOptions 创建组时追加的选项，如WithHandoff、WithPeerPolicy
+	GroupOptions []GroupOption
+}
+
+// WithGroupRegistry 开启组定义注册表和CreateGroup/ResizeGroup/DestroyGroup管理接口
+func WithGroupRegistry(opts GroupRegistryOptions) ServerOption {
+	return func(o *ServerOptions) {
+		o.GroupRegistry = &opts
+	}
+}
+
+// groupRegistry 把组定义的变化应用到本地
+type groupRegistry struct {
+	opts   GroupRegistryOptions
+	store  registry.GroupStore
+	cancel context.CancelFunc
+
+	mu      sync.Mutex        // 串行化apply
+	getters map[string]Getter // 按定义创建的组使用的数据源，销毁时关闭
+	specs   map[string]string // 各组当前数据源对应的定义中的getter字段
+}
+
+func newGroupRegistry(opts GroupRegistryOptions, store registry.GroupStore) *groupRegistry {
+	return &groupRegistry{
+		opts:    opts,
+		store:   store,
+		cancel:  func() {},
+		getters: make(map[string]Getter),
+		specs:   make(map[string]string),
+	}
+}
+
+// start 同步已有定义并开始监听变化
+func (r *groupRegistry) start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	r.cancel = cancel
+	r.mu.Unlock()
+	// Watch会同步调用apply，不能持有r.mu
+	if err := r.store.Watch(ctx, r.apply); err != nil {
+		cancel()
+		return fmt.Errorf("failed to load group definitions: %w", err)
+	}
+	return nil
+}
+
+// stop 停止监听，已经创建的组保留
+func (r *groupRegistry) stop() {
+	r.mu.Lock()
+	cancel := r.cancel
+	r.mu.Unlock()
+	cancel()
+}
+
+// apply 把一次定义变化应用到本地
+func (r *groupRegistry) apply(ev registry.GroupEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	def := ev.Definition
+	if ev.Deleted {
+		if DestroyGroup(def.Name) {
+			logrus.Infof("group %s destroyed by definition registry", def.Name)
+		}
+		r.closeGetter(def.Name)
+		return
+	}
+
+	if g := GetGroup(def.Name); g != nil {
+		if g.maxBytes() != def.MaxBytes {
+			if err := g.SetMaxBytes(def.MaxBytes); err != nil {
+				logrus.Warnf("group %s not resized: %v", def.Name, err)
+			}
+		}
+		if def.CacheType != "" && store.CacheType(def.CacheType) != g.mainCache.CacheType() {
+			if err := g.SetCacheType(store.CacheType(def.CacheType)); err != nil {
+				logrus.Warnf("group %s not migrated: %v", def.Name, err)
+			}
+		}
+		if g.defaultTTL() != def.TTL {
+			g.setExpiration(def.TTL)
+		}
+		// 定义里没有getter时保留组原来的数据源（如配置文件中创建的组）
+		if r.opts.Getters != nil && string(def.Getter) != r.specs[def.Name] {
+			getter, err := r.opts.Getters(def)
+			if err != nil {
+				logrus.Errorf("group %s getter not replaced: %v", def.Name, err)
+				return
+			}
+			g.swapGetter(getter)
+			r.closeGetter(def.Name)
+			r.getters[def.Name] = getter
+			r.specs[def.Name] = string(def.Getter)
+		}
+		return
+	}
+
+	var getter Getter = GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	})
+	if r.opts.Getters != nil {
+		var err error
+		if getter, err = r.opts.Getters(def); err != nil {
+			logrus.Errorf("group %s not created: %v", def.Name, err)
+			return
+		}
+		r.getters[def.Name] = getter
+		r.specs[def.Name] = string(def.Getter)
+	}
+	NewGroup(def.Name, def.MaxBytes, getter, r.groupOptions(def)...)
+}
+
+func (r *groupRegistry) groupOptions(def registry.GroupDefinition) []GroupOption {
+	cacheOpts := DefaultCacheOptions()
+	cacheOpts.MaxBytes = def.MaxBytes
+	if def.CacheType != "" {
+		cacheOpts.CacheType = store.CacheType(def.CacheType)
+	}
+	opts := []GroupOption{WithCacheOptions(cacheOpts), WithExpiration(def.TTL)}
+	if r.opts.Peers != nil {
+		opts = append(opts, WIthPeers(r.opts.Peers))
+	}
+	return append(opts, r.opts.GroupOptions...)
+}
+
+func (r *groupRegistry) closeGetter(name string) {
+	if closer, ok := r.getters[name].(io.Closer); ok {
+		closer.Close()
+	}
+	delete(r.getters, name)
+	delete(r.specs, name)
+}
+
+// groupRegistry 返回注册表，未开启时返回Unimplemented
+func (s *Server) groupRegistry() (*groupRegistry, error) {
+	if s.groupReg == nil {
+		return nil, status.Error(codes.Unimplemented, "server has no group registry configured")
+	}
+	return s.groupReg, nil
+}
+
+// createGroup gRPC和HTTP接口共用的创建路径
+func (s *Server) createGroup(ctx context.Context, def registry.GroupDefinition) error {
+	reg, err := s.groupRegistry()
+	if err != nil {
+		return err
+	}
+	if err := def.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	if def.CacheType != "" && def.CacheType != string(store.LRU) && def.CacheType != string(store.LRU2) {
+		return fmt.Errorf("%w: unknown cache type %q", ErrInvalidArgument, def.CacheType)
+	}
+	// 数据源无法创建的定义不写入，否则每个节点都会在应用时失败
+	if reg.opts.Getters != nil {
+		getter, err := reg.opts.Getters(def)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		if closer, ok := getter.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+	if err := reg.store.Create(ctx, def); err != nil {
+		return err
+	}
+	reg.apply(registry.GroupEvent{Definition: def})
+	return nil
+}
+
+// resizeGroup gRPC和HTTP接口共用的调整路径，maxBytes为0、cacheType为空表示不修改对应项；
+// 开启注册表时修改定义并由所有节点应用，否则只调整本节点上的组
+func (s *Server) resizeGroup(ctx context.Context, name string, maxBytes int64, cacheType string) (registry.GroupDefinition, error) {
+	switch {
+	case maxBytes < 0:
+		return registry.GroupDefinition{}, fmt.Errorf("%w: max bytes must be positive", ErrInvalidArgument)
+	case maxBytes == 0 && cacheType == "":
+		return registry.GroupDefinition{}, fmt.Errorf("%w: nothing to change", ErrInvalidArgument)
+	case cacheType != "" && cacheType != string(store.LRU) && cacheType != string(store.LRU2):
+		return registry.GroupDefinition{}, fmt.Errorf("%w: unknown cache type %q", ErrInvalidArgument, cacheType)
+	}
+	if s.groupReg == nil {
+		return resizeLocalGroup(name, maxBytes, cacheType)
+	}
+
+	reg := s.groupReg
+	def, err := reg.store.Update(ctx, name, func(def *registry.GroupDefinition) error {
+		if maxBytes > 0 {
+			def.MaxBytes = maxBytes
+		}
+		if cacheType != "" {
+			def.CacheType = cacheType
+		}
+		return nil
+	})
+	if err != nil {
+		return registry.GroupDefinition{}, err
+	}
+	reg.apply(registry.GroupEvent{Definition: def})
+	return def, nil
+}
+
+// resizeLocalGroup 调整本节点上的组，返回调整后的组配置
+func resizeLocalGroup(name string, maxBytes int64, cacheType string) (registry.GroupDefinition, error) {
+	g := GetGroup(name)
+	if g == nil {
+		return registry.GroupDefinition{}, groupNotFound(name)
+	}
+	if maxBytes > 0 {
+		if err := g.SetMaxBytes(maxBytes); err != nil {
+			return registry.GroupDefinition{}, err
+		}
+	}
+	if cacheType != "" {
+		if err := g.SetCacheType(store.CacheType(cacheType)); err != nil {
+			return registry.GroupDefinition{}, err
+		}
+	}
+	return registry.GroupDefinition{
+		Name:      name,
+		MaxBytes:  g.maxBytes(),
+		TTL:       g.defaultTTL(),
+		CacheType: string(g.mainCache.CacheType()),
+	}, nil
+}
+
+// destroyGroup gRPC和HTTP接口共用的销毁路径，返回定义是否存在
+func (s *Server) destroyGroup(ctx context.Context, name string) (bool, error) {
+	reg, err := s.groupRegistry()
+	if err != nil {
+		return false, err
+	}
+	existed, err := reg.store.Delete(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	// 没有定义的组只存在于各自的进程中，不在这里销毁
+	if existed {
+		reg.apply(registry.GroupEvent{Definition: registry.GroupDefinition{Name: name}, Deleted: true})
+	}
+	return existed, nil
+}
+
+// CreateGroup 实现Cache服务的CreateGroup方法
+func (s *Server) CreateGroup(ctx context.Context, req *pb.GroupDefinition) (*pb.GroupDefinition, error) {
+	def := definitionFromPB(req)
+	if err := s.createGroup(ctx, def); err != nil {
+		return nil, toStatus(err)
+	}
+	return definitionToPB(def), nil
+}
+
+// ResizeGroup 实现Cache服务的ResizeGroup方法
+func (s *Server) ResizeGroup(ctx context.Context, req *pb.ResizeGroupRequest) (*pb.GroupDefinition, error) {
+	def, err := s.resizeGroup(ctx, req.Group, req.MaxBytes, req.CacheType)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return definitionToPB(def), nil
+}
+
+// DestroyGroup 实现Cache服务的DestroyGroup方法
+func (s *Server) DestroyGroup(ctx context.Context, req *pb.DestroyGroupRequest) (*pb.DestroyGroupResponse, error) {
+	existed, err := s.destroyGroup(ctx, req.Group)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DestroyGroupResponse{Existed: existed}, nil
+}
+
+func definitionFromPB(d *pb.GroupDefinition) registry.GroupDefinition {
+	return registry.GroupDefinition{
+		Name:      d.GetGroup(),
+		MaxBytes:  d.GetMaxBytes(),
+		TTL:       time.Duration(d.GetTtlMs()) * time.Millisecond,
+		CacheType: d.GetCacheType(),
+		Getter:    d.GetGetter(),
+	}
+}
+
+func definitionToPB(d registry.GroupDefinition) *pb.GroupDefinition {
+	return &pb.GroupDefinition{
+		Group:     d.Name,
+		MaxBytes:  d.MaxBytes,
+		TtlMs:     d.TTL.Milliseconds(),
+		CacheType: d.CacheType,
+		Getter:    d.Getter,
+	}
+}
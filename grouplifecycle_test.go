@@ -0,0 +1,359 @@
+package blockcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/pb"
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/crypt0walker/BlockCache/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// closingGetter 记录是否被关闭的Getter
+type closingGetter struct {
+	value  string
+	closed atomic.Bool
+}
+
+func (g *closingGetter) Get(ctx context.Context, key string) ([]byte, error) {
+	return []byte(g.value + ":" + key), nil
+}
+
+func (g *closingGetter) Close() error {
+	g.closed.Store(true)
+	return nil
+}
+
+// startRegistryServer 启动开启了组定义注册表的节点，返回连接它的Client
+func startRegistryServer(t *testing.T, reg *groupRegistry) *Client {
+	t.Helper()
+	if err := reg.start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(reg.stop)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer()
+	pb.RegisterBlockCacheServer(gs, &Server{opts: &ServerOptions{}, groupReg: reg})
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+
+	client, err := NewClient(lis.Addr().String(), "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// TestGroupRegistry_Lifecycle 通过管理接口创建、调整、销毁组，本地组随定义变化
+func TestGroupRegistry_Lifecycle(t *testing.T) {
+	store := registry.NewMemoryGroupStore()
+	getters := make(map[string]*closingGetter)
+	reg := newGroupRegistry(GroupRegistryOptions{
+		Getters: func(def registry.GroupDefinition) (Getter, error) {
+			var cfg struct{ Value string }
+			if err := json.Unmarshal(def.Getter, &cfg); err != nil {
+				return nil, err
+			}
+			getters[def.Name] = &closingGetter{value: cfg.Value}
+			return getters[def.Name], nil
+		},
+	}, store)
+	client := startRegistryServer(t, reg)
+	ctx := context.Background()
+
+	def := registry.GroupDefinition{Name: "lifecycle", MaxBytes: 1 << 20, TTL: time.Minute, Getter: json.RawMessage(`{"value":"origin"}`)}
+	if err := client.CreateGroup(ctx, def); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { DestroyGroup("lifecycle") })
+	g := GetGroup("lifecycle")
+	if g == nil || g.defaultTTL() != time.Minute {
+		t.Fatalf("group after create = %+v", g)
+	}
+	if v, err := client.Get(ctx, "lifecycle", "k"); err != nil || string(v) != "origin:k" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if err := client.CreateGroup(ctx, def); !errors.Is(err, registry.ErrGroupExists) || status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("duplicate create err = %v", err)
+	}
+	// 数据源无法创建的定义不写入
+	bad := registry.GroupDefinition{Name: "bad-getter", MaxBytes: 1 << 20, Getter: json.RawMessage(`"nope"`)}
+	if err := client.CreateGroup(ctx, bad); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("bad getter err = %v", err)
+	}
+	if defs, _ := store.List(ctx); len(defs) != 1 {
+		t.Fatalf("definitions after bad create = %+v", defs)
+	}
+
+	resized, err := client.ResizeGroup(ctx, "lifecycle", 4<<10, "")
+	if err != nil || resized.MaxBytes != 4<<10 || resized.TTL != time.Minute {
+		t.Fatalf("resize = %+v, %v", resized, err)
+	}
+	if max := g.mainCache.StoreStats().MaxBytes; max != 4<<10 {
+		t.Fatalf("max bytes after resize = %d", max)
+	}
+	if _, err := client.ResizeGroup(ctx, "no-such-def", 1, ""); !errors.Is(err, registry.ErrGroupUndefined) {
+		t.Fatalf("resize missing err = %v", err)
+	}
+
+	// 直接修改定义中的TTL和getter，已有的组随之更新，原来的数据源被关闭
+	first := getters["lifecycle"]
+	if _, err := store.Update(ctx, "lifecycle", func(def *registry.GroupDefinition) error {
+		def.TTL = time.Hour
+		def.Getter = json.RawMessage(`{"value":"updated"}`)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for g.defaultTTL() != time.Hour && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if ttl := g.defaultTTL(); ttl != time.Hour || !first.closed.Load() {
+		t.Fatalf("ttl = %v, old getter closed = %v", ttl, first.closed.Load())
+	}
+	if v, err := client.Get(ctx, "lifecycle", "k2"); err != nil || string(v) != "updated:k2" {
+		t.Fatalf("Get after getter update = %q, %v", v, err)
+	}
+
+	if existed, err := client.DestroyGroup(ctx, "lifecycle"); err != nil || !existed {
+		t.Fatalf("destroy = %v, %v", existed, err)
+	}
+	if GetGroup("lifecycle") != nil || !getters["lifecycle"].closed.Load() {
+		t.Fatal("group or getter still alive after destroy")
+	}
+	if _, err := client.Get(ctx, "lifecycle", "k"); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("Get after destroy err = %v", err)
+	}
+}
+
+// TestGroupRegistry_Join 新节点启动时按已有定义建好组，之后同步其他节点写入的变化
+func TestGroupRegistry_Join(t *testing.T) {
+	store := registry.NewMemoryGroupStore()
+	ctx := context.Background()
+	store.Create(ctx, registry.GroupDefinition{Name: "join-existing", MaxBytes: 1 << 20, CacheType: "lru"})
+	t.Cleanup(func() { DestroyGroup("join-existing"); DestroyGroup("join-later") })
+
+	reg := newGroupRegistry(GroupRegistryOptions{}, store)
+	if err := reg.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer reg.stop()
+	if GetGroup("join-existing") == nil {
+		t.Fatal("existing definition not applied on start")
+	}
+
+	// 其他节点直接写入的定义
+	store.Create(ctx, registry.GroupDefinition{Name: "join-later", MaxBytes: 1 << 20})
+	g := GetGroup("join-later")
+	if g == nil {
+		t.Fatal("definition created elsewhere not applied")
+	}
+	// 没有配置数据源的组只保存Set写入的数据
+	if _, err := g.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get err = %v", err)
+	}
+	store.Delete(ctx, "join-later")
+	if GetGroup("join-later") != nil {
+		t.Fatal("deleted definition not applied")
+	}
+}
+
+// TestGroupRegistry_Disabled 未开启注册表时管理接口返回Unimplemented
+func TestGroupRegistry_Disabled(t *testing.T) {
+	client, err := NewClient(startTestServer(t), "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	err = client.CreateGroup(context.Background(), registry.GroupDefinition{Name: "disabled", MaxBytes: 1})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// TestGroupRegistry_HTTP HTTP管理接口与gRPC走同一条路径
+func TestGroupRegistry_HTTP(t *testing.T) {
+	reg := newGroupRegistry(GroupRegistryOptions{}, registry.NewMemoryGroupStore())
+	srv := httptest.NewServer((&Server{opts: &ServerOptions{}, groupReg: reg}).HTTPHandler())
+	defer srv.Close()
+	t.Cleanup(func() { DestroyGroup("http-lifecycle") })
+	base := srv.URL + "/groups/http-lifecycle"
+
+	if resp, body := doHTTP(t, "PUT", base, `{"maxBytes": 1048576}`, nil); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("PUT = %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := doHTTP(t, "PUT", base, `{"maxBytes": 1048576}`, nil); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("second PUT = %d", resp.StatusCode)
+	}
+	if resp, body := doHTTP(t, "PATCH", base, `{"maxBytes": 2048}`, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH = %d %s", resp.StatusCode, body)
+	}
+	if max := GetGroup("http-lifecycle").mainCache.StoreStats().MaxBytes; max != 2048 {
+		t.Fatalf("max bytes = %d", max)
+	}
+	if resp, _ := doHTTP(t, "PATCH", base, `{"maxBytes": 0}`, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("PATCH 0 = %d", resp.StatusCode)
+	}
+	if resp, _ := doHTTP(t, "DELETE", base, "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", resp.StatusCode)
+	}
+	if resp, _ := doHTTP(t, "DELETE", base, "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("second DELETE = %d", resp.StatusCode)
+	}
+}
+
+// TestGroup_SetMaxBytes 在线缩小容量时按LRU顺序淘汰，扩大后立即生效
+func TestGroup_SetMaxBytes(t *testing.T) {
+	g := NewGroup("resize", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	defer g.Close()
+	ctx := context.Background()
+
+	value := make([]byte, 100)
+	for i := range 10 {
+		g.Set(ctx, fmt.Sprintf("k%d", i), value)
+	}
+	g.Get(ctx, "k0") // k0变为最近访问
+
+	if err := g.SetMaxBytes(350); err != nil {
+		t.Fatal(err)
+	}
+	stats := g.mainCache.StoreStats()
+	if stats.MaxBytes != 350 || stats.Bytes > 350 || stats.Items != 3 {
+		t.Fatalf("stats after shrink = %+v", stats)
+	}
+	for _, k := range []string{"k0", "k8", "k9"} {
+		if _, ok := g.mainCache.Get(ctx, k); !ok {
+			t.Fatalf("%s evicted, want the least recently used keys evicted first", k)
+		}
+	}
+
+	if err := g.SetMaxBytes(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 10 {
+		g.Set(ctx, fmt.Sprintf("n%d", i), value)
+	}
+	if stats := g.mainCache.StoreStats(); stats.Items != 13 {
+		t.Fatalf("items after grow = %d", stats.Items)
+	}
+	if err := g.SetMaxBytes(0); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("SetMaxBytes(0) err = %v", err)
+	}
+}
+
+// TestGroup_SetCacheType 切换存储类型后已有数据和剩余TTL保留
+func TestGroup_SetCacheType(t *testing.T) {
+	g := NewGroup("migrate", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	defer g.Close()
+	ctx := context.Background()
+	g.Set(ctx, "plain", []byte("v1"))
+	g.SetWithTTL(ctx, "short", []byte("v2"), time.Hour)
+
+	if err := g.SetCacheType(store.LRU); err != nil {
+		t.Fatal(err)
+	}
+	if got := g.mainCache.CacheType(); got != store.LRU {
+		t.Fatalf("cache type = %s", got)
+	}
+	ttls := make(map[string]time.Duration)
+	g.mainCache.Range(func(key string, value ByteView, ttl time.Duration) bool {
+		ttls[key] = ttl
+		return true
+	})
+	if len(ttls) != 2 || ttls["plain"] != 0 || ttls["short"] <= 59*time.Minute {
+		t.Fatalf("migrated entries = %v", ttls)
+	}
+	if v, err := g.Get(ctx, "plain"); err != nil || v.String() != "v1" {
+		t.Fatalf("Get after migration = %q, %v", v.String(), err)
+	}
+	if err := g.SetCacheType("arc"); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("unknown type err = %v", err)
+	}
+
+	// 淘汰计数跨存储切换累计，不会回退
+	g.SetMaxBytes(8)
+	before := g.mainCache.StoreStats().Evictions
+	if before == 0 {
+		t.Fatal("expected evictions after shrinking")
+	}
+	if err := g.SetCacheType(store.LRU2); err != nil {
+		t.Fatal(err)
+	}
+	if after := g.mainCache.StoreStats().Evictions; after < before {
+		t.Fatalf("evictions went back from %d to %d", before, after)
+	}
+}
+
+// TestResizeGroup_Local 未开启注册表时ResizeGroup只调整本节点，结果体现在统计中
+func TestResizeGroup_Local(t *testing.T) {
+	g := NewGroup("resize-local", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}))
+	defer g.Close()
+	client, err := NewClient(startTestServer(t), "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	def, err := client.ResizeGroup(ctx, "resize-local", 2048, "lru")
+	if err != nil || def.MaxBytes != 2048 || def.CacheType != "lru" {
+		t.Fatalf("resize = %+v, %v", def, err)
+	}
+	stats, err := client.Stats(ctx, "resize-local")
+	if err != nil || stats.MaxBytes != 2048 || stats.CacheType != "lru" {
+		t.Fatalf("stats = %+v, %v", stats, err)
+	}
+	if _, err := client.ResizeGroup(ctx, "resize-local", 0, ""); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("empty resize err = %v", err)
+	}
+	if _, err := client.ResizeGroup(ctx, "no-such-group", 1, ""); !errors.Is(err, ErrGroupNotFound) {
+		t.Fatalf("missing group err = %v", err)
+	}
+}
+
+// TestResizeGroup_Governed 受内存预算管理的组按预算上限比较和返回，而不是当前分到的预算
+func TestResizeGroup_Governed(t *testing.T) {
+	gov := newTestGovernor(t, PolicyWeight)
+	g := newGovernedGroup(t, gov, "resize-governed", 1)
+
+	def, err := resizeLocalGroup("resize-governed", 4096, "")
+	if err != nil || def.MaxBytes != 4096 {
+		t.Fatalf("resize = %+v, %v", def, err)
+	}
+	if budget := g.mainCache.StoreStats().MaxBytes; budget > 1000 {
+		t.Fatalf("budget = %d, exceeds the governor total", budget)
+	}
+
+	// 定义中的容量等于预算上限时不再调整
+	reg := newGroupRegistry(GroupRegistryOptions{}, registry.NewMemoryGroupStore())
+	reg.apply(registry.GroupEvent{Definition: registry.GroupDefinition{Name: "resize-governed", MaxBytes: 4096}})
+	if limit, _ := gov.limitOf(g); limit != 4096 {
+		t.Fatalf("limit = %d", limit)
+	}
+	reg.apply(registry.GroupEvent{Definition: registry.GroupDefinition{Name: "resize-governed", MaxBytes: 2048}})
+	if limit, _ := gov.limitOf(g); limit != 2048 || g.maxBytes() != 2048 {
+		t.Fatalf("limit after apply = %d", limit)
+	}
+}
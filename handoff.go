@@ -0,0 +1,265 @@
+package blockcache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 哈希环成员变化时的key迁移（handoff）
+// 扩容后新节点默认是冷的，所有落到它上面的key都要回源，容易把后端打垮。
+// 开启handoff后，每个节点在环变化时找出原来归自己、现在归别人的key，
+// 按速率限制把它们（连同剩余TTL）流式推给新的负责节点。
+
+// HandoffEntry 迁移的单个缓存项
+type HandoffEntry struct {
+	Key   string
+	Value []byte
+	TTL   time.Duration // 剩余存活时间，0表示不过期
+}
+
+// HandoffPeer 由能够接收迁移数据的节点实现
+type HandoffPeer interface {
+	Handoff(ctx context.Context, group string, entries []HandoffEntry) (int, error)
+}
+
+// handoffPicker 支持key迁移的节点选择器，ClientPicker实现了该接口
+type handoffPicker interface {
+	Owner(key string) (addr string, isSelf bool)
+	HandoffPeer(addr string) (HandoffPeer, bool)
+	OnRingChange(fn func(RingChange)) (cancel func())
+}
+
+// HandoffOptions key迁移配置
+type HandoffOptions struct {
+	Rate      int           // 每秒最多迁移的key数
+	BatchSize int           // 每个RPC携带的key数
+	Timeout   time.Duration // 单次RPC超时
+	// KeepLocal 迁移成功后是否保留本地副本，默认删除，避免新旧负责人数据分叉
+	KeepLocal bool
+}
+
+// DefaultHandoffOptions 默认迁移配置
+func DefaultHandoffOptions() HandoffOptions {
+	return HandoffOptions{
+		Rate:      1000,
+		BatchSize: 100,
+		Timeout:   5 * time.Second,
+	}
+}
+
+// WithHandoff 开启哈希环变化时的key迁移
+func WithHandoff(opts HandoffOptions) GroupOption {
+	return func(g *Group) {
+		defaults := DefaultHandoffOptions()
+		if opts.Rate <= 0 {
+			opts.Rate = defaults.Rate
+		}
+		if opts.BatchSize <= 0 {
+			opts.BatchSize = defaults.BatchSize
+		}
+		if opts.Timeout <= 0 {
+			opts.Timeout = defaults.Timeout
+		}
+		g.handoff = &handoffState{opts: opts}
+	}
+}
+
+// handoffState 每个Group的迁移状态
+type handoffState struct {
+	opts HandoffOptions
+	// 同一时刻只运行一轮迁移，环连续变化时按顺序处理
+	mu sync.Mutex
+	// 取消对当前节点选择器的订阅，由subMu保护
+	subMu       sync.Mutex
+	unsubscribe func()
+}
+
+// subscribeHandoff 在注册节点选择器时订阅环变化
+func (g *Group) subscribeHandoff(peers PeerPicker) {
+	if g.handoff == nil {
+		return
+	}
+	picker, ok := peers.(handoffPicker)
+	if !ok {
+		logrus.Warnf("Peer picker of group %s does not support handoff", g.name)
+		return
+	}
+	cancel := picker.OnRingChange(func(change RingChange) {
+		g.runHandoff(picker, change)
+	})
+
+	// 重新注册节点选择器时取消对旧选择器的订阅
+	g.handoff.subMu.Lock()
+	defer g.handoff.subMu.Unlock()
+	if g.handoff.unsubscribe != nil {
+		g.handoff.unsubscribe()
+	}
+	g.handoff.unsubscribe = cancel
+}
+
+// unsubscribeHandoff 组关闭时取消环变化订阅，不再持有组的引用
+func (g *Group) unsubscribeHandoff() {
+	if g.handoff == nil {
+		return
+	}
+	g.handoff.subMu.Lock()
+	defer g.handoff.subMu.Unlock()
+	if g.handoff.unsubscribe != nil {
+		g.handoff.unsubscribe()
+		g.handoff.unsubscribe = nil
+	}
+}
+
+// runHandoff 执行一轮迁移：找出原来归自己、现在归别人的key，按目标节点分批推送
+func (g *Group) runHandoff(picker handoffPicker, change RingChange) {
+	h := g.handoff
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// 按新负责节点分组
+	pending := make(map[string][]HandoffEntry)
+	g.mainCache.Range(func(key string, value ByteView, ttl time.Duration) bool {
+		if !change.WasOwner(key) {
+			return true
+		}
+		if addr, isSelf := picker.Owner(key); addr != "" && !isSelf {
+			pending[addr] = append(pending[addr], HandoffEntry{Key: key, Value: value.ByteSlice(), TTL: ttl})
+		}
+		return true
+	})
+	if len(pending) == 0 {
+		return
+	}
+
+	limiter := newRateLimiter(h.opts.Rate)
+	defer limiter.stop()
+
+	var moved, failed int
+	for addr, entries := range pending {
+		peer, ok := picker.HandoffPeer(addr)
+		if !ok {
+			failed += len(entries)
+			continue
+		}
+		for start := 0; start < len(entries); start += h.opts.BatchSize {
+			end := start + h.opts.BatchSize
+			if end > len(entries) {
+				end = len(entries)
+			}
+			batch := entries[start:end]
+			if atomic.LoadInt32(&g.closed) == 1 || !limiter.wait(len(batch)) {
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), h.opts.Timeout)
+			_, err := peer.Handoff(ctx, g.name, batch)
+			cancel()
+			if err != nil {
+				logrus.Warnf("handoff of %d keys in group %s to %s failed: %v", len(batch), g.name, addr, err)
+				failed += len(batch)
+				continue
+			}
+			moved += len(batch)
+			if !h.opts.KeepLocal {
+				for _, e := range batch {
+					g.mainCache.Delete(e.Key)
+				}
+			}
+		}
+	}
+	logrus.Infof("handoff for group %s finished: moved=%d, failed=%d", g.name, moved, failed)
+}
+
+// acceptHandoff 接收其他节点迁移过来的key
+// 本地已有的key不覆盖：它可能是迁移开始后新写入的，比迁移数据更新
+func (g *Group) acceptHandoff(ctx context.Context, key string, value []byte, ttl time.Duration) bool {
+	if atomic.LoadInt32(&g.closed) == 1 || key == "" {
+		return false
+	}
+	if _, ok := g.mainCache.Get(ctx, key); ok {
+		return false
+	}
+
+	view := ByteView{data: cloneBytes(value)}
+	if ttl <= 0 {
+		ttl = g.defaultTTL()
+	}
+	if ttl > 0 {
+		g.mainCache.AddWithExpiration(key, view, time.Now().Add(ttl))
+	} else {
+		g.mainCache.Add(key, view)
+	}
+	return true
+}
+
+// rateLimiter 简单的令牌桶，每秒补充rate个令牌
+type rateLimiter struct {
+	tokens chan struct{}
+	done   chan struct{}
+}
+
+const (
+	// limiterTick 补充令牌的最小间隔，速率很高时每次补充多个令牌
+	limiterTick = 10 * time.Millisecond
+	// maxLimiterBurst 桶容量上限，避免速率很高时分配过大的channel
+	maxLimiterBurst = 10000
+)
+
+func newRateLimiter(rate int) *rateLimiter {
+	rate = max(rate, 1)
+	l := &rateLimiter{
+		tokens: make(chan struct{}, min(rate, maxLimiterBurst)),
+		done:   make(chan struct{}),
+	}
+	// 启动时桶是满的，允许第一批立即发出
+	for i := 0; i < cap(l.tokens); i++ {
+		l.tokens <- struct{}{}
+	}
+	interval := max(time.Second/time.Duration(rate), limiterTick)
+	perTick := float64(rate) * interval.Seconds()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		// owed 累计应补充但还不足一个的令牌
+		var owed float64
+		for {
+			select {
+			case <-ticker.C:
+				owed += perTick
+			refill:
+				for ; owed >= 1; owed-- {
+					select {
+					case l.tokens <- struct{}{}:
+					default:
+						// 桶已满，多出的令牌丢弃
+						owed = 0
+						break refill
+					}
+				}
+			case <-l.done:
+				return
+			}
+		}
+	}()
+	return l
+}
+
+// wait 获取n个令牌，限速器停止时返回false
+func (l *rateLimiter) wait(n int) bool {
+	for i := 0; i < n; i++ {
+		select {
+		case <-l.tokens:
+		case <-l.done:
+			return false
+		}
+	}
+	return true
+}
+
+func (l *rateLimiter) stop() {
+	close(l.done)
+}
@@ -0,0 +1,128 @@
+package blockcache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/consistenthash"
+)
+
+// fakeHandoffPicker 用固定的路由模拟环变化
+type fakeHandoffPicker struct {
+	self   string
+	router consistenthash.Router
+	peers  map[string]*fakeHandoffPeer
+}
+
+func (p *fakeHandoffPicker) PickPeer(key string) (Peer, bool, bool)  { return nil, false, false }
+func (p *fakeHandoffPicker) Close() error                            { return nil }
+func (p *fakeHandoffPicker) OnRingChange(fn func(RingChange)) func() { return func() {} }
+
+func (p *fakeHandoffPicker) Owner(key string) (string, bool) {
+	addr := p.router.Get(key)
+	return addr, addr == p.self
+}
+
+func (p *fakeHandoffPicker) HandoffPeer(addr string) (HandoffPeer, bool) {
+	peer, ok := p.peers[addr]
+	return peer, ok
+}
+
+// fakeHandoffPeer 记录收到的迁移数据
+type fakeHandoffPeer struct {
+	mu       sync.Mutex
+	received map[string]HandoffEntry
+}
+
+func (f *fakeHandoffPeer) Handoff(ctx context.Context, group string, entries []HandoffEntry) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range entries {
+		f.received[e.Key] = e
+	}
+	return len(entries), nil
+}
+
+// TestHandoff_MovesKeysToNewOwner 只迁移原来归自己、现在归别人的key，并携带剩余TTL
+func TestHandoff_MovesKeysToNewOwner(t *testing.T) {
+	g := NewGroup("handoff-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, fmt.Errorf("not found")
+	}), WithHandoff(HandoffOptions{Rate: 10000, BatchSize: 7}))
+	defer g.Close()
+
+	for i := 0; i < 100; i++ {
+		g.mainCache.AddWithExpiration(fmt.Sprintf("key-%d", i), ByteView{data: []byte("v")}, time.Now().Add(time.Hour))
+	}
+
+	// 变化前只有self，变化后加入了node-b
+	previous := consistenthash.NewRendezvous()
+	previous.Add("self")
+	current := consistenthash.NewRendezvous()
+	current.Add("self", "node-b")
+
+	peerB := &fakeHandoffPeer{received: make(map[string]HandoffEntry)}
+	picker := &fakeHandoffPicker{self: "self", router: current, peers: map[string]*fakeHandoffPeer{"node-b": peerB}}
+	g.runHandoff(picker, RingChange{Added: []string{"node-b"}, previous: previous, self: "self"})
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		entry, moved := peerB.received[key]
+		if owner := current.Get(key); owner == "node-b" {
+			if !moved {
+				t.Fatalf("%s should be handed off to node-b", key)
+			}
+			if entry.TTL <= 0 || entry.TTL > time.Hour {
+				t.Fatalf("%s: unexpected ttl %v", key, entry.TTL)
+			}
+			if _, ok := g.mainCache.Get(context.Background(), key); ok {
+				t.Fatalf("%s should be dropped locally after handoff", key)
+			}
+		} else if moved {
+			t.Fatalf("%s still belongs to self but was handed off", key)
+		}
+	}
+	if len(peerB.received) == 0 {
+		t.Fatal("no keys were handed off")
+	}
+}
+
+// TestHandoff_AcceptKeepsNewerLocal 接收方已有的key不会被迁移数据覆盖
+func TestHandoff_AcceptKeepsNewerLocal(t *testing.T) {
+	g := NewGroup("handoff-accept", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, fmt.Errorf("not found")
+	}))
+	defer g.Close()
+	ctx := context.Background()
+
+	g.Set(ctx, "k", []byte("fresh"))
+	if g.acceptHandoff(ctx, "k", []byte("stale"), 0) {
+		t.Fatal("existing key should not be overwritten")
+	}
+	if !g.acceptHandoff(ctx, "new", []byte("moved"), time.Minute) {
+		t.Fatal("absent key should be accepted")
+	}
+
+	if v, _ := g.Get(ctx, "k"); v.String() != "fresh" {
+		t.Fatalf("k = %s, want fresh", v.String())
+	}
+	if v, _ := g.Get(ctx, "new"); v.String() != "moved" {
+		t.Fatalf("new = %s, want moved", v.String())
+	}
+}
+
+// TestRateLimiter_ExtremeRates 速率过高或过低时不会panic，桶容量有上限
+func TestRateLimiter_ExtremeRates(t *testing.T) {
+	for _, rate := range []int{0, 1, 2_000_000_000} {
+		l := newRateLimiter(rate)
+		if !l.wait(1) {
+			t.Fatalf("rate %d: first token not available", rate)
+		}
+		if cap(l.tokens) > maxLimiterBurst {
+			t.Fatalf("rate %d: burst %d exceeds limit", rate, cap(l.tokens))
+		}
+		l.stop()
+	}
+}
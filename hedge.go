@@ -0,0 +1,263 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 访问其他节点时的重试与对冲请求（hedged request）
+// 单个节点偶发变慢会直接拉高尾延迟。开启对冲后，Get在等待超过一定延迟（固定值或
+// 最近请求延迟的某个分位数）仍未返回时，向下一个副本（没有其他副本时向负责节点）
+// 再发一次请求，取先返回的结果。失败的请求按带抖动的指数退避重试：
+// Get遇到可重试的状态码都会重试；Set和Delete只在Client确认请求没有发出
+// （熔断器打开或连接始终没有建立）时重试：服务端返回的UNAVAILABLE可能发生在写入之后，
+// 重放旧的写请求会覆盖或删除期间写入的新值。声明了IdempotentSet的Set按Get的规则重试。
+
+// PeerPolicy 访问其他节点的重试与对冲策略
+type PeerPolicy struct {
+	MaxRetries  int           // 失败后的最大重试次数，0表示不重试
+	BaseBackoff time.Duration // 首次重试前的退避基数
+	MaxBackoff  time.Duration // 退避上限
+	// HedgeDelay 发出对冲请求前的固定等待时间，0表示不按固定延迟对冲
+	HedgeDelay time.Duration
+	// HedgePercentile 按最近Get延迟的分位数（如0.95）决定等待时间，优先于HedgeDelay；
+	// 样本不足HedgeMinSamples时退回HedgeDelay
+	HedgePercentile float64
+	HedgeMinSamples int
+	// IdempotentSet 声明Set是幂等的，可以像Get一样在超时等错误后重试
+	IdempotentSet bool
+}
+
+// DefaultPeerPolicy 默认策略：最多重试2次，不开启对冲
+func DefaultPeerPolicy() PeerPolicy {
+	return PeerPolicy{
+		MaxRetries:      2,
+		BaseBackoff:     20 * time.Millisecond,
+		MaxBackoff:      500 * time.Millisecond,
+		HedgeMinSamples: 20,
+	}
+}
+
+// WithPeerPolicy 设置该Group访问其他节点时的重试与对冲策略
+func WithPeerPolicy(policy PeerPolicy) GroupOption {
+	return func(g *Group) {
+		defaults := DefaultPeerPolicy()
+		if policy.BaseBackoff <= 0 {
+			policy.BaseBackoff = defaults.BaseBackoff
+		}
+		if policy.MaxBackoff < policy.BaseBackoff {
+			policy.MaxBackoff = defaults.MaxBackoff
+			if policy.MaxBackoff < policy.BaseBackoff {
+				policy.MaxBackoff = policy.BaseBackoff
+			}
+		}
+		if policy.HedgeMinSamples <= 0 {
+			policy.HedgeMinSamples = defaults.HedgeMinSamples
+		}
+		g.peerPolicy = &peerPolicyState{policy: policy, latencies: newLatencyWindow(latencyWindowSize)}
+	}
+}
+
+// multiPeerPicker 能按优先级返回多个候选节点的选择器，ClientPicker实现了该接口
+type multiPeerPicker interface {
+	PickPeers(key string, n int) []Peer
+}
+
+// peerPolicyState 每个Group的策略与延迟统计
+type peerPolicyState struct {
+	policy    PeerPolicy
+	latencies *latencyWindow
+}
+
+// retryable 判断op失败后是否可以重试
+func (s *peerPolicyState) retryable(op string, err error) bool {
+	if op == "delete" || (op == "set" && !s.policy.IdempotentSet) {
+		// 写请求只在确定没有到达服务端时重试
+		return errors.Is(err, errPeerUnreachable)
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff 第attempt次重试前的等待时间，使用full jitter避免各节点同时重试
+func (s *peerPolicyState) backoff(attempt int) time.Duration {
+	d := s.policy.BaseBackoff << uint(attempt)
+	if d <= 0 || d > s.policy.MaxBackoff {
+		d = s.policy.MaxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(d) + 1))
+}
+
+// hedgeDelay 发出对冲请求前的等待时间，0表示不对冲
+func (s *peerPolicyState) hedgeDelay() time.Duration {
+	if s.policy.HedgePercentile > 0 {
+		if d, ok := s.latencies.percentile(s.policy.HedgePercentile, s.policy.HedgeMinSamples); ok {
+			return d
+		}
+	}
+	return s.policy.HedgeDelay
+}
+
+// callPeer 按策略执行一次对其他节点的调用，失败时退避重试
+func (g *Group) callPeer(ctx context.Context, op string, call func(context.Context) error) error {
+	s := g.peerPolicy
+	if s == nil {
+		return call(ctx)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := call(ctx)
+		if err == nil || attempt >= s.policy.MaxRetries || !s.retryable(op, err) {
+			return err
+		}
+		atomic.AddInt64(&g.stats.peerRetries, 1)
+
+		timer := time.NewTimer(s.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// hedgedGet 向负责节点发起Get，超过对冲延迟仍未返回时向下一个副本再发一次，取先成功的结果
+func (g *Group) hedgedGet(ctx context.Context, primary Peer, key string) ([]byte, error) {
+	var delay time.Duration
+	if g.peerPolicy != nil {
+		delay = g.peerPolicy.hedgeDelay()
+	}
+	if delay <= 0 {
+		return g.timedGet(ctx, primary, key)
+	}
+
+	// 先返回的请求胜出，另一个随ctx一起取消
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		value []byte
+		err   error
+		hedge bool
+	}
+	results := make(chan result, 2)
+	send := func(peer Peer, hedge bool) {
+		value, err := g.timedGet(ctx, peer, key)
+		results <- result{value: value, err: err, hedge: hedge}
+	}
+
+	go send(primary, false)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	inflight, hedged := 1, false
+	var firstErr error
+	for {
+		select {
+		case <-timer.C:
+			hedged = true
+			inflight++
+			atomic.AddInt64(&g.stats.hedges, 1)
+			go send(g.hedgePeer(key, primary), true)
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				if r.hedge {
+					atomic.AddInt64(&g.stats.hedgeWins, 1)
+				}
+				return r.value, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			// 对冲前就失败的请求交给重试逻辑处理，不再等待对冲
+			if !hedged || inflight == 0 {
+				return nil, firstErr
+			}
+		}
+	}
+}
+
+// timedGet 调用peer.Get，并把成功请求的延迟记入统计窗口
+func (g *Group) timedGet(ctx context.Context, peer Peer, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := peer.Get(ctx, g.name, key)
+	if err == nil && g.peerPolicy != nil {
+		g.peerPolicy.latencies.observe(time.Since(start))
+	}
+	return value, err
+}
+
+// hedgePeer 选择对冲请求的目标：优先下一个副本，没有时再次请求负责节点
+func (g *Group) hedgePeer(key string, primary Peer) Peer {
+	if picker, ok := g.peers.(multiPeerPicker); ok {
+		if peers := picker.PickPeers(key, 2); len(peers) > 1 {
+			return peers[1]
+		}
+	}
+	return primary
+}
+
+// latencyWindowSize 计算分位数时保留的最近样本数
+const latencyWindowSize = 256
+
+// latencyWindow 最近若干次请求延迟的环形缓冲区
+type latencyWindow struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+func newLatencyWindow(size int) *latencyWindow {
+	return &latencyWindow{samples: make([]time.Duration, size)}
+}
+
+func (w *latencyWindow) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % len(w.samples)
+	if w.next == 0 {
+		w.full = true
+	}
+}
+
+// percentile 返回p分位数，样本数少于minSamples时返回false
+func (w *latencyWindow) percentile(p float64, minSamples int) (time.Duration, bool) {
+	w.mu.Lock()
+	n := w.next
+	if w.full {
+		n = len(w.samples)
+	}
+	if n == 0 || n < minSamples {
+		w.mu.Unlock()
+		return 0, false
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, w.samples[:n])
+	w.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p*float64(n)+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= n {
+		idx = n - 1
+	}
+	return sorted[idx], true
+}
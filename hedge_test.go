@@ -0,0 +1,254 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakePeer 按预设延迟和错误序列响应Get
+type fakePeer struct {
+	name  string
+	delay time.Duration
+	errs  []error // 前len(errs)次调用依次返回的错误
+	calls int32
+}
+
+func (f *fakePeer) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	n := int(atomic.AddInt32(&f.calls, 1)) - 1
+	if n < len(f.errs) && f.errs[n] != nil {
+		return nil, f.errs[n]
+	}
+	select {
+	case <-time.After(f.delay):
+		return []byte(f.name), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakePeer) Set(ctx context.Context, group string, key string, value []byte) error {
+	return nil
+}
+
+func (f *fakePeer) Delete(ctx context.Context, group string, key string) (bool, error) {
+	return true, nil
+}
+
+func (f *fakePeer) Close() error { return nil }
+
+// fakePeerPicker 所有key都路由到固定的候选列表
+type fakePeerPicker struct {
+	peers []Peer
+}
+
+func (p *fakePeerPicker) PickPeer(key string) (Peer, bool, bool) { return p.peers[0], true, false }
+func (p *fakePeerPicker) PickPeers(key string, n int) []Peer {
+	if n > len(p.peers) {
+		n = len(p.peers)
+	}
+	return p.peers[:n]
+}
+func (p *fakePeerPicker) Close() error { return nil }
+
+func newPolicyGroup(t *testing.T, policy PeerPolicy, peers ...Peer) *Group {
+	g := NewGroup(t.Name(), 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("getter"), nil
+	}), WIthPeers(&fakePeerPicker{peers: peers}), WithPeerPolicy(policy))
+	t.Cleanup(func() { g.Close() })
+	return g
+}
+
+// TestPeerPolicy_RetriesUnavailable 节点暂时不可用时退避重试，而不是直接回源
+func TestPeerPolicy_RetriesUnavailable(t *testing.T) {
+	owner := &fakePeer{name: "owner", errs: []error{status.Error(codes.Unavailable, "down")}}
+	g := newPolicyGroup(t, PeerPolicy{MaxRetries: 2, BaseBackoff: time.Millisecond}, owner)
+
+	v, err := g.Get(context.Background(), "k")
+	if err != nil || v.String() != "owner" {
+		t.Fatalf("Get = %q, %v; want owner", v.String(), err)
+	}
+	if got := g.Stats().PeerRetries; got != 1 {
+		t.Fatalf("PeerRetries = %d, want 1", got)
+	}
+}
+
+// TestPeerPolicy_NoRetryOnPermanentError 不可重试的错误直接回源
+func TestPeerPolicy_NoRetryOnPermanentError(t *testing.T) {
+	owner := &fakePeer{name: "owner", errs: []error{status.Error(codes.NotFound, "no group")}}
+	g := newPolicyGroup(t, PeerPolicy{MaxRetries: 3, BaseBackoff: time.Millisecond}, owner)
+
+	v, err := g.Get(context.Background(), "k")
+	if err != nil || v.String() != "getter" {
+		t.Fatalf("Get = %q, %v; want getter", v.String(), err)
+	}
+	if calls := atomic.LoadInt32(&owner.calls); calls != 1 {
+		t.Fatalf("owner called %d times, want 1", calls)
+	}
+}
+
+// TestPeerPolicy_SetIdempotency 写请求只在Client确认没有发出时重试，幂等Set按Get的规则重试
+func TestPeerPolicy_SetIdempotency(t *testing.T) {
+	timeout := status.Error(codes.DeadlineExceeded, "slow")
+	unavailable := status.Error(codes.Unavailable, "down")
+	notSent := fmt.Errorf("%w: breaker open", errPeerUnreachable)
+	tests := []struct {
+		name       string
+		op         string
+		idempotent bool
+		err        error
+		wantCalls  int
+	}{
+		{"set timeout", "set", false, timeout, 1},
+		{"idempotent set timeout", "set", true, timeout, 3},
+		{"set unavailable", "set", false, unavailable, 1},
+		{"set not sent", "set", false, notSent, 3},
+		{"delete timeout", "delete", false, timeout, 1},
+		{"delete unavailable", "delete", false, unavailable, 1},
+		{"delete not sent", "delete", false, notSent, 3},
+		{"get unavailable", "get", false, unavailable, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newPolicyGroup(t, PeerPolicy{MaxRetries: 2, BaseBackoff: time.Millisecond, IdempotentSet: tt.idempotent})
+			calls := 0
+			err := g.callPeer(context.Background(), tt.op, func(ctx context.Context) error {
+				calls++
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// TestPeerPolicy_RetryStopsOnCancel 调用方取消后不再重试
+func TestPeerPolicy_RetryStopsOnCancel(t *testing.T) {
+	g := newPolicyGroup(t, PeerPolicy{MaxRetries: 5, BaseBackoff: time.Hour, MaxBackoff: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	g.callPeer(ctx, "get", func(ctx context.Context) error {
+		calls++
+		return status.Error(codes.Unavailable, "down")
+	})
+	if calls != 1 || time.Since(start) > time.Second {
+		t.Fatalf("calls = %d after %v, retry should stop when ctx is done", calls, time.Since(start))
+	}
+}
+
+// TestHedge_SlowOwner 负责节点变慢时，对冲请求发往下一个副本并先返回
+func TestHedge_SlowOwner(t *testing.T) {
+	owner := &fakePeer{name: "owner", delay: time.Second}
+	replica := &fakePeer{name: "replica"}
+	g := newPolicyGroup(t, PeerPolicy{HedgeDelay: 10 * time.Millisecond}, owner, replica)
+
+	start := time.Now()
+	v, err := g.Get(context.Background(), "k")
+	if err != nil || v.String() != "replica" {
+		t.Fatalf("Get = %q, %v; want replica", v.String(), err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("hedged Get took %v", elapsed)
+	}
+	stats := g.Stats()
+	if stats.Hedges != 1 || stats.HedgeWins != 1 {
+		t.Fatalf("Hedges = %d, HedgeWins = %d; want 1, 1", stats.Hedges, stats.HedgeWins)
+	}
+}
+
+// TestHedge_FastOwner 负责节点在对冲延迟内返回时不发对冲请求
+func TestHedge_FastOwner(t *testing.T) {
+	owner := &fakePeer{name: "owner"}
+	replica := &fakePeer{name: "replica"}
+	g := newPolicyGroup(t, PeerPolicy{HedgeDelay: time.Second}, owner, replica)
+
+	v, err := g.Get(context.Background(), "k")
+	if err != nil || v.String() != "owner" {
+		t.Fatalf("Get = %q, %v; want owner", v.String(), err)
+	}
+	if calls := atomic.LoadInt32(&replica.calls); calls != 0 || g.Stats().Hedges != 0 {
+		t.Fatalf("replica called %d times, want no hedge", calls)
+	}
+}
+
+// TestHedge_NoReplicaRetriesOwner 没有其他副本时对冲请求再次发给负责节点
+func TestHedge_NoReplicaRetriesOwner(t *testing.T) {
+	owner := &fakePeer{name: "owner", delay: 50 * time.Millisecond}
+	g := newPolicyGroup(t, PeerPolicy{HedgeDelay: 10 * time.Millisecond}, owner)
+
+	if _, err := g.Get(context.Background(), "k"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := atomic.LoadInt32(&owner.calls); calls != 2 {
+		t.Fatalf("owner called %d times, want 2", calls)
+	}
+}
+
+// TestLatencyWindow_Percentile 分位数计算与样本不足时的回退
+func TestLatencyWindow_Percentile(t *testing.T) {
+	w := newLatencyWindow(100)
+	if _, ok := w.percentile(0.95, 1); ok {
+		t.Fatal("empty window should have no percentile")
+	}
+	for i := 1; i <= 200; i++ {
+		w.observe(time.Duration(i) * time.Millisecond)
+	}
+	// 只保留最近100个样本：101ms~200ms
+	if d, ok := w.percentile(0.95, 10); !ok || d != 195*time.Millisecond {
+		t.Fatalf("p95 = %v, %v; want 195ms", d, ok)
+	}
+	if d, _ := w.percentile(0.5, 10); d != 150*time.Millisecond {
+		t.Fatalf("p50 = %v, want 150ms", d)
+	}
+}
+
+// TestLoadData_FromPeerNotForwarded 其他节点转发来的请求不会再次转发
+func TestLoadData_FromPeerNotForwarded(t *testing.T) {
+	owner := &fakePeer{name: "owner"}
+	g := newPolicyGroup(t, DefaultPeerPolicy(), owner)
+
+	ctx := context.WithValue(context.Background(), "from_peer", true)
+	v, err := g.Get(ctx, "k")
+	if err != nil || v.String() != "getter" {
+		t.Fatalf("Get = %q, %v; want getter", v.String(), err)
+	}
+	if calls := atomic.LoadInt32(&owner.calls); calls != 0 {
+		t.Fatalf("peer called %d times, want 0", calls)
+	}
+}
+
+// TestServer_PeerGetNotForwarded 经Client转发的Get在对端本地处理，不会被再次转发
+func TestServer_PeerGetNotForwarded(t *testing.T) {
+	other := &fakePeer{name: "other"}
+	g := NewGroup("peer-get-local", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("getter"), nil
+	}), WIthPeers(&fakePeerPicker{peers: []Peer{other}}))
+	defer g.Close()
+
+	client, err := NewClient(startTestServer(t), "test", nil, WithClientDialTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	value, err := client.Get(context.Background(), "peer-get-local", "k")
+	if err != nil || string(value) != "getter" {
+		t.Fatalf("Get = %q, %v; want getter", value, err)
+	}
+	if calls := atomic.LoadInt32(&other.calls); calls != 0 {
+		t.Fatalf("forwarded request was forwarded again %d times", calls)
+	}
+}
@@ -0,0 +1,469 @@
+package blockcache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	pb "github.com/crypt0walker/BlockCache/pb"
+	"github.com/crypt0walker/BlockCache/registry"
+	"github.com/crypt0walker/BlockCache/store"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// HTTP/JSON接口
+// 供不使用gRPC的服务和运维工具访问，与gRPC接口共用Group的读写路径和认证授权：
+//
+//	GET    /groups/{group}/keys/{key}  读取，响应体为原始value
+//	PUT    /groups/{group}/keys/{key}  写入，请求体为原始value，可选?ttl=30s
+//	DELETE /groups/{group}/keys/{key}  删除
+//	GET    /groups                     列出所有组
+//	GET    /groups/{group}/stats       组统计与本地缓存用量（时间单位为纳秒）
+//	POST   /groups/{group}/clear       清空组的本地缓存
+//	GET    /peers                      已发现的节点及熔断状态
+//	GET    /ring                       哈希环上各节点的虚拟节点数和负责比例
+//
+// 认证凭证放在HTTP头中，与gRPC元数据同名（authorization、x-blockcache-*）。
+// Server配置了TLS时HTTP接口和/metrics使用同一份证书提供HTTPS，开启mTLS时客户端证书也可用于认证。
+// 数据接口的签名方法名与gRPC相同（如/pb.BlockCache/Get），管理接口为/blockcache.Admin/<名称>并按OpAdmin授权。
+// 错误以JSON返回：{"code": gRPC状态码, "reason": ErrorInfo原因, "message": 描述}。
+
+// adminMethodPrefix 管理接口在认证中使用的方法名前缀
+const adminMethodPrefix = "/blockcache.Admin/"
+
+// WithHTTP 在addr上提供HTTP/JSON接口，picker用于/peers和/ring，可为nil
+func WithHTTP(addr string, picker *ClientPicker) ServerOption {
+	return func(o *ServerOptions) {
+		o.HTTPAddr = addr
+		if picker != nil {
+			o.Picker = picker
+		}
+	}
+}
+
+// startHTTP 启动HTTP/JSON接口
+func (s *Server) startHTTP() error {
+	srv, err := serveHTTP(s.opts.HTTPAddr, s.HTTPHandler(), "http api", s.certs)
+	if err != nil {
+		return err
+	}
+	s.httpSrv = srv
+	return nil
+}
+
+// serveHTTP 在addr上后台提供HTTP服务，name用于日志
+// certs不为nil时使用与gRPC相同的证书提供HTTPS，开启mTLS时同样要求客户端证书，认证器可以从r.TLS读取身份
+func serveHTTP(addr string, handler http.Handler, name string, certs *CertReloader) (*http.Server, error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for %s: %w", name, err)
+	}
+	srv := &http.Server{Addr: lis.Addr().String(), Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	scheme := "http"
+	if certs != nil {
+		srv.TLSConfig = certs.ServerTLSConfig()
+		scheme = "https"
+	}
+	go func() {
+		var err error
+		if certs != nil {
+			//证书由TLSConfig.GetCertificate提供
+			err = srv.ServeTLS(lis, "", "")
+		} else {
+			err = srv.Serve(lis)
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logrus.Errorf("%s server stopped: %v", name, err)
+		}
+	}()
+	logrus.Infof("Serving %s at %s://%s", name, scheme, srv.Addr)
+	return srv, nil
+}
+
+// HTTPHandler 返回HTTP/JSON接口的处理器，可以挂载到已有的HTTP服务上
+func (s *Server) HTTPHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /groups/{group}/keys/{key...}", s.httpGet)
+	mux.HandleFunc("PUT /groups/{group}/keys/{key...}", s.httpSet)
+	mux.HandleFunc("DELETE /groups/{group}/keys/{key...}", s.httpDelete)
+	mux.HandleFunc("GET /groups", s.httpListGroups)
+	mux.HandleFunc("GET /groups/{group}/stats", s.httpGroupStats)
+	mux.HandleFunc("POST /groups/{group}/clear", s.httpClear)
+	mux.HandleFunc("PUT /groups/{group}", s.httpCreateGroup)
+	mux.HandleFunc("PATCH /groups/{group}", s.httpResizeGroup)
+	mux.HandleFunc("DELETE /groups/{group}", s.httpDestroyGroup)
+	mux.HandleFunc("GET /peers", s.httpPeers)
+	mux.HandleFunc("GET /ring", s.httpRing)
+	return mux
+}
+
+// grpcMethod 数据接口对应的gRPC方法全名
+func grpcMethod(name string) string {
+	return "/" + pb.BlockCache_ServiceDesc.ServiceName + "/" + name
+}
+
+// httpAuth 用与gRPC相同的认证器和ACL检查HTTP请求，未开启认证时直接放行
+func (s *Server) httpAuth(r *http.Request, ri RequestInfo, op Operation) (context.Context, error) {
+	if s.opts == nil || s.opts.Auth == nil {
+		return r.Context(), nil
+	}
+	return s.opts.Auth.check(httpAuthContext(r), ri, op)
+}
+
+// httpAuthHeaders 认证不依赖value时在读取请求体之前完成认证，未通过认证的调用方不能让服务端读取请求体；
+// 返回done为false时需要读取请求体后带上value调用httpAuth
+func (s *Server) httpAuthHeaders(r *http.Request, ri RequestInfo, op Operation) (ctx context.Context, done bool, err error) {
+	if s.opts == nil || s.opts.Auth == nil {
+		return r.Context(), true, nil
+	}
+	ctx = httpAuthContext(r)
+	if s.opts.Auth.needsValue(ctx) {
+		return nil, false, nil
+	}
+	ctx, err = s.opts.Auth.check(ctx, ri, op)
+	return ctx, true, err
+}
+
+// httpAuthContext 把HTTP头和TLS状态转换成认证器读取的gRPC元数据和peer信息
+func httpAuthContext(r *http.Request) context.Context {
+	md := metadata.MD{}
+	for k, v := range r.Header {
+		md[strings.ToLower(k)] = v
+	}
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	if r.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{State: *r.TLS}})
+	}
+	return ctx
+}
+
+func (s *Server) httpGet(w http.ResponseWriter, r *http.Request) {
+	group, key := r.PathValue("group"), r.PathValue("key")
+	ctx, err := s.httpAuth(r, RequestInfo{FullMethod: grpcMethod("Get"), Group: group, Key: key}, OpGet)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	value, err := s.getValue(ctx, group, key)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(value)
+}
+
+func (s *Server) httpSet(w http.ResponseWriter, r *http.Request) {
+	group, key := r.PathValue("group"), r.PathValue("key")
+	ri := RequestInfo{FullMethod: grpcMethod("Set"), Group: group, Key: key}
+	ctx, authed, err := s.httpAuthHeaders(r, ri, OpSet)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(s.maxMsgSize())))
+	if err != nil {
+		writeHTTPError(w, fmt.Errorf("%w: %v", ErrInvalidArgument, err))
+		return
+	}
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		if ttl, err = time.ParseDuration(v); err != nil || ttl < 0 {
+			writeHTTPError(w, fmt.Errorf("%w: invalid ttl %q", ErrInvalidArgument, v))
+			return
+		}
+	}
+	if !authed {
+		ri.Value = value
+		if ctx, err = s.httpAuth(r, ri, OpSet); err != nil {
+			writeHTTPError(w, err)
+			return
+		}
+	}
+	// 与外部gRPC调用方不同，HTTP调用方不是节点，写入后照常同步给负责该key的节点
+	if err := s.setValue(ctx, group, key, value, ttl); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) httpDelete(w http.ResponseWriter, r *http.Request) {
+	group, key := r.PathValue("group"), r.PathValue("key")
+	ctx, err := s.httpAuth(r, RequestInfo{FullMethod: grpcMethod("Delete"), Group: group, Key: key}, OpDelete)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	if err := s.deleteValue(ctx, group, key); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) httpListGroups(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.httpAuth(r, RequestInfo{FullMethod: adminMethodPrefix + "ListGroups", Group: "*"}, OpAdmin); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	names := ListGroups()
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string][]string{"groups": names})
+}
+
+// groupStatsResponse /groups/{group}/stats的响应
+type groupStatsResponse struct {
+	Group     string          `json:"group"`
+	CacheType store.CacheType `json:"cacheType"`
+	Stats     GroupStats      `json:"stats"`
+	Cache     store.Stats     `json:"cache"`
+}
+
+func (s *Server) httpGroupStats(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	if _, err := s.httpAuth(r, RequestInfo{FullMethod: adminMethodPrefix + "GroupStats", Group: name}, OpAdmin); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	group := GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, groupNotFound(name))
+		return
+	}
+	writeJSON(w, http.StatusOK, groupStatsResponse{
+		Group:     name,
+		CacheType: group.mainCache.CacheType(),
+		Stats:     group.Stats(),
+		Cache:     group.mainCache.StoreStats(),
+	})
+}
+
+func (s *Server) httpClear(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	if _, err := s.httpAuth(r, RequestInfo{FullMethod: adminMethodPrefix + "Clear", Group: name}, OpAdmin); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	group := GetGroup(name)
+	if group == nil {
+		writeHTTPError(w, groupNotFound(name))
+		return
+	}
+	group.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// httpCreateGroup 请求体为组定义（JSON），组名以路径为准
+func (s *Server) httpCreateGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	ctx, err := s.httpAuth(r, RequestInfo{FullMethod: grpcMethod("CreateGroup"), Group: name}, OpAdmin)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	var def registry.GroupDefinition
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(s.maxMsgSize()))).Decode(&def); err != nil {
+		writeHTTPError(w, fmt.Errorf("%w: %v", ErrInvalidArgument, err))
+		return
+	}
+	def.Name = name
+	if err := s.createGroup(ctx, def); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, def)
+}
+
+// resizeRequest PATCH /groups/{group}的请求体
+type resizeRequest struct {
+	MaxBytes  int64  `json:"maxBytes,omitempty"`  // 0表示不修改
+	CacheType string `json:"cacheType,omitempty"` // 空表示不修改
+}
+
+func (s *Server) httpResizeGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	ctx, err := s.httpAuth(r, RequestInfo{FullMethod: grpcMethod("ResizeGroup"), Group: name}, OpAdmin)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	var req resizeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, int64(s.maxMsgSize()))).Decode(&req); err != nil {
+		writeHTTPError(w, fmt.Errorf("%w: %v", ErrInvalidArgument, err))
+		return
+	}
+	def, err := s.resizeGroup(ctx, name, req.MaxBytes, req.CacheType)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (s *Server) httpDestroyGroup(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("group")
+	ctx, err := s.httpAuth(r, RequestInfo{FullMethod: grpcMethod("DestroyGroup"), Group: name}, OpAdmin)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	existed, err := s.destroyGroup(ctx, name)
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	if !existed {
+		writeHTTPError(w, fmt.Errorf("%w: %s", registry.ErrGroupUndefined, name))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// peerResponse /peers中的一个节点
+type peerResponse struct {
+	registry.Endpoint
+	Breaker string `json:"breaker"`
+}
+
+func (s *Server) httpPeers(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.httpAuth(r, RequestInfo{FullMethod: adminMethodPrefix + "Peers", Group: "*"}, OpAdmin); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	picker, err := s.picker()
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	states := picker.BreakerStates()
+	endpoints := picker.Endpoints()
+	peers := make([]peerResponse, 0, len(endpoints))
+	for _, ep := range endpoints {
+		peers = append(peers, peerResponse{Endpoint: ep, Breaker: states[ep.Addr].String()})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].Addr < peers[j].Addr })
+	writeJSON(w, http.StatusOK, map[string]interface{}{"self": picker.selfAddr, "peers": peers})
+}
+
+// ringNodeResponse /ring中的一个节点
+type ringNodeResponse struct {
+	Node      string  `json:"node"`
+	Weight    int     `json:"weight"`
+	Replicas  int     `json:"replicas"`
+	Ownership float64 `json:"ownership"`
+}
+
+func (s *Server) httpRing(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.httpAuth(r, RequestInfo{FullMethod: adminMethodPrefix + "Ring", Group: "*"}, OpAdmin); err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	picker, err := s.picker()
+	if err != nil {
+		writeHTTPError(w, err)
+		return
+	}
+	snap, err := picker.RingSnapshot()
+	if err != nil {
+		writeHTTPError(w, status.Error(codes.Unimplemented, err.Error()))
+		return
+	}
+	nodes := make([]ringNodeResponse, 0, len(snap.Nodes))
+	for _, n := range snap.Nodes {
+		nodes = append(nodes, ringNodeResponse{Node: n.Node, Weight: n.Weight, Replicas: n.Replicas, Ownership: n.Ownership})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"takenAt": snap.TakenAt,
+		"points":  len(snap.Points),
+		"nodes":   nodes,
+	})
+}
+
+// picker 返回配置的节点选择器
+func (s *Server) picker() (*ClientPicker, error) {
+	if s.opts == nil || s.opts.Picker == nil {
+		return nil, status.Error(codes.Unimplemented, "server has no peer picker configured")
+	}
+	return s.opts.Picker, nil
+}
+
+// maxMsgSize HTTP请求体的上限，与gRPC消息大小一致
+func (s *Server) maxMsgSize() int {
+	if s.opts != nil && s.opts.MaxMsgSize > 0 {
+		return s.opts.MaxMsgSize
+	}
+	return DefaultServerOptions.MaxMsgSize
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.Warnf("failed to write http response: %v", err)
+	}
+}
+
+// httpError HTTP接口的错误响应
+type httpError struct {
+	Code    string `json:"code"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message"`
+}
+
+// writeHTTPError 按与gRPC相同的规则把错误转换成状态码后输出
+func writeHTTPError(w http.ResponseWriter, err error) {
+	st := status.Convert(toStatus(err))
+	body := httpError{Code: st.Code().String(), Message: st.Message()}
+	for _, d := range st.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			body.Reason = info.GetReason()
+		}
+	}
+	writeJSON(w, httpStatus(st.Code()), body)
+}
+
+// httpStatus gRPC状态码对应的HTTP状态码
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Canceled:
+		return 499
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,254 @@
+package blockcache
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func startHTTPAPI(t *testing.T, o *ServerOptions) *httptest.Server {
+	srv := httptest.NewServer((&Server{opts: o}).HTTPHandler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func doHTTP(t *testing.T, method, url, body string, header map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp, string(data)
+}
+
+// TestHTTPAPI_Keys 读写删除与gRPC接口走同一条Group路径
+func TestHTTPAPI_Keys(t *testing.T) {
+	g := NewGroup("http-keys", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte("loaded:" + key), nil
+	}))
+	defer g.Close()
+	base := startHTTPAPI(t, &ServerOptions{}).URL + "/groups/http-keys/keys/"
+
+	if resp, body := doHTTP(t, "GET", base+"a/b", "", nil); resp.StatusCode != 200 || body != "loaded:a/b" {
+		t.Fatalf("GET = %d %q", resp.StatusCode, body)
+	}
+	if resp, _ := doHTTP(t, "PUT", base+"k", "v1", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("PUT = %d", resp.StatusCode)
+	}
+	if v, err := g.Get(context.Background(), "k"); err != nil || v.String() != "v1" {
+		t.Fatalf("group Get after PUT = %q, %v", v.String(), err)
+	}
+	if resp, _ := doHTTP(t, "DELETE", base+"k", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE = %d", resp.StatusCode)
+	}
+
+	tests := []struct {
+		method, url, body string
+		status            int
+		reason            string
+	}{
+		{"GET", base + "missing", "", http.StatusNotFound, "KEY_NOT_FOUND"},
+		{"PUT", base + "k", "", http.StatusBadRequest, "INVALID_ARGUMENT"},
+		{"GET", strings.Replace(base, "http-keys", "no-such-group", 1) + "k", "", http.StatusNotFound, "GROUP_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		resp, body := doHTTP(t, tt.method, tt.url, tt.body, nil)
+		var e httpError
+		if err := json.Unmarshal([]byte(body), &e); err != nil {
+			t.Fatalf("%s %s: invalid error body %q", tt.method, tt.url, body)
+		}
+		if resp.StatusCode != tt.status || e.Reason != tt.reason {
+			t.Fatalf("%s %s = %d %s, want %d %s", tt.method, tt.url, resp.StatusCode, e.Reason, tt.status, tt.reason)
+		}
+	}
+}
+
+// TestHTTPAPI_Admin 列出组、统计、清空，没有节点选择器时节点接口返回501
+func TestHTTPAPI_Admin(t *testing.T) {
+	g := NewGroup("http-admin", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}))
+	defer g.Close()
+	g.Get(context.Background(), "k")
+	base := startHTTPAPI(t, &ServerOptions{}).URL
+
+	_, body := doHTTP(t, "GET", base+"/groups", "", nil)
+	var list struct{ Groups []string }
+	json.Unmarshal([]byte(body), &list)
+	found := false
+	for _, name := range list.Groups {
+		found = found || name == "http-admin"
+	}
+	if !found {
+		t.Fatalf("groups = %v", list.Groups)
+	}
+
+	_, body = doHTTP(t, "GET", base+"/groups/http-admin/stats", "", nil)
+	var stats groupStatsResponse
+	if err := json.Unmarshal([]byte(body), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Stats.LoaderHits != 1 || stats.Cache.Items != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	if resp, _ := doHTTP(t, "POST", base+"/groups/http-admin/clear", "", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("clear = %d", resp.StatusCode)
+	}
+	if n := g.mainCache.StoreStats().Items; n != 0 {
+		t.Fatalf("items after clear = %d", n)
+	}
+
+	for _, path := range []string{"/peers", "/ring"} {
+		if resp, _ := doHTTP(t, "GET", base+path, "", nil); resp.StatusCode != http.StatusNotImplemented {
+			t.Fatalf("%s = %d, want 501", path, resp.StatusCode)
+		}
+	}
+}
+
+// TestHTTPAPI_Auth HTTP接口使用与gRPC相同的认证器和ACL
+func TestHTTPAPI_Auth(t *testing.T) {
+	newAuthTestGroup(t, "http-auth")
+	base := startHTTPAPI(t, &ServerOptions{Auth: &AuthOptions{
+		Authenticators: []Authenticator{
+			NewStaticTokenAuth(map[string]string{"r": "reader", "a": "admin"}),
+			NewHMACAuth(map[string][]byte{"node-b": []byte("secret")}, 0),
+		},
+		ACL: &ACL{Rules: []ACLRule{
+			{Identity: "reader", Groups: []string{"http-auth"}, Ops: []Operation{OpGet}},
+			{Identity: "admin", Groups: []string{"*"}, Ops: []Operation{"*"}},
+			{Identity: "node-b", Groups: []string{"http-auth"}, Ops: []Operation{OpSet}},
+		}},
+	}}).URL
+	reader := map[string]string{"Authorization": "Bearer r"}
+	admin := map[string]string{"Authorization": "Bearer a"}
+
+	tests := []struct {
+		name, method, path string
+		header             map[string]string
+		status             int
+	}{
+		{"anonymous", "GET", "/groups/http-auth/keys/k", nil, http.StatusUnauthorized},
+		{"reader get", "GET", "/groups/http-auth/keys/k", reader, http.StatusOK},
+		{"reader set", "PUT", "/groups/http-auth/keys/k", reader, http.StatusForbidden},
+		{"reader admin", "GET", "/groups", reader, http.StatusForbidden},
+		{"admin list", "GET", "/groups", admin, http.StatusOK},
+		{"admin clear", "POST", "/groups/http-auth/clear", admin, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if resp, body := doHTTP(t, tt.method, base+tt.path, "x", tt.header); resp.StatusCode != tt.status {
+			t.Errorf("%s: %d %s, want %d", tt.name, resp.StatusCode, body, tt.status)
+		}
+	}
+
+	// HMAC签名与gRPC使用同样的方法名，覆盖请求体
+	req := RequestInfo{FullMethod: grpcMethod("Set"), Group: "http-auth", Key: "k", Value: []byte("signed")}
+	creds, _ := HMACSigner("node-b", []byte("secret")).Credentials(context.Background(), req)
+	if resp, body := doHTTP(t, "PUT", base+"/groups/http-auth/keys/k", "signed", creds); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("signed PUT = %d %s", resp.StatusCode, body)
+	}
+	if resp, _ := doHTTP(t, "PUT", base+"/groups/http-auth/keys/k", "tampered", creds); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("tampered PUT = %d", resp.StatusCode)
+	}
+}
+
+// unreadBody 请求体被读取时让测试失败
+type unreadBody struct{ t *testing.T }
+
+func (b unreadBody) Read(p []byte) (int, error) {
+	b.t.Error("request body was read before authentication")
+	return 0, io.EOF
+}
+
+// TestHTTPAPI_AuthBeforeBody 认证不依赖value时，未通过认证的写请求不会读取请求体
+func TestHTTPAPI_AuthBeforeBody(t *testing.T) {
+	newAuthTestGroup(t, "http-auth-body")
+	s := &Server{opts: &ServerOptions{Auth: &AuthOptions{
+		Authenticators: []Authenticator{
+			NewStaticTokenAuth(map[string]string{"r": "reader"}),
+			NewHMACAuth(map[string][]byte{"node-b": []byte("secret")}, 0),
+		},
+		ACL: &ACL{Rules: []ACLRule{{Identity: "reader", Groups: []string{"*"}, Ops: []Operation{OpGet}}}},
+	}}}
+	handler := s.HTTPHandler()
+
+	for name, header := range map[string]string{"anonymous": "", "bad token": "Bearer nope", "forbidden": "Bearer r"} {
+		req := httptest.NewRequest("PUT", "/groups/http-auth-body/keys/k", unreadBody{t})
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusForbidden {
+			t.Errorf("%s: status %d", name, rec.Code)
+		}
+	}
+}
+
+// TestHTTPAPI_TLS 配置了TLS时HTTP接口使用同一份证书，mTLS的客户端证书可以用于认证
+func TestHTTPAPI_TLS(t *testing.T) {
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, 20, "node-a")
+	cfg := writeTLSFiles(t, t.TempDir(), ca, certPEM, keyPEM)
+	cfg.RequireClientCert = true
+	certs, err := NewCertReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer certs.Close()
+
+	s := &Server{opts: &ServerOptions{Auth: &AuthOptions{
+		Authenticators: []Authenticator{MTLSAuth{}},
+		ACL:            &ACL{Rules: []ACLRule{{Identity: "node-b", Groups: []string{"*"}, Ops: []Operation{OpAdmin}}}},
+	}}}
+	srv, err := serveHTTP("127.0.0.1:0", s.HTTPHandler(), "test", certs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	clientPEM, clientKey := ca.issue(t, 21, "node-b")
+	clientCert, err := tls.X509KeyPair(clientPEM, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{clientCert},
+	}}}
+	resp, err := client.Get("https://" + srv.Addr + "/groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("mTLS GET /groups = %d", resp.StatusCode)
+	}
+
+	// 没有客户端证书时握手失败
+	noCert := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := noCert.Get("https://" + srv.Addr + "/groups"); err == nil {
+		resp.Body.Close()
+		t.Fatal("request without client certificate succeeded")
+	}
+}
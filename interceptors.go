@@ -0,0 +1,143 @@
+package blockcache
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// 服务端拦截器链
+// NewServer按以下顺序组装拦截器：panic恢复（总是开启）→ 追踪 → 认证授权 → 用户配置的拦截器。
+// 日志、指标、限流等横切逻辑通过WithUnaryInterceptors/WithStreamInterceptors接入，
+// 不需要修改server.go。
+
+// WithUnaryInterceptors 追加一元调用拦截器，按传入顺序执行
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) ServerOption {
+	return func(o *ServerOptions) {
+		o.UnaryInterceptors = append(o.UnaryInterceptors, interceptors...)
+	}
+}
+
+// WithStreamInterceptors 追加流式调用拦截器，按传入顺序执行
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) ServerOption {
+	return func(o *ServerOptions) {
+		o.StreamInterceptors = append(o.StreamInterceptors, interceptors...)
+	}
+}
+
+// serverInterceptors 按固定顺序组装拦截器链
+func serverInterceptors(o *ServerOptions) []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{RecoveryUnaryInterceptor()}
+	stream := []grpc.StreamServerInterceptor{RecoveryStreamInterceptor()}
+	if o.TracerProvider != nil {
+		tracer := newTracer(o.TracerProvider)
+		unary = append(unary, serverTracingUnary(tracer))
+		stream = append(stream, serverTracingStream(tracer))
+	}
+	if o.Auth != nil {
+		unary = append(unary, o.Auth.unaryInterceptor)
+		stream = append(stream, o.Auth.streamInterceptor)
+	}
+	unary = append(unary, o.UnaryInterceptors...)
+	stream = append(stream, o.StreamInterceptors...)
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+// RecoveryUnaryInterceptor 把处理链中的panic转换为INTERNAL错误，避免整个节点崩溃
+// 注意Getter运行在singleflight的goroutine中，其中的panic不经过拦截器
+func RecoveryUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// RecoveryStreamInterceptor 流式调用的panic恢复
+func RecoveryStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		logrus.Errorf("panic in %s: %v\n%s", method, r, debug.Stack())
+		*err = status.Errorf(codes.Internal, "internal error in %s", method)
+	}
+}
+
+// LoggingUnaryInterceptor 记录每个请求的方法、组、key、耗时和状态码
+func LoggingUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		ri := requestInfo(info.FullMethod, req)
+		entry := logrus.WithFields(logrus.Fields{
+			"method":  info.FullMethod,
+			"group":   ri.Group,
+			"key":     ri.Key,
+			"latency": time.Since(start),
+			"code":    status.Code(err),
+		})
+		if err != nil {
+			entry.Warnf("request failed: %v", err)
+		} else {
+			entry.Info("request served")
+		}
+		return resp, err
+	}
+}
+
+// LoggingStreamInterceptor 记录流式调用的方法、耗时和状态码
+func LoggingStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		entry := logrus.WithFields(logrus.Fields{
+			"method":  info.FullMethod,
+			"latency": time.Since(start),
+			"code":    status.Code(err),
+		})
+		if err != nil {
+			entry.Warnf("stream failed: %v", err)
+		} else {
+			entry.Info("stream served")
+		}
+		return err
+	}
+}
+
+// DeadlineUnaryInterceptor 限制请求的最长处理时间：调用方没有设置截止时间或截止时间更晚时使用max
+func DeadlineUnaryInterceptor(max time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := context.WithTimeout(ctx, max)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// DeadlineStreamInterceptor 限制流式调用的最长持续时间
+func DeadlineStreamInterceptor(max time.Duration) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := context.WithTimeout(ss.Context(), max)
+		defer cancel()
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream 替换了Context的ServerStream
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context { return s.ctx }
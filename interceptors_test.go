@@ -0,0 +1,121 @@
+package blockcache
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	pb "github.com/crypt0walker/BlockCache/pb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// startOptionsServer 按ServerOptions组装拦截器链并启动不依赖etcd的测试服务
+func startOptionsServer(t *testing.T, o *ServerOptions, opts ...grpc.ServerOption) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := grpc.NewServer(append(opts, serverInterceptors(o)...)...)
+	pb.RegisterBlockCacheServer(gs, &Server{opts: o})
+	go gs.Serve(lis)
+	t.Cleanup(gs.Stop)
+	return lis.Addr().String()
+}
+
+// TestInterceptors_RecoverPanic 处理链中的panic转换为INTERNAL，节点继续服务
+func TestInterceptors_RecoverPanic(t *testing.T) {
+	newAuthTestGroup(t, "interceptor-panic")
+	buggy := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if req.(*pb.Request).GetKey() == "boom" {
+			panic("middleware exploded")
+		}
+		return handler(ctx, req)
+	}
+	o := &ServerOptions{}
+	WithUnaryInterceptors(buggy)(o)
+
+	client := dialAuth(t, startOptionsServer(t, o))
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "interceptor-panic", "boom"); status.Code(err) != codes.Internal {
+		t.Fatalf("code = %v, want Internal", status.Code(err))
+	}
+	if v, err := client.Get(ctx, "interceptor-panic", "fine"); err != nil || string(v) != "v" {
+		t.Fatalf("Get after panic = %q, %v", v, err)
+	}
+}
+
+// TestInterceptors_Order 用户拦截器在认证之后执行，能读到调用方身份
+func TestInterceptors_Order(t *testing.T) {
+	newAuthTestGroup(t, "interceptor-order")
+	var seen []string
+	record := func(name string) grpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			identity, _ := IdentityFromContext(ctx)
+			seen = append(seen, name+":"+identity)
+			return handler(ctx, req)
+		}
+	}
+	o := &ServerOptions{}
+	WithAuth(AuthOptions{Authenticators: []Authenticator{NewStaticTokenAuth(map[string]string{"t": "alice"})}})(o)
+	WithUnaryInterceptors(record("first"), record("second"))(o)
+
+	client := dialAuth(t, startOptionsServer(t, o), WithClientAuth(BearerToken("t")))
+	if _, err := client.Get(context.Background(), "interceptor-order", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(seen, ","); got != "first:alice,second:alice" {
+		t.Fatalf("interceptors ran as %s", got)
+	}
+}
+
+// TestDeadlineInterceptor 调用方没有截止时间时使用服务端上限
+func TestDeadlineInterceptor(t *testing.T) {
+	g := NewGroup("interceptor-deadline", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	defer g.Close()
+
+	o := &ServerOptions{}
+	WithUnaryInterceptors(DeadlineUnaryInterceptor(50 * time.Millisecond))(o)
+	client := dialAuth(t, startOptionsServer(t, o), WithRPCTimeouts(0, 0, 0))
+
+	start := time.Now()
+	_, err := client.Get(context.Background(), "interceptor-deadline", "k")
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Fatalf("code = %v, want DeadlineExceeded", status.Code(err))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("request ran for %v", elapsed)
+	}
+}
+
+// TestLoggingInterceptor 日志包含组、key和状态码
+func TestLoggingInterceptor(t *testing.T) {
+	newAuthTestGroup(t, "interceptor-log")
+	var buf bytes.Buffer
+	logrus.SetOutput(&buf)
+	t.Cleanup(func() { logrus.SetOutput(os.Stderr) })
+
+	o := &ServerOptions{}
+	WithUnaryInterceptors(LoggingUnaryInterceptor())(o)
+	client := dialAuth(t, startOptionsServer(t, o))
+	if _, err := client.Get(context.Background(), "interceptor-log", "user-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{"group=interceptor-log", "key=user-42", "code=OK", "method=/pb.BlockCache/Get"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("log %q missing %q", out, want)
+		}
+	}
+}
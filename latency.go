@@ -0,0 +1,241 @@
+package blockcache
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// 延迟直方图
+// 每个桶是一个原子计数器，记录一次延迟只需要几次原子加法，不加锁。
+// 桶上界从1µs开始按2倍增长，最后一个桶收集超过上界的样本，分位数精度为2倍以内。
+// 设置了统计窗口时，直方图在窗口到期后被原子地替换为新的，Stats报告上一个完整窗口与当前窗口之和，
+// 这样刚切换窗口时也不会只剩寥寥几个样本。
+
+// latencyBucketCount 有上界的桶数，最大上界为1µs<<31（约36分钟）
+const latencyBucketCount = 32
+
+// LatencyBucket 直方图的一个桶
+type LatencyBucket struct {
+	UpperBound time.Duration `json:"upperBound"` // 桶上界（含），溢出桶为math.MaxInt64
+	Count      int64         `json:"count"`      // 落在该桶的样本数（非累积）
+}
+
+// LatencySnapshot 某一时刻直方图的只读拷贝
+type LatencySnapshot struct {
+	Count   int64           `json:"count"`
+	Sum     time.Duration   `json:"sum"`
+	Max     time.Duration   `json:"max"`
+	Since   time.Time       `json:"since"`   // 统计起点
+	Buckets []LatencyBucket `json:"buckets"` // 按上界升序，长度固定；没有样本时为nil
+}
+
+// Mean 平均延迟
+func (s LatencySnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile 返回q分位（0<q<=1）所在桶的上界，不超过观测到的最大值；没有样本时返回0
+func (s LatencySnapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(s.Count)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, b := range s.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			if b.UpperBound > s.Max {
+				return s.Max
+			}
+			return b.UpperBound
+		}
+	}
+	return s.Max
+}
+
+// merge 合并两个快照，用于把上一个窗口和当前窗口一起报告
+func (s LatencySnapshot) merge(o LatencySnapshot) LatencySnapshot {
+	if o.Count == 0 {
+		return s
+	}
+	if s.Count == 0 {
+		return o
+	}
+	out := LatencySnapshot{
+		Count:   s.Count + o.Count,
+		Sum:     s.Sum + o.Sum,
+		Max:     max(s.Max, o.Max),
+		Since:   s.Since,
+		Buckets: make([]LatencyBucket, len(s.Buckets)),
+	}
+	if o.Since.Before(out.Since) {
+		out.Since = o.Since
+	}
+	for i := range s.Buckets {
+		out.Buckets[i] = LatencyBucket{UpperBound: s.Buckets[i].UpperBound, Count: s.Buckets[i].Count + o.Buckets[i].Count}
+	}
+	return out
+}
+
+// latencyHistogram 无锁的指数桶直方图
+type latencyHistogram struct {
+	start   time.Time
+	buckets [latencyBucketCount + 1]atomic.Int64
+	count   atomic.Int64
+	sum     atomic.Int64
+	max     atomic.Int64
+}
+
+func newLatencyHistogram(start time.Time) *latencyHistogram {
+	return &latencyHistogram{start: start}
+}
+
+// latencyBucketIndex 返回d所在的桶：上界为1µs<<i的最小i
+func latencyBucketIndex(d time.Duration) int {
+	if d <= time.Microsecond {
+		return 0
+	}
+	i := bits.Len64(uint64(d-1) / uint64(time.Microsecond))
+	if i > latencyBucketCount {
+		return latencyBucketCount
+	}
+	return i
+}
+
+func latencyBucketBound(i int) time.Duration {
+	if i >= latencyBucketCount {
+		return math.MaxInt64
+	}
+	return time.Microsecond << uint(i)
+}
+
+func (h *latencyHistogram) observe(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.buckets[latencyBucketIndex(d)].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	for {
+		cur := h.max.Load()
+		if int64(d) <= cur || h.max.CompareAndSwap(cur, int64(d)) {
+			return
+		}
+	}
+}
+
+// snapshot 各计数器分别读取，与并发的observe之间只保证最终一致
+func (h *latencyHistogram) snapshot() LatencySnapshot {
+	s := LatencySnapshot{
+		Count: h.count.Load(),
+		Sum:   time.Duration(h.sum.Load()),
+		Max:   time.Duration(h.max.Load()),
+		Since: h.start,
+	}
+	if s.Count == 0 {
+		return s
+	}
+	s.Buckets = make([]LatencyBucket, len(h.buckets))
+	for i := range h.buckets {
+		s.Buckets[i] = LatencyBucket{UpperBound: latencyBucketBound(i), Count: h.buckets[i].Load()}
+	}
+	return s
+}
+
+// latencyRecorder 带可选时间窗口的直方图，零值不可用，需要init
+type latencyRecorder struct {
+	window time.Duration // 0表示一直累积到Reset
+	cur    atomic.Pointer[latencyHistogram]
+	prev   atomic.Pointer[latencyHistogram]
+}
+
+func (r *latencyRecorder) init(window time.Duration) {
+	r.window = window
+	r.cur.Store(newLatencyHistogram(time.Now()))
+	r.prev.Store(nil)
+}
+
+// current 返回当前窗口的直方图，窗口到期时切换；多个goroutine同时切换时只有一个成功
+func (r *latencyRecorder) current(now time.Time) *latencyHistogram {
+	h := r.cur.Load()
+	if r.window <= 0 || now.Sub(h.start) < r.window {
+		return h
+	}
+	next := newLatencyHistogram(now)
+	if r.cur.CompareAndSwap(h, next) {
+		// 空闲超过两个窗口时上一个窗口已经过时
+		if now.Sub(h.start) < 2*r.window {
+			r.prev.Store(h)
+		} else {
+			r.prev.Store(nil)
+		}
+		return next
+	}
+	return r.cur.Load()
+}
+
+func (r *latencyRecorder) observe(d time.Duration) {
+	r.current(time.Now()).observe(d)
+}
+
+// snapshot 返回当前统计；设置了窗口时包含上一个完整窗口
+func (r *latencyRecorder) snapshot() LatencySnapshot {
+	s := r.current(time.Now()).snapshot()
+	if prev := r.prev.Load(); prev != nil {
+		s = prev.snapshot().merge(s)
+	}
+	return s
+}
+
+// reset 清空统计并返回清空前的数据；与reset并发的少量样本可能计入新旧任一侧
+func (r *latencyRecorder) reset() LatencySnapshot {
+	old := r.snapshot()
+	r.prev.Store(nil)
+	r.cur.Store(newLatencyHistogram(time.Now()))
+	return old
+}
+
+// GroupLatencies 组内三类请求的延迟分布
+type GroupLatencies struct {
+	LocalHit LatencySnapshot `json:"localHit"` // 本地缓存命中
+	Peer     LatencySnapshot `json:"peer"`     // 从其他节点获取（含重试与对冲）
+	Loader   LatencySnapshot `json:"loader"`   // 调用Getter回源
+}
+
+// WithLatencyWindow 让延迟直方图只反映最近一到两个窗口内的请求，0表示一直累积到ResetLatencies
+func WithLatencyWindow(window time.Duration) GroupOption {
+	return func(g *Group) {
+		g.stats.initLatencies(window)
+	}
+}
+
+func (s *groupStats) initLatencies(window time.Duration) {
+	s.localHitLatency.init(window)
+	s.peerLatency.init(window)
+	s.loaderLatency.init(window)
+}
+
+func (s *groupStats) latencies() GroupLatencies {
+	return GroupLatencies{
+		LocalHit: s.localHitLatency.snapshot(),
+		Peer:     s.peerLatency.snapshot(),
+		Loader:   s.loaderLatency.snapshot(),
+	}
+}
+
+// ResetLatencies 清空延迟直方图并返回清空前的分布，调用方可以按固定周期调用以自行划分窗口
+func (g *Group) ResetLatencies() GroupLatencies {
+	return GroupLatencies{
+		LocalHit: g.stats.localHitLatency.reset(),
+		Peer:     g.stats.peerLatency.reset(),
+		Loader:   g.stats.loaderLatency.reset(),
+	}
+}
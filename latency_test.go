@@ -0,0 +1,125 @@
+package blockcache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestLatencyBucketIndex 样本落在上界不小于它的最小桶
+func TestLatencyBucketIndex(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{0, 0},
+		{time.Microsecond, 0},
+		{time.Microsecond + 1, 1},
+		{2 * time.Microsecond, 1},
+		{3 * time.Microsecond, 2},
+		{time.Millisecond, 10},
+		{time.Hour, latencyBucketCount},
+	}
+	for _, tt := range tests {
+		if got := latencyBucketIndex(tt.d); got != tt.want {
+			t.Errorf("latencyBucketIndex(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+		if tt.d > latencyBucketBound(tt.want) {
+			t.Errorf("%v exceeds bound %v of bucket %d", tt.d, latencyBucketBound(tt.want), tt.want)
+		}
+	}
+}
+
+// TestLatencyHistogram_Quantile 分位数误差不超过2倍，并发记录不丢样本
+func TestLatencyHistogram_Quantile(t *testing.T) {
+	h := newLatencyHistogram(time.Now())
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= 250; i++ {
+				h.observe(time.Duration(i) * 100 * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := h.snapshot()
+	if s.Count != 1000 {
+		t.Fatalf("count = %d, want 1000", s.Count)
+	}
+	if s.Max != 25*time.Millisecond {
+		t.Fatalf("max = %v", s.Max)
+	}
+	for _, q := range []struct {
+		q    float64
+		real time.Duration
+	}{{0.5, 12500 * time.Microsecond}, {0.99, 24750 * time.Microsecond}} {
+		got := s.Quantile(q.q)
+		if got < q.real || got > 2*q.real {
+			t.Errorf("p%v = %v, want within [%v, %v]", q.q*100, got, q.real, 2*q.real)
+		}
+	}
+	if got := s.Quantile(1); got != s.Max {
+		t.Errorf("p100 = %v, want max %v", got, s.Max)
+	}
+}
+
+// TestLatencyRecorder_Window 窗口到期后旧样本只保留一个窗口
+func TestLatencyRecorder_Window(t *testing.T) {
+	var r latencyRecorder
+	r.init(time.Hour)
+	start := r.cur.Load().start
+
+	r.current(start).observe(time.Millisecond)
+	r.current(start.Add(90 * time.Minute)).observe(time.Millisecond)
+	r.current(start.Add(90 * time.Minute)).observe(time.Millisecond)
+	if got := r.prev.Load().snapshot().Count + r.cur.Load().snapshot().Count; got != 3 {
+		t.Fatalf("after one rotation count = %d, want 3", got)
+	}
+
+	// 第一个窗口已经超出范围
+	r.current(start.Add(150 * time.Minute))
+	if got := r.prev.Load().snapshot().Count; got != 2 {
+		t.Fatalf("previous window count = %d, want 2", got)
+	}
+
+	// 长时间空闲后旧数据全部丢弃
+	r.current(start.Add(10 * time.Hour))
+	if r.prev.Load() != nil {
+		t.Fatal("stale window should be dropped")
+	}
+}
+
+// TestGroup_LatencyStats Stats区分本地命中和回源延迟，ResetLatencies清空并返回旧数据
+func TestGroup_LatencyStats(t *testing.T) {
+	g := NewGroup("latency-stats", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		time.Sleep(5 * time.Millisecond)
+		return []byte("v"), nil
+	}))
+	defer g.Close()
+
+	ctx := context.Background()
+	g.Get(ctx, "k")
+	for i := 0; i < 3; i++ {
+		g.Get(ctx, "k")
+	}
+
+	lat := g.Stats().Latency
+	if lat.Loader.Count != 1 || lat.LocalHit.Count != 3 || lat.Peer.Count != 0 {
+		t.Fatalf("counts: loader=%d local=%d peer=%d", lat.Loader.Count, lat.LocalHit.Count, lat.Peer.Count)
+	}
+	if lat.Loader.Quantile(0.5) < 5*time.Millisecond {
+		t.Fatalf("loader p50 = %v, want >= 5ms", lat.Loader.Quantile(0.5))
+	}
+
+	old := g.ResetLatencies()
+	if old.LocalHit.Count != 3 {
+		t.Fatalf("reset returned %d local hits", old.LocalHit.Count)
+	}
+	if n := g.Stats().Latency.LocalHit.Count; n != 0 {
+		t.Fatalf("after reset count = %d", n)
+	}
+}
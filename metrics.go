@@ -0,0 +1,181 @@
+package blockcache
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc/status"
+)
+
+// Prometheus指标导出
+// 组和缓存的计数器在抓取时直接读取GroupStats和Cache.StoreStats，不重复计数；
+// 延迟分布和节点间RPC结果在发生时记录到直方图/计数器中。
+// 哈希环归属比例和熔断状态来自ClientPicker（可选）。
+
+const metricsNamespace = "blockcache"
+
+// latencyBuckets 0.5ms到约16s的指数桶
+var latencyBuckets = prometheus.ExponentialBuckets(0.0005, 2, 16)
+
+var (
+	// loadDurationSeconds 未命中本地缓存时的加载耗时，source为peer或getter
+	loadDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "load_duration_seconds",
+		Help:      "Latency of loading a key missing from the local cache, by source.",
+		Buckets:   latencyBuckets,
+	}, []string{"group", "source"})
+
+	// peerRPCDurationSeconds 到各节点的RPC耗时
+	peerRPCDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "peer_rpc_duration_seconds",
+		Help:      "Latency of RPCs sent to peers.",
+		Buckets:   latencyBuckets,
+	}, []string{"peer", "method"})
+
+	// peerRPCErrorsTotal 到各节点的RPC错误数，按状态码区分
+	peerRPCErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "peer_rpc_errors_total",
+		Help:      "RPCs sent to peers that returned an error, by status code.",
+	}, []string{"peer", "method", "code"})
+)
+
+// observePeerRPC 记录一次到节点的RPC
+func observePeerRPC(peer, method string, start time.Time, err error) {
+	peerRPCDurationSeconds.WithLabelValues(peer, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		peerRPCErrorsTotal.WithLabelValues(peer, method, status.Code(err).String()).Inc()
+	}
+}
+
+// NewMetricsRegistry 创建包含所有BlockCache指标的注册表，picker为nil时不导出环和节点状态
+func NewMetricsRegistry(picker *ClientPicker) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		newStatsCollector(picker),
+		loadDurationSeconds,
+		peerRPCDurationSeconds,
+		peerRPCErrorsTotal,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return reg
+}
+
+// MetricsHandler 返回以Prometheus文本格式输出指标的HTTP处理器
+func MetricsHandler(picker *ClientPicker) http.Handler {
+	return promhttp.HandlerFor(NewMetricsRegistry(picker), promhttp.HandlerOpts{})
+}
+
+// WithMetrics 在addr上以HTTP提供/metrics，picker用于导出哈希环和节点状态，可为nil
+func WithMetrics(addr string, picker *ClientPicker) ServerOption {
+	return func(o *ServerOptions) {
+		o.MetricsAddr = addr
+		if picker != nil {
+			o.Picker = picker
+		}
+	}
+}
+
+// startMetrics 启动/metrics HTTP服务，监听失败时返回错误
+func (s *Server) startMetrics() error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler(s.opts.Picker))
+	srv, err := serveHTTP(s.opts.MetricsAddr, mux, "metrics", s.certs)
+	if err != nil {
+		return err
+	}
+	s.metricsSrv = srv
+	return nil
+}
+
+// statsCollector 在抓取时读取各组、缓存和哈希环的状态
+type statsCollector struct {
+	picker *ClientPicker
+
+	groupRequests *prometheus.Desc
+	groupLoads    *prometheus.Desc
+	cacheBytes    *prometheus.Desc
+	cacheMaxBytes *prometheus.Desc
+	cacheItems    *prometheus.Desc
+	cacheRemoved  *prometheus.Desc
+	ringOwnership *prometheus.Desc
+	ringReplicas  *prometheus.Desc
+	peerBreaker   *prometheus.Desc
+}
+
+func newStatsCollector(picker *ClientPicker) *statsCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, labels, nil)
+	}
+	return &statsCollector{
+		picker:        picker,
+		groupRequests: desc("group_requests_total", "Group lookups by where they were served from and the outcome.", "group", "source", "result"),
+		groupLoads:    desc("group_loads_total", "Loads triggered by local cache misses.", "group"),
+		cacheBytes:    desc("cache_bytes", "Bytes used by the group's local cache.", "group"),
+		cacheMaxBytes: desc("cache_max_bytes", "Capacity of the group's local cache in bytes.", "group"),
+		cacheItems:    desc("cache_items", "Entries in the group's local cache.", "group"),
+		cacheRemoved:  desc("cache_removed_total", "Entries removed from the local cache, by reason.", "group", "reason"),
+		ringOwnership: desc("ring_ownership_ratio", "Fraction of the hash space owned by each node.", "node"),
+		ringReplicas:  desc("ring_virtual_nodes", "Virtual nodes placed on the ring for each node.", "node"),
+		peerBreaker:   desc("peer_breaker_state", "Circuit breaker state per peer (0=closed, 1=open, 2=half-open).", "peer"),
+	}
+}
+
+// Describe 实现prometheus.Collector
+func (c *statsCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+// Collect 实现prometheus.Collector
+func (c *statsCollector) Collect(ch chan<- prometheus.Metric) {
+	groupsMu.RLock()
+	snapshot := make([]*Group, 0, len(groups))
+	for _, g := range groups {
+		snapshot = append(snapshot, g)
+	}
+	groupsMu.RUnlock()
+
+	counter := func(desc *prometheus.Desc, v int64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+	}
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+
+	for _, g := range snapshot {
+		stats := g.Stats()
+		counter(c.groupRequests, stats.LocalHits, g.name, "local", "hit")
+		counter(c.groupRequests, stats.LocalMisses, g.name, "local", "miss")
+		counter(c.groupRequests, stats.PeerHits, g.name, "peer", "hit")
+		counter(c.groupRequests, stats.PeerMisses, g.name, "peer", "miss")
+		counter(c.groupRequests, stats.LoaderHits, g.name, "getter", "hit")
+		counter(c.groupRequests, stats.LoaderErrors, g.name, "getter", "error")
+		counter(c.groupLoads, stats.Loads, g.name)
+
+		cache := g.mainCache.StoreStats()
+		gauge(c.cacheBytes, float64(cache.Bytes), g.name)
+		gauge(c.cacheMaxBytes, float64(cache.MaxBytes), g.name)
+		gauge(c.cacheItems, float64(cache.Items), g.name)
+		counter(c.cacheRemoved, cache.Evictions, g.name, "evicted")
+		counter(c.cacheRemoved, cache.Expirations, g.name, "expired")
+	}
+
+	if c.picker == nil {
+		return
+	}
+	if snap, err := c.picker.RingSnapshot(); err == nil {
+		for _, n := range snap.Nodes {
+			gauge(c.ringOwnership, n.Ownership, n.Node)
+			gauge(c.ringReplicas, float64(n.Replicas), n.Node)
+		}
+	}
+	for peer, state := range c.picker.BreakerStates() {
+		gauge(c.peerBreaker, float64(state), peer)
+	}
+}
@@ -0,0 +1,88 @@
+package blockcache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(MetricsHandler(nil))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// TestMetrics_GroupAndCache 组统计、缓存用量和加载耗时都能被抓取
+func TestMetrics_GroupAndCache(t *testing.T) {
+	g := NewGroup("metrics-group", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		if key == "missing" {
+			return nil, ErrNotFound
+		}
+		return []byte("value"), nil
+	}))
+	defer g.Close()
+
+	ctx := context.Background()
+	g.Get(ctx, "k")
+	g.Get(ctx, "k")
+	g.Get(ctx, "missing")
+
+	body := scrapeMetrics(t)
+	for _, want := range []string{
+		`blockcache_group_requests_total{group="metrics-group",result="hit",source="local"} 1`,
+		`blockcache_group_requests_total{group="metrics-group",result="hit",source="getter"} 1`,
+		`blockcache_cache_items{group="metrics-group"} 1`,
+		`blockcache_cache_max_bytes{group="metrics-group"} 1.048576e+06`,
+		`blockcache_load_duration_seconds_count{group="metrics-group",source="getter"} 2`,
+		`go_goroutines`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
+
+// TestMetrics_PeerRPC 客户端记录到各节点的RPC耗时和错误码
+func TestMetrics_PeerRPC(t *testing.T) {
+	g := NewGroup("metrics-rpc", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("v"), nil
+	}))
+	defer g.Close()
+
+	addr := startTestServer(t)
+	client, err := NewClient(addr, "test", nil, WithClientDialTimeout(time.Second), WithRetryPolicy(RetryPolicy{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	if _, err := client.Get(ctx, "metrics-rpc", "k"); err != nil {
+		t.Fatal(err)
+	}
+	client.Get(ctx, "no-such-group", "k")
+
+	body := scrapeMetrics(t)
+	for _, want := range []string{
+		fmt.Sprintf(`blockcache_peer_rpc_duration_seconds_count{method="get",peer=%q} 2`, addr),
+		fmt.Sprintf(`blockcache_peer_rpc_errors_total{code="NotFound",method="get",peer=%q} 1`, addr),
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q", want)
+		}
+	}
+}
@@ -24,9 +24,10 @@ const (
 // Request 请求消息
 type Request struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 缓存组名
-	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`     // 缓存键
-	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"` // 缓存值（Set时使用）
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`               // 缓存组名
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`                   // 缓存键
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`               // 缓存值（Set时使用）
+	TtlMs         int64                  `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"` // 存活时间（毫秒，Set时使用），0表示使用组的默认过期时间
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -82,6 +83,13 @@ func (x *Request) GetValue() []byte {
 	return nil
 }
 
+func (x *Request) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
 // ResponseForGet Get/Set操作的响应
 type ResponseForGet struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -172,24 +180,1157 @@ func (x *ResponseForDelete) GetValue() bool {
 	return false
 }
 
-var File_pb_blockcache_proto protoreflect.FileDescriptor
+// HandoffEntry 迁移的单个缓存项
+type HandoffEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`               // 缓存组名
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`                   // 缓存键
+	Value         []byte                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`               // 缓存值
+	TtlMs         int64                  `protobuf:"varint,4,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"` // 剩余存活时间（毫秒），0表示不过期
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-const file_pb_blockcache_proto_rawDesc = "" +
-	"\n" +
-	"\x13pb/blockcache.proto\x12\x02pb\"G\n" +
-	"\aRequest\x12\x14\n" +
-	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
-	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x03 \x01(\fR\x05value\"&\n" +
-	"\x0eResponseForGet\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\fR\x05value\")\n" +
-	"\x11ResponseForDelete\x12\x14\n" +
-	"\x05value\x18\x01 \x01(\bR\x05value2\x8a\x01\n" +
-	"\n" +
-	"BlockCache\x12&\n" +
-	"\x03Get\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12&\n" +
-	"\x03Set\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12,\n" +
-	"\x06Delete\x12\v.pb.Request\x1a\x15.pb.ResponseForDeleteB'Z%github.com/crypt0walker/BlockCache/pbb\x06proto3"
+func (x *HandoffEntry) Reset() {
+	*x = HandoffEntry{}
+	mi := &file_pb_blockcache_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandoffEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffEntry) ProtoMessage() {}
+
+func (x *HandoffEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffEntry.ProtoReflect.Descriptor instead.
+func (*HandoffEntry) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *HandoffEntry) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *HandoffEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *HandoffEntry) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+func (x *HandoffEntry) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+// HandoffResponse 迁移结果
+type HandoffResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      int64                  `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"` // 接收方实际写入的条数
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HandoffResponse) Reset() {
+	*x = HandoffResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HandoffResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HandoffResponse) ProtoMessage() {}
+
+func (x *HandoffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HandoffResponse.ProtoReflect.Descriptor instead.
+func (*HandoffResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HandoffResponse) GetAccepted() int64 {
+	if x != nil {
+		return x.Accepted
+	}
+	return 0
+}
+
+type ListGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsRequest) Reset() {
+	*x = ListGroupsRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsRequest) ProtoMessage() {}
+
+func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{5}
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []string               `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListGroupsResponse) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type StatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StatsRequest) Reset() {
+	*x = StatsRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsRequest) ProtoMessage() {}
+
+func (x *StatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsRequest.ProtoReflect.Descriptor instead.
+func (*StatsRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *StatsRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// LatencySummary 延迟分布摘要（纳秒）
+type LatencySummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int64                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	MeanNs        int64                  `protobuf:"varint,2,opt,name=mean_ns,json=meanNs,proto3" json:"mean_ns,omitempty"`
+	P50Ns         int64                  `protobuf:"varint,3,opt,name=p50_ns,json=p50Ns,proto3" json:"p50_ns,omitempty"`
+	P99Ns         int64                  `protobuf:"varint,4,opt,name=p99_ns,json=p99Ns,proto3" json:"p99_ns,omitempty"`
+	MaxNs         int64                  `protobuf:"varint,5,opt,name=max_ns,json=maxNs,proto3" json:"max_ns,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LatencySummary) Reset() {
+	*x = LatencySummary{}
+	mi := &file_pb_blockcache_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LatencySummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LatencySummary) ProtoMessage() {}
+
+func (x *LatencySummary) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LatencySummary.ProtoReflect.Descriptor instead.
+func (*LatencySummary) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LatencySummary) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *LatencySummary) GetMeanNs() int64 {
+	if x != nil {
+		return x.MeanNs
+	}
+	return 0
+}
+
+func (x *LatencySummary) GetP50Ns() int64 {
+	if x != nil {
+		return x.P50Ns
+	}
+	return 0
+}
+
+func (x *LatencySummary) GetP99Ns() int64 {
+	if x != nil {
+		return x.P99Ns
+	}
+	return 0
+}
+
+func (x *LatencySummary) GetMaxNs() int64 {
+	if x != nil {
+		return x.MaxNs
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Group        string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Loads        int64                  `protobuf:"varint,2,opt,name=loads,proto3" json:"loads,omitempty"`
+	LocalHits    int64                  `protobuf:"varint,3,opt,name=local_hits,json=localHits,proto3" json:"local_hits,omitempty"`
+	LocalMisses  int64                  `protobuf:"varint,4,opt,name=local_misses,json=localMisses,proto3" json:"local_misses,omitempty"`
+	PeerHits     int64                  `protobuf:"varint,5,opt,name=peer_hits,json=peerHits,proto3" json:"peer_hits,omitempty"`
+	PeerMisses   int64                  `protobuf:"varint,6,opt,name=peer_misses,json=peerMisses,proto3" json:"peer_misses,omitempty"`
+	LoaderHits   int64                  `protobuf:"varint,7,opt,name=loader_hits,json=loaderHits,proto3" json:"loader_hits,omitempty"`
+	LoaderErrors int64                  `protobuf:"varint,8,opt,name=loader_errors,json=loaderErrors,proto3" json:"loader_errors,omitempty"`
+	PeerRetries  int64                  `protobuf:"varint,9,opt,name=peer_retries,json=peerRetries,proto3" json:"peer_retries,omitempty"`
+	Hedges       int64                  `protobuf:"varint,10,opt,name=hedges,proto3" json:"hedges,omitempty"`
+	HedgeWins    int64                  `protobuf:"varint,11,opt,name=hedge_wins,json=hedgeWins,proto3" json:"hedge_wins,omitempty"`
+	// 本地缓存用量
+	Items           int64           `protobuf:"varint,12,opt,name=items,proto3" json:"items,omitempty"`
+	Bytes           int64           `protobuf:"varint,13,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	MaxBytes        int64           `protobuf:"varint,14,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	Evictions       int64           `protobuf:"varint,15,opt,name=evictions,proto3" json:"evictions,omitempty"`
+	Expirations     int64           `protobuf:"varint,16,opt,name=expirations,proto3" json:"expirations,omitempty"`
+	LocalHitLatency *LatencySummary `protobuf:"bytes,17,opt,name=local_hit_latency,json=localHitLatency,proto3" json:"local_hit_latency,omitempty"`
+	PeerLatency     *LatencySummary `protobuf:"bytes,18,opt,name=peer_latency,json=peerLatency,proto3" json:"peer_latency,omitempty"`
+	LoaderLatency   *LatencySummary `protobuf:"bytes,19,opt,name=loader_latency,json=loaderLatency,proto3" json:"loader_latency,omitempty"`
+	CacheType       string          `protobuf:"bytes,20,opt,name=cache_type,json=cacheType,proto3" json:"cache_type,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatsResponse) ProtoMessage() {}
+
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StatsResponse) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *StatsResponse) GetLoads() int64 {
+	if x != nil {
+		return x.Loads
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetLocalHits() int64 {
+	if x != nil {
+		return x.LocalHits
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetLocalMisses() int64 {
+	if x != nil {
+		return x.LocalMisses
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetPeerHits() int64 {
+	if x != nil {
+		return x.PeerHits
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetPeerMisses() int64 {
+	if x != nil {
+		return x.PeerMisses
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetLoaderHits() int64 {
+	if x != nil {
+		return x.LoaderHits
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetLoaderErrors() int64 {
+	if x != nil {
+		return x.LoaderErrors
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetPeerRetries() int64 {
+	if x != nil {
+		return x.PeerRetries
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetHedges() int64 {
+	if x != nil {
+		return x.Hedges
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetHedgeWins() int64 {
+	if x != nil {
+		return x.HedgeWins
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetItems() int64 {
+	if x != nil {
+		return x.Items
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetEvictions() int64 {
+	if x != nil {
+		return x.Evictions
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetExpirations() int64 {
+	if x != nil {
+		return x.Expirations
+	}
+	return 0
+}
+
+func (x *StatsResponse) GetLocalHitLatency() *LatencySummary {
+	if x != nil {
+		return x.LocalHitLatency
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetPeerLatency() *LatencySummary {
+	if x != nil {
+		return x.PeerLatency
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetLoaderLatency() *LatencySummary {
+	if x != nil {
+		return x.LoaderLatency
+	}
+	return nil
+}
+
+func (x *StatsResponse) GetCacheType() string {
+	if x != nil {
+		return x.CacheType
+	}
+	return ""
+}
+
+type PeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeersRequest) Reset() {
+	*x = PeersRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeersRequest) ProtoMessage() {}
+
+func (x *PeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeersRequest.ProtoReflect.Descriptor instead.
+func (*PeersRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{10}
+}
+
+type PeerInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Addr          string                 `protobuf:"bytes,1,opt,name=addr,proto3" json:"addr,omitempty"`
+	Breaker       string                 `protobuf:"bytes,2,opt,name=breaker,proto3" json:"breaker,omitempty"` // closed/open/half-open
+	Weight        int32                  `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	Zone          string                 `protobuf:"bytes,4,opt,name=zone,proto3" json:"zone,omitempty"`
+	Version       string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerInfo) Reset() {
+	*x = PeerInfo{}
+	mi := &file_pb_blockcache_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerInfo) ProtoMessage() {}
+
+func (x *PeerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerInfo.ProtoReflect.Descriptor instead.
+func (*PeerInfo) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PeerInfo) GetAddr() string {
+	if x != nil {
+		return x.Addr
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetBreaker() string {
+	if x != nil {
+		return x.Breaker
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *PeerInfo) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+func (x *PeerInfo) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type PeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Self          string                 `protobuf:"bytes,1,opt,name=self,proto3" json:"self,omitempty"`
+	Peers         []*PeerInfo            `protobuf:"bytes,2,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeersResponse) Reset() {
+	*x = PeersResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeersResponse) ProtoMessage() {}
+
+func (x *PeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeersResponse.ProtoReflect.Descriptor instead.
+func (*PeersResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *PeersResponse) GetSelf() string {
+	if x != nil {
+		return x.Self
+	}
+	return ""
+}
+
+func (x *PeersResponse) GetPeers() []*PeerInfo {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type DumpRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DumpRequest) Reset() {
+	*x = DumpRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DumpRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DumpRequest) ProtoMessage() {}
+
+func (x *DumpRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DumpRequest.ProtoReflect.Descriptor instead.
+func (*DumpRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DumpRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// LoadRequest 回源请求
+type LoadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"` // 发起回源的缓存组
+	Key           string                 `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`     // 缓存键
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadRequest) Reset() {
+	*x = LoadRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadRequest) ProtoMessage() {}
+
+func (x *LoadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadRequest.ProtoReflect.Descriptor instead.
+func (*LoadRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LoadRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *LoadRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+// LoadResponse 回源结果
+type LoadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Value         []byte                 `protobuf:"bytes,1,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LoadResponse) Reset() {
+	*x = LoadResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LoadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LoadResponse) ProtoMessage() {}
+
+func (x *LoadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LoadResponse.ProtoReflect.Descriptor instead.
+func (*LoadResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LoadResponse) GetValue() []byte {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+// GroupDefinition 集群范围的缓存组定义
+type GroupDefinition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`   // 每个节点上的容量上限
+	TtlMs         int64                  `protobuf:"varint,3,opt,name=ttl_ms,json=ttlMs,proto3" json:"ttl_ms,omitempty"`            // 默认过期时间（毫秒），0表示不过期
+	CacheType     string                 `protobuf:"bytes,4,opt,name=cache_type,json=cacheType,proto3" json:"cache_type,omitempty"` // lru或lru2，空表示默认
+	Getter        []byte                 `protobuf:"bytes,5,opt,name=getter,proto3" json:"getter,omitempty"`                        // 数据源配置（JSON），由节点解释，空表示只保存Set写入的数据
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupDefinition) Reset() {
+	*x = GroupDefinition{}
+	mi := &file_pb_blockcache_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupDefinition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupDefinition) ProtoMessage() {}
+
+func (x *GroupDefinition) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupDefinition.ProtoReflect.Descriptor instead.
+func (*GroupDefinition) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *GroupDefinition) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *GroupDefinition) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *GroupDefinition) GetTtlMs() int64 {
+	if x != nil {
+		return x.TtlMs
+	}
+	return 0
+}
+
+func (x *GroupDefinition) GetCacheType() string {
+	if x != nil {
+		return x.CacheType
+	}
+	return ""
+}
+
+func (x *GroupDefinition) GetGetter() []byte {
+	if x != nil {
+		return x.Getter
+	}
+	return nil
+}
+
+type ResizeGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	MaxBytes      int64                  `protobuf:"varint,2,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`   // 0表示不修改
+	CacheType     string                 `protobuf:"bytes,3,opt,name=cache_type,json=cacheType,proto3" json:"cache_type,omitempty"` // 空表示不修改，否则迁移到新的存储类型
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResizeGroupRequest) Reset() {
+	*x = ResizeGroupRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResizeGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResizeGroupRequest) ProtoMessage() {}
+
+func (x *ResizeGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResizeGroupRequest.ProtoReflect.Descriptor instead.
+func (*ResizeGroupRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *ResizeGroupRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *ResizeGroupRequest) GetMaxBytes() int64 {
+	if x != nil {
+		return x.MaxBytes
+	}
+	return 0
+}
+
+func (x *ResizeGroupRequest) GetCacheType() string {
+	if x != nil {
+		return x.CacheType
+	}
+	return ""
+}
+
+type DestroyGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         string                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DestroyGroupRequest) Reset() {
+	*x = DestroyGroupRequest{}
+	mi := &file_pb_blockcache_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DestroyGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DestroyGroupRequest) ProtoMessage() {}
+
+func (x *DestroyGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DestroyGroupRequest.ProtoReflect.Descriptor instead.
+func (*DestroyGroupRequest) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DestroyGroupRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type DestroyGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Existed       bool                   `protobuf:"varint,1,opt,name=existed,proto3" json:"existed,omitempty"` // 删除前定义是否存在
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DestroyGroupResponse) Reset() {
+	*x = DestroyGroupResponse{}
+	mi := &file_pb_blockcache_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DestroyGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DestroyGroupResponse) ProtoMessage() {}
+
+func (x *DestroyGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pb_blockcache_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DestroyGroupResponse.ProtoReflect.Descriptor instead.
+func (*DestroyGroupResponse) Descriptor() ([]byte, []int) {
+	return file_pb_blockcache_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *DestroyGroupResponse) GetExisted() bool {
+	if x != nil {
+		return x.Existed
+	}
+	return false
+}
+
+var File_pb_blockcache_proto protoreflect.FileDescriptor
+
+const file_pb_blockcache_proto_rawDesc = "" +
+	"\n" +
+	"\x13pb/blockcache.proto\x12\x02pb\"^\n" +
+	"\aRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x15\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03R\x05ttlMs\"&\n" +
+	"\x0eResponseForGet\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\")\n" +
+	"\x11ResponseForDelete\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\bR\x05value\"c\n" +
+	"\fHandoffEntry\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\fR\x05value\x12\x15\n" +
+	"\x06ttl_ms\x18\x04 \x01(\x03R\x05ttlMs\"-\n" +
+	"\x0fHandoffResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\x03R\baccepted\"\x13\n" +
+	"\x11ListGroupsRequest\",\n" +
+	"\x12ListGroupsResponse\x12\x16\n" +
+	"\x06groups\x18\x01 \x03(\tR\x06groups\"$\n" +
+	"\fStatsRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\"\x84\x01\n" +
+	"\x0eLatencySummary\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x03R\x05count\x12\x17\n" +
+	"\amean_ns\x18\x02 \x01(\x03R\x06meanNs\x12\x15\n" +
+	"\x06p50_ns\x18\x03 \x01(\x03R\x05p50Ns\x12\x15\n" +
+	"\x06p99_ns\x18\x04 \x01(\x03R\x05p99Ns\x12\x15\n" +
+	"\x06max_ns\x18\x05 \x01(\x03R\x05maxNs\"\xb5\x05\n" +
+	"\rStatsResponse\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x14\n" +
+	"\x05loads\x18\x02 \x01(\x03R\x05loads\x12\x1d\n" +
+	"\n" +
+	"local_hits\x18\x03 \x01(\x03R\tlocalHits\x12!\n" +
+	"\flocal_misses\x18\x04 \x01(\x03R\vlocalMisses\x12\x1b\n" +
+	"\tpeer_hits\x18\x05 \x01(\x03R\bpeerHits\x12\x1f\n" +
+	"\vpeer_misses\x18\x06 \x01(\x03R\n" +
+	"peerMisses\x12\x1f\n" +
+	"\vloader_hits\x18\a \x01(\x03R\n" +
+	"loaderHits\x12#\n" +
+	"\rloader_errors\x18\b \x01(\x03R\floaderErrors\x12!\n" +
+	"\fpeer_retries\x18\t \x01(\x03R\vpeerRetries\x12\x16\n" +
+	"\x06hedges\x18\n" +
+	" \x01(\x03R\x06hedges\x12\x1d\n" +
+	"\n" +
+	"hedge_wins\x18\v \x01(\x03R\thedgeWins\x12\x14\n" +
+	"\x05items\x18\f \x01(\x03R\x05items\x12\x14\n" +
+	"\x05bytes\x18\r \x01(\x03R\x05bytes\x12\x1b\n" +
+	"\tmax_bytes\x18\x0e \x01(\x03R\bmaxBytes\x12\x1c\n" +
+	"\tevictions\x18\x0f \x01(\x03R\tevictions\x12 \n" +
+	"\vexpirations\x18\x10 \x01(\x03R\vexpirations\x12>\n" +
+	"\x11local_hit_latency\x18\x11 \x01(\v2\x12.pb.LatencySummaryR\x0flocalHitLatency\x125\n" +
+	"\fpeer_latency\x18\x12 \x01(\v2\x12.pb.LatencySummaryR\vpeerLatency\x129\n" +
+	"\x0eloader_latency\x18\x13 \x01(\v2\x12.pb.LatencySummaryR\rloaderLatency\x12\x1d\n" +
+	"\n" +
+	"cache_type\x18\x14 \x01(\tR\tcacheType\"\x0e\n" +
+	"\fPeersRequest\"~\n" +
+	"\bPeerInfo\x12\x12\n" +
+	"\x04addr\x18\x01 \x01(\tR\x04addr\x12\x18\n" +
+	"\abreaker\x18\x02 \x01(\tR\abreaker\x12\x16\n" +
+	"\x06weight\x18\x03 \x01(\x05R\x06weight\x12\x12\n" +
+	"\x04zone\x18\x04 \x01(\tR\x04zone\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\"G\n" +
+	"\rPeersResponse\x12\x12\n" +
+	"\x04self\x18\x01 \x01(\tR\x04self\x12\"\n" +
+	"\x05peers\x18\x02 \x03(\v2\f.pb.PeerInfoR\x05peers\"#\n" +
+	"\vDumpRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\"5\n" +
+	"\vLoadRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\"$\n" +
+	"\fLoadResponse\x12\x14\n" +
+	"\x05value\x18\x01 \x01(\fR\x05value\"\x92\x01\n" +
+	"\x0fGroupDefinition\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12\x15\n" +
+	"\x06ttl_ms\x18\x03 \x01(\x03R\x05ttlMs\x12\x1d\n" +
+	"\n" +
+	"cache_type\x18\x04 \x01(\tR\tcacheType\x12\x16\n" +
+	"\x06getter\x18\x05 \x01(\fR\x06getter\"f\n" +
+	"\x12ResizeGroupRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\x12\x1b\n" +
+	"\tmax_bytes\x18\x02 \x01(\x03R\bmaxBytes\x12\x1d\n" +
+	"\n" +
+	"cache_type\x18\x03 \x01(\tR\tcacheType\"+\n" +
+	"\x13DestroyGroupRequest\x12\x14\n" +
+	"\x05group\x18\x01 \x01(\tR\x05group\"0\n" +
+	"\x14DestroyGroupResponse\x12\x18\n" +
+	"\aexisted\x18\x01 \x01(\bR\aexisted2\xbc\x04\n" +
+	"\n" +
+	"BlockCache\x12&\n" +
+	"\x03Get\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12&\n" +
+	"\x03Set\x12\v.pb.Request\x1a\x12.pb.ResponseForGet\x12,\n" +
+	"\x06Delete\x12\v.pb.Request\x1a\x15.pb.ResponseForDelete\x122\n" +
+	"\aHandoff\x12\x10.pb.HandoffEntry\x1a\x13.pb.HandoffResponse(\x01\x12;\n" +
+	"\n" +
+	"ListGroups\x12\x15.pb.ListGroupsRequest\x1a\x16.pb.ListGroupsResponse\x12,\n" +
+	"\x05Stats\x12\x10.pb.StatsRequest\x1a\x11.pb.StatsResponse\x12,\n" +
+	"\x05Peers\x12\x10.pb.PeersRequest\x1a\x11.pb.PeersResponse\x12+\n" +
+	"\x04Dump\x12\x0f.pb.DumpRequest\x1a\x10.pb.HandoffEntry0\x01\x127\n" +
+	"\vCreateGroup\x12\x13.pb.GroupDefinition\x1a\x13.pb.GroupDefinition\x12:\n" +
+	"\vResizeGroup\x12\x16.pb.ResizeGroupRequest\x1a\x13.pb.GroupDefinition\x12A\n" +
+	"\fDestroyGroup\x12\x17.pb.DestroyGroupRequest\x1a\x18.pb.DestroyGroupResponse23\n" +
+	"\x06Origin\x12)\n" +
+	"\x04Load\x12\x0f.pb.LoadRequest\x1a\x10.pb.LoadResponseB'Z%github.com/crypt0walker/BlockCache/pbb\x06proto3"
 
 var (
 	file_pb_blockcache_proto_rawDescOnce sync.Once
@@ -203,24 +1344,63 @@ func file_pb_blockcache_proto_rawDescGZIP() []byte {
 	return file_pb_blockcache_proto_rawDescData
 }
 
-var file_pb_blockcache_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_pb_blockcache_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
 var file_pb_blockcache_proto_goTypes = []any{
-	(*Request)(nil),           // 0: pb.Request
-	(*ResponseForGet)(nil),    // 1: pb.ResponseForGet
-	(*ResponseForDelete)(nil), // 2: pb.ResponseForDelete
+	(*Request)(nil),              // 0: pb.Request
+	(*ResponseForGet)(nil),       // 1: pb.ResponseForGet
+	(*ResponseForDelete)(nil),    // 2: pb.ResponseForDelete
+	(*HandoffEntry)(nil),         // 3: pb.HandoffEntry
+	(*HandoffResponse)(nil),      // 4: pb.HandoffResponse
+	(*ListGroupsRequest)(nil),    // 5: pb.ListGroupsRequest
+	(*ListGroupsResponse)(nil),   // 6: pb.ListGroupsResponse
+	(*StatsRequest)(nil),         // 7: pb.StatsRequest
+	(*LatencySummary)(nil),       // 8: pb.LatencySummary
+	(*StatsResponse)(nil),        // 9: pb.StatsResponse
+	(*PeersRequest)(nil),         // 10: pb.PeersRequest
+	(*PeerInfo)(nil),             // 11: pb.PeerInfo
+	(*PeersResponse)(nil),        // 12: pb.PeersResponse
+	(*DumpRequest)(nil),          // 13: pb.DumpRequest
+	(*LoadRequest)(nil),          // 14: pb.LoadRequest
+	(*LoadResponse)(nil),         // 15: pb.LoadResponse
+	(*GroupDefinition)(nil),      // 16: pb.GroupDefinition
+	(*ResizeGroupRequest)(nil),   // 17: pb.ResizeGroupRequest
+	(*DestroyGroupRequest)(nil),  // 18: pb.DestroyGroupRequest
+	(*DestroyGroupResponse)(nil), // 19: pb.DestroyGroupResponse
 }
 var file_pb_blockcache_proto_depIdxs = []int32{
-	0, // 0: pb.BlockCache.Get:input_type -> pb.Request
-	0, // 1: pb.BlockCache.Set:input_type -> pb.Request
-	0, // 2: pb.BlockCache.Delete:input_type -> pb.Request
-	1, // 3: pb.BlockCache.Get:output_type -> pb.ResponseForGet
-	1, // 4: pb.BlockCache.Set:output_type -> pb.ResponseForGet
-	2, // 5: pb.BlockCache.Delete:output_type -> pb.ResponseForDelete
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	8,  // 0: pb.StatsResponse.local_hit_latency:type_name -> pb.LatencySummary
+	8,  // 1: pb.StatsResponse.peer_latency:type_name -> pb.LatencySummary
+	8,  // 2: pb.StatsResponse.loader_latency:type_name -> pb.LatencySummary
+	11, // 3: pb.PeersResponse.peers:type_name -> pb.PeerInfo
+	0,  // 4: pb.BlockCache.Get:input_type -> pb.Request
+	0,  // 5: pb.BlockCache.Set:input_type -> pb.Request
+	0,  // 6: pb.BlockCache.Delete:input_type -> pb.Request
+	3,  // 7: pb.BlockCache.Handoff:input_type -> pb.HandoffEntry
+	5,  // 8: pb.BlockCache.ListGroups:input_type -> pb.ListGroupsRequest
+	7,  // 9: pb.BlockCache.Stats:input_type -> pb.StatsRequest
+	10, // 10: pb.BlockCache.Peers:input_type -> pb.PeersRequest
+	13, // 11: pb.BlockCache.Dump:input_type -> pb.DumpRequest
+	16, // 12: pb.BlockCache.CreateGroup:input_type -> pb.GroupDefinition
+	17, // 13: pb.BlockCache.ResizeGroup:input_type -> pb.ResizeGroupRequest
+	18, // 14: pb.BlockCache.DestroyGroup:input_type -> pb.DestroyGroupRequest
+	14, // 15: pb.Origin.Load:input_type -> pb.LoadRequest
+	1,  // 16: pb.BlockCache.Get:output_type -> pb.ResponseForGet
+	1,  // 17: pb.BlockCache.Set:output_type -> pb.ResponseForGet
+	2,  // 18: pb.BlockCache.Delete:output_type -> pb.ResponseForDelete
+	4,  // 19: pb.BlockCache.Handoff:output_type -> pb.HandoffResponse
+	6,  // 20: pb.BlockCache.ListGroups:output_type -> pb.ListGroupsResponse
+	9,  // 21: pb.BlockCache.Stats:output_type -> pb.StatsResponse
+	12, // 22: pb.BlockCache.Peers:output_type -> pb.PeersResponse
+	3,  // 23: pb.BlockCache.Dump:output_type -> pb.HandoffEntry
+	16, // 24: pb.BlockCache.CreateGroup:output_type -> pb.GroupDefinition
+	16, // 25: pb.BlockCache.ResizeGroup:output_type -> pb.GroupDefinition
+	19, // 26: pb.BlockCache.DestroyGroup:output_type -> pb.DestroyGroupResponse
+	15, // 27: pb.Origin.Load:output_type -> pb.LoadResponse
+	16, // [16:28] is the sub-list for method output_type
+	4,  // [4:16] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_pb_blockcache_proto_init() }
@@ -234,9 +1414,9 @@ func file_pb_blockcache_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pb_blockcache_proto_rawDesc), len(file_pb_blockcache_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   20,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_pb_blockcache_proto_goTypes,
 		DependencyIndexes: file_pb_blockcache_proto_depIdxs,
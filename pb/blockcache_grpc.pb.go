@@ -19,9 +19,17 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BlockCache_Get_FullMethodName    = "/pb.BlockCache/Get"
-	BlockCache_Set_FullMethodName    = "/pb.BlockCache/Set"
-	BlockCache_Delete_FullMethodName = "/pb.BlockCache/Delete"
+	BlockCache_Get_FullMethodName          = "/pb.BlockCache/Get"
+	BlockCache_Set_FullMethodName          = "/pb.BlockCache/Set"
+	BlockCache_Delete_FullMethodName       = "/pb.BlockCache/Delete"
+	BlockCache_Handoff_FullMethodName      = "/pb.BlockCache/Handoff"
+	BlockCache_ListGroups_FullMethodName   = "/pb.BlockCache/ListGroups"
+	BlockCache_Stats_FullMethodName        = "/pb.BlockCache/Stats"
+	BlockCache_Peers_FullMethodName        = "/pb.BlockCache/Peers"
+	BlockCache_Dump_FullMethodName         = "/pb.BlockCache/Dump"
+	BlockCache_CreateGroup_FullMethodName  = "/pb.BlockCache/CreateGroup"
+	BlockCache_ResizeGroup_FullMethodName  = "/pb.BlockCache/ResizeGroup"
+	BlockCache_DestroyGroup_FullMethodName = "/pb.BlockCache/DestroyGroup"
 )
 
 // BlockCacheClient is the client API for BlockCache service.
@@ -36,6 +44,22 @@ type BlockCacheClient interface {
 	Set(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForGet, error)
 	// Delete 删除缓存值
 	Delete(ctx context.Context, in *Request, opts ...grpc.CallOption) (*ResponseForDelete, error)
+	// Handoff 哈希环变化时，旧节点把不再归自己负责的key流式迁移给新节点
+	Handoff(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[HandoffEntry, HandoffResponse], error)
+	// ListGroups 列出节点上的所有缓存组
+	ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error)
+	// Stats 返回缓存组的统计信息
+	Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error)
+	// Peers 返回节点已发现的其他节点及其熔断状态
+	Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersResponse, error)
+	// Dump 流式导出缓存组中所有未过期的项，可以用Handoff导入
+	Dump(ctx context.Context, in *DumpRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HandoffEntry], error)
+	// CreateGroup 写入集群范围的组定义，所有节点随后在本地创建该组
+	CreateGroup(ctx context.Context, in *GroupDefinition, opts ...grpc.CallOption) (*GroupDefinition, error)
+	// ResizeGroup 在线调整组的容量上限或存储类型；开启组定义注册表时修改集群范围的定义，否则只调整本节点
+	ResizeGroup(ctx context.Context, in *ResizeGroupRequest, opts ...grpc.CallOption) (*GroupDefinition, error)
+	// DestroyGroup 删除组定义，所有节点销毁该组
+	DestroyGroup(ctx context.Context, in *DestroyGroupRequest, opts ...grpc.CallOption) (*DestroyGroupResponse, error)
 }
 
 type blockCacheClient struct {
@@ -76,6 +100,98 @@ func (c *blockCacheClient) Delete(ctx context.Context, in *Request, opts ...grpc
 	return out, nil
 }
 
+func (c *blockCacheClient) Handoff(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[HandoffEntry, HandoffResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BlockCache_ServiceDesc.Streams[0], BlockCache_Handoff_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[HandoffEntry, HandoffResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockCache_HandoffClient = grpc.ClientStreamingClient[HandoffEntry, HandoffResponse]
+
+func (c *blockCacheClient) ListGroups(ctx context.Context, in *ListGroupsRequest, opts ...grpc.CallOption) (*ListGroupsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGroupsResponse)
+	err := c.cc.Invoke(ctx, BlockCache_ListGroups_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockCacheClient) Stats(ctx context.Context, in *StatsRequest, opts ...grpc.CallOption) (*StatsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StatsResponse)
+	err := c.cc.Invoke(ctx, BlockCache_Stats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockCacheClient) Peers(ctx context.Context, in *PeersRequest, opts ...grpc.CallOption) (*PeersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PeersResponse)
+	err := c.cc.Invoke(ctx, BlockCache_Peers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockCacheClient) Dump(ctx context.Context, in *DumpRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HandoffEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BlockCache_ServiceDesc.Streams[1], BlockCache_Dump_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[DumpRequest, HandoffEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockCache_DumpClient = grpc.ServerStreamingClient[HandoffEntry]
+
+func (c *blockCacheClient) CreateGroup(ctx context.Context, in *GroupDefinition, opts ...grpc.CallOption) (*GroupDefinition, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GroupDefinition)
+	err := c.cc.Invoke(ctx, BlockCache_CreateGroup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockCacheClient) ResizeGroup(ctx context.Context, in *ResizeGroupRequest, opts ...grpc.CallOption) (*GroupDefinition, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GroupDefinition)
+	err := c.cc.Invoke(ctx, BlockCache_ResizeGroup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *blockCacheClient) DestroyGroup(ctx context.Context, in *DestroyGroupRequest, opts ...grpc.CallOption) (*DestroyGroupResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DestroyGroupResponse)
+	err := c.cc.Invoke(ctx, BlockCache_DestroyGroup_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BlockCacheServer is the server API for BlockCache service.
 // All implementations must embed UnimplementedBlockCacheServer
 // for forward compatibility.
@@ -88,6 +204,22 @@ type BlockCacheServer interface {
 	Set(context.Context, *Request) (*ResponseForGet, error)
 	// Delete 删除缓存值
 	Delete(context.Context, *Request) (*ResponseForDelete, error)
+	// Handoff 哈希环变化时，旧节点把不再归自己负责的key流式迁移给新节点
+	Handoff(grpc.ClientStreamingServer[HandoffEntry, HandoffResponse]) error
+	// ListGroups 列出节点上的所有缓存组
+	ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error)
+	// Stats 返回缓存组的统计信息
+	Stats(context.Context, *StatsRequest) (*StatsResponse, error)
+	// Peers 返回节点已发现的其他节点及其熔断状态
+	Peers(context.Context, *PeersRequest) (*PeersResponse, error)
+	// Dump 流式导出缓存组中所有未过期的项，可以用Handoff导入
+	Dump(*DumpRequest, grpc.ServerStreamingServer[HandoffEntry]) error
+	// CreateGroup 写入集群范围的组定义，所有节点随后在本地创建该组
+	CreateGroup(context.Context, *GroupDefinition) (*GroupDefinition, error)
+	// ResizeGroup 在线调整组的容量上限或存储类型；开启组定义注册表时修改集群范围的定义，否则只调整本节点
+	ResizeGroup(context.Context, *ResizeGroupRequest) (*GroupDefinition, error)
+	// DestroyGroup 删除组定义，所有节点销毁该组
+	DestroyGroup(context.Context, *DestroyGroupRequest) (*DestroyGroupResponse, error)
 	mustEmbedUnimplementedBlockCacheServer()
 }
 
@@ -107,6 +239,30 @@ func (UnimplementedBlockCacheServer) Set(context.Context, *Request) (*ResponseFo
 func (UnimplementedBlockCacheServer) Delete(context.Context, *Request) (*ResponseForDelete, error) {
 	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
 }
+func (UnimplementedBlockCacheServer) Handoff(grpc.ClientStreamingServer[HandoffEntry, HandoffResponse]) error {
+	return status.Error(codes.Unimplemented, "method Handoff not implemented")
+}
+func (UnimplementedBlockCacheServer) ListGroups(context.Context, *ListGroupsRequest) (*ListGroupsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListGroups not implemented")
+}
+func (UnimplementedBlockCacheServer) Stats(context.Context, *StatsRequest) (*StatsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Stats not implemented")
+}
+func (UnimplementedBlockCacheServer) Peers(context.Context, *PeersRequest) (*PeersResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Peers not implemented")
+}
+func (UnimplementedBlockCacheServer) Dump(*DumpRequest, grpc.ServerStreamingServer[HandoffEntry]) error {
+	return status.Error(codes.Unimplemented, "method Dump not implemented")
+}
+func (UnimplementedBlockCacheServer) CreateGroup(context.Context, *GroupDefinition) (*GroupDefinition, error) {
+	return nil, status.Error(codes.Unimplemented, "method CreateGroup not implemented")
+}
+func (UnimplementedBlockCacheServer) ResizeGroup(context.Context, *ResizeGroupRequest) (*GroupDefinition, error) {
+	return nil, status.Error(codes.Unimplemented, "method ResizeGroup not implemented")
+}
+func (UnimplementedBlockCacheServer) DestroyGroup(context.Context, *DestroyGroupRequest) (*DestroyGroupResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method DestroyGroup not implemented")
+}
 func (UnimplementedBlockCacheServer) mustEmbedUnimplementedBlockCacheServer() {}
 func (UnimplementedBlockCacheServer) testEmbeddedByValue()                    {}
 
@@ -182,6 +338,132 @@ func _BlockCache_Delete_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BlockCache_Handoff_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BlockCacheServer).Handoff(&grpc.GenericServerStream[HandoffEntry, HandoffResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockCache_HandoffServer = grpc.ClientStreamingServer[HandoffEntry, HandoffResponse]
+
+func _BlockCache_ListGroups_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGroupsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).ListGroups(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_ListGroups_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).ListGroups(ctx, req.(*ListGroupsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockCache_Stats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).Stats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_Stats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).Stats(ctx, req.(*StatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockCache_Peers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).Peers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_Peers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).Peers(ctx, req.(*PeersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockCache_Dump_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DumpRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BlockCacheServer).Dump(m, &grpc.GenericServerStream[DumpRequest, HandoffEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BlockCache_DumpServer = grpc.ServerStreamingServer[HandoffEntry]
+
+func _BlockCache_CreateGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GroupDefinition)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).CreateGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_CreateGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).CreateGroup(ctx, req.(*GroupDefinition))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockCache_ResizeGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResizeGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).ResizeGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_ResizeGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).ResizeGroup(ctx, req.(*ResizeGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BlockCache_DestroyGroup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DestroyGroupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BlockCacheServer).DestroyGroup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BlockCache_DestroyGroup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BlockCacheServer).DestroyGroup(ctx, req.(*DestroyGroupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BlockCache_ServiceDesc is the grpc.ServiceDesc for BlockCache service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -201,6 +483,151 @@ var BlockCache_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _BlockCache_Delete_Handler,
 		},
+		{
+			MethodName: "ListGroups",
+			Handler:    _BlockCache_ListGroups_Handler,
+		},
+		{
+			MethodName: "Stats",
+			Handler:    _BlockCache_Stats_Handler,
+		},
+		{
+			MethodName: "Peers",
+			Handler:    _BlockCache_Peers_Handler,
+		},
+		{
+			MethodName: "CreateGroup",
+			Handler:    _BlockCache_CreateGroup_Handler,
+		},
+		{
+			MethodName: "ResizeGroup",
+			Handler:    _BlockCache_ResizeGroup_Handler,
+		},
+		{
+			MethodName: "DestroyGroup",
+			Handler:    _BlockCache_DestroyGroup_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Handoff",
+			Handler:       _BlockCache_Handoff_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Dump",
+			Handler:       _BlockCache_Dump_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pb/blockcache.proto",
+}
+
+const (
+	Origin_Load_FullMethodName = "/pb.Origin/Load"
+)
+
+// OriginClient is the client API for Origin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Origin 数据源实现的回源服务，GRPCGetter在缓存未命中时调用
+// 数据源中没有该key时应返回NOT_FOUND
+type OriginClient interface {
+	// Load 从数据源读取key对应的值
+	Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error)
+}
+
+type originClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOriginClient(cc grpc.ClientConnInterface) OriginClient {
+	return &originClient{cc}
+}
+
+func (c *originClient) Load(ctx context.Context, in *LoadRequest, opts ...grpc.CallOption) (*LoadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoadResponse)
+	err := c.cc.Invoke(ctx, Origin_Load_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OriginServer is the server API for Origin service.
+// All implementations must embed UnimplementedOriginServer
+// for forward compatibility.
+//
+// Origin 数据源实现的回源服务，GRPCGetter在缓存未命中时调用
+// 数据源中没有该key时应返回NOT_FOUND
+type OriginServer interface {
+	// Load 从数据源读取key对应的值
+	Load(context.Context, *LoadRequest) (*LoadResponse, error)
+	mustEmbedUnimplementedOriginServer()
+}
+
+// UnimplementedOriginServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedOriginServer struct{}
+
+func (UnimplementedOriginServer) Load(context.Context, *LoadRequest) (*LoadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Load not implemented")
+}
+func (UnimplementedOriginServer) mustEmbedUnimplementedOriginServer() {}
+func (UnimplementedOriginServer) testEmbeddedByValue()                {}
+
+// UnsafeOriginServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OriginServer will
+// result in compilation errors.
+type UnsafeOriginServer interface {
+	mustEmbedUnimplementedOriginServer()
+}
+
+func RegisterOriginServer(s grpc.ServiceRegistrar, srv OriginServer) {
+	// If the following call panics, it indicates UnimplementedOriginServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Origin_ServiceDesc, srv)
+}
+
+func _Origin_Load_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LoadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OriginServer).Load(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Origin_Load_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OriginServer).Load(ctx, req.(*LoadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Origin_ServiceDesc is the grpc.ServiceDesc for Origin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Origin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "pb.Origin",
+	HandlerType: (*OriginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Load",
+			Handler:    _Origin_Load_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/blockcache.proto",
@@ -4,6 +4,8 @@ package blockcache
 import (
 	"context"
 	"fmt"
+	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -36,10 +38,15 @@ type PeerPicker interface {
 type Peer interface {
 	Get(ctx context.Context, group string, key string) ([]byte, error)
 	Set(ctx context.Context, group string, key string, value []byte) error
-	Delete(group string, key string) (bool, error)
+	Delete(ctx context.Context, group string, key string) (bool, error)
 	Close() error
 }
 
+// ttlPeer 写入时能指定存活时间的节点，Client实现了该接口
+type ttlPeer interface {
+	SetWithTTL(ctx context.Context, group string, key string, value []byte, ttl time.Duration) error
+}
+
 // clientpicker：实现了peerpicker接口：核心管理者
 type ClientPicker struct {
 	//我是谁，我的地址
@@ -49,30 +56,114 @@ type ClientPicker struct {
 	//读写锁
 	mu sync.RWMutex
 	//一致性哈希，相当于一个路由，由key找到节点地址
-	consHash *consistenthash.Map
+	consHash consistenthash.Router
+	//路由算法：哈希环、rendezvous、jump等
+	routing consistenthash.Algorithm
 	//维护着节点到客户端连接对象的映射：map[selfAddr] = Client
 	clients map[string]*Client
+	//节点地址到注册元信息（权重、可用区、版本等）的映射
+	endpoints map[string]registry.Endpoint
+	//元信息key中的记录，与服务key分开写入，可能先于或晚于服务key被监听到
+	meta map[string]registry.Endpoint
 	//etcd集群发现,ETCD的连接器，保持和ETCD服务的长连接；用来注册自己和监听他人
 	etcdCli *clientv3.Client
 	//生命周期管理：为了能够优雅地杀死一直在后台运行的etcd监听协程
 	ctx    context.Context    //ctx是一个令牌，交给etcd监听协程进行监听
 	cancel context.CancelFunc //cancel用于杀死etcd监听协程
+	//自己在哈希环上的权重，来自自己注册到etcd的元信息
+	selfWeight int
+	//哈希环成员变化的订阅者（如key迁移）
+	ringListeners []ringListener
+	nextListener  uint64
+	//节点熔断配置，nil表示不开启
+	breakerOpts *BreakerOptions
+	//创建到各节点的Client时使用的选项
+	clientOpts []ClientOption
+	//节点间TLS配置，所有Client共享同一个证书加载器
+	tlsConfig *TLSConfig
+	certs     *CertReloader
+	//服务发现使用的etcd集群，nil表示使用registry.DefaultConfig
+	etcdConfig *registry.Config
+}
 
+// RingChange 描述一次哈希环成员变化
+type RingChange struct {
+	Added   []string // 新加入的节点
+	Removed []string // 离开的节点
+	// 变化前的路由，用于判断key原来归谁负责
+	previous consistenthash.Router
+	self     string
+}
+
+// WasOwner 判断变化前本节点是否负责该key
+func (c RingChange) WasOwner(key string) bool {
+	return c.previous != nil && c.previous.Get(key) == c.self
 }
 
 // Option的函数类型，作为opts的函数签名
 type PickerOption func(*ClientPicker)
 
+// WithRoutingAlgorithm 选择路由算法，默认为一致性哈希环
+func WithRoutingAlgorithm(alg consistenthash.Algorithm) PickerOption {
+	return func(p *ClientPicker) {
+		p.routing = alg
+	}
+}
+
+// WithRouter 直接指定路由器实例（如自定义ε的有界负载哈希），优先于WithRoutingAlgorithm
+func WithRouter(r consistenthash.Router) PickerOption {
+	return func(p *ClientPicker) {
+		p.consHash = r
+	}
+}
+
+// WithCircuitBreaker 为每个远程节点开启熔断，熔断期间该节点负责的key直接回源
+func WithCircuitBreaker(opts BreakerOptions) PickerOption {
+	return func(p *ClientPicker) {
+		p.breakerOpts = &opts
+	}
+}
+
+// WithClientOptions 设置连接各节点时的客户端选项（超时、拨号方式、重试策略）
+func WithClientOptions(opts ...ClientOption) PickerOption {
+	return func(p *ClientPicker) {
+		p.clientOpts = append(p.clientOpts, opts...)
+	}
+}
+
+// WithPickerTLS 使用TLS/mTLS连接所有节点，证书由各Client共享并支持热加载
+func WithPickerTLS(cfg TLSConfig) PickerOption {
+	return func(p *ClientPicker) {
+		p.tlsConfig = &cfg
+	}
+}
+
+// WithServiceName 设置要发现的服务名，需要与各节点NewServer时的svcName一致
+func WithServiceName(svcName string) PickerOption {
+	return func(p *ClientPicker) {
+		p.svcName = svcName
+	}
+}
+
+// WithPickerEtcdEndpoints 设置服务发现使用的etcd集群
+func WithPickerEtcdEndpoints(endpoints []string, dialTimeout time.Duration) PickerOption {
+	return func(p *ClientPicker) {
+		p.etcdConfig = &registry.Config{Endpoints: endpoints, DialTimeout: dialTimeout}
+	}
+}
+
 // 初始化逻辑
 // 创建新ClientPicker实例
 func NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
 	//此处创建了带取消的context
 	ctx, cancel := context.WithCancel(context.Background())
 	picker := &ClientPicker{
-		selfAddr: addr,
-		svcName:  defaultSvcName,
-		clients:  make(map[string]*Client),
-		consHash: consistenthash.New(),
+		selfAddr:  addr,
+		svcName:   defaultSvcName,
+		clients:   make(map[string]*Client),
+		endpoints: make(map[string]registry.Endpoint),
+		meta:      make(map[string]registry.Endpoint),
+		routing:   consistenthash.AlgRing,
 		//初始化赋值ctx与cancel
 		ctx:    ctx,
 		cancel: cancel,
@@ -82,14 +173,43 @@ func NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
 		opt(picker)
 	}
 
+	//按选定的算法创建路由器
+	if picker.consHash == nil {
+		router, err := consistenthash.NewRouter(picker.routing)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		picker.consHash = router
+	}
+	if picker.tlsConfig != nil {
+		certs, err := NewCertReloader(*picker.tlsConfig)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
+		}
+		picker.certs = certs
+		picker.clientOpts = append(picker.clientOpts, WithTransportCredentials(certs.ClientCredentials()))
+	}
+
+	//自己也在环上，否则各节点看到的环不一致，且自己永远不是任何key的负责人
+	picker.selfWeight = 1
+	picker.consHash.AddWithWeight(addr, picker.selfWeight)
+
 	//初始化并建立到ETCD集群的连接
 	//1. 调用官方库的构造函数 New
 	// 与etcd的交互都有这个客户端cli完成
+	etcdConfig := registry.DefaultConfig
+	if picker.etcdConfig != nil {
+		etcdConfig = picker.etcdConfig
+	}
 	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   registry.DefaultConfig.Endpoints,
-		DialTimeout: registry.DefaultConfig.DialTimeout,
+		Endpoints:   etcdConfig.Endpoints,
+		DialTimeout: etcdConfig.DialTimeout,
 	})
 	if err != nil {
+		cancel()
+		picker.closeCerts()
 		return nil, err
 	}
 	picker.etcdCli = cli
@@ -98,6 +218,7 @@ func NewClientPicker(addr string, opts ...PickerOption) (*ClientPicker, error) {
 	if err := picker.startServiceDiscovery(); err != nil {
 		cancel()
 		cli.Close()
+		picker.closeCerts()
 		return nil, err
 	}
 	return picker, nil
@@ -125,30 +246,72 @@ func (p *ClientPicker) fetchAllServices() error {
 	// 参数 1: "/services/" + p.svcName
 	// 假设 svcName 是 "block-cache"，那么这个 Key 前缀就是 "/services/block-cache"
 	// 参数 2: clientv3.WithPrefix() -> 以第二个参数为前缀的所有
-	resp, err := p.etcdCli.Get(ctx, "/services/"+p.svcName, clientv3.WithPrefix())
+	//先读元信息，节点加入哈希环时就能使用注册的权重
+	metaResp, err := p.etcdCli.Get(ctx, registry.MetadataPrefix(p.svcName), clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("failed to get service metadata: %v", err)
+	}
+	resp, err := p.etcdCli.Get(ctx, registry.ServicePrefix(p.svcName), clientv3.WithPrefix())
 	if err != nil {
 		return fmt.Errorf("failed to get all services: %v", err)
 	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	for _, kv := range metaResp.Kvs {
+		if ep, err := registry.ParseEndpoint(kv.Value); err == nil {
+			p.meta[registry.AddrFromMetadataKey(p.svcName, string(kv.Key))] = ep
+		}
+	}
 	//使用读写锁并发安全地遍历etcd返回的结果中的地址，逐个创建client、地址加入哈希环、加入clients的map
 	for _, kv := range resp.Kvs {
-		addr := string(kv.Value)
-		if addr != "" && addr != p.selfAddr {
-			p.set(addr)
-			logrus.Infof("Discovered service at %s", addr)
+		ep, err := registry.ParseEndpoint(kv.Value)
+		if err != nil {
+			logrus.Warnf("Ignoring malformed service entry %s: %v", kv.Key, err)
+			continue
+		}
+		ep = p.endpointLocked(ep)
+		if ep.Addr != p.selfAddr {
+			p.set(ep)
+		} else {
+			p.setSelfWeight(ep.EffectiveWeight())
 		}
 	}
 	return nil
 }
 
+// endpointLocked 用元信息key中的记录补全服务key中的地址，调用者需持有锁
+// 旧版本节点没有元信息key，直接使用服务key中的记录
+func (p *ClientPicker) endpointLocked(ep registry.Endpoint) registry.Endpoint {
+	if meta, ok := p.meta[ep.Addr]; ok {
+		meta.Addr = ep.Addr
+		return meta
+	}
+	return ep
+}
+
+// setSelfWeight 按自己注册的权重调整自己在环上的虚拟节点，调用者需持有写锁
+func (p *ClientPicker) setSelfWeight(weight int) {
+	if weight == p.selfWeight {
+		return
+	}
+	p.selfWeight = weight
+	p.consHash.AddWithWeight(p.selfAddr, weight)
+}
+
 // set方法：创建client、地址加入哈希环、加入clients的map
-func (p *ClientPicker) set(addr string) {
-	if client, err := NewClient(addr, p.svcName, p.etcdCli); err == nil {
+func (p *ClientPicker) set(ep registry.Endpoint) {
+	addr := ep.Addr
+	if client, err := NewClient(addr, p.svcName, p.etcdCli, p.clientOpts...); err == nil {
+		if p.breakerOpts != nil {
+			client.breaker = newCircuitBreaker(*p.breakerOpts)
+		}
 		p.clients[addr] = client
-		p.consHash.Add(addr)
-		logrus.Infof("Discovered service at %s", addr)
+		p.endpoints[addr] = ep
+		//按注册的权重分配虚拟节点，机器越大承担的key越多
+		p.consHash.AddWithWeight(addr, ep.EffectiveWeight())
+		logrus.Infof("Discovered service at %s (weight=%d, zone=%q, version=%q)",
+			addr, ep.EffectiveWeight(), ep.Zone, ep.Version)
 	} else {
 		logrus.Errorf("Failed to create client for %s: %v", addr, err)
 	}
@@ -157,7 +320,8 @@ func (p *ClientPicker) set(addr string) {
 // watchServiceChanges 监听服务实例变化
 func (p *ClientPicker) watchServiceChanges() {
 	watcher := clientv3.NewWatcher(p.etcdCli)
-	watchChan := watcher.Watch(p.ctx, "/services/"+p.svcName, clientv3.WithPrefix())
+	watchChan := watcher.Watch(p.ctx, registry.ServicePrefix(p.svcName), clientv3.WithPrefix())
+	metaChan := watcher.Watch(p.ctx, registry.MetadataPrefix(p.svcName), clientv3.WithPrefix())
 	for {
 		select {
 		case <-p.ctx.Done():
@@ -165,28 +329,86 @@ func (p *ClientPicker) watchServiceChanges() {
 			return
 		case resp := <-watchChan:
 			p.handleWatchEvents(resp.Events)
+		case resp := <-metaChan:
+			p.handleMetadataEvents(resp.Events)
 		}
 	}
 }
 
+// handleMetadataEvents 处理元信息key的变化，已发现的节点刷新元信息
+func (p *ClientPicker) handleMetadataEvents(events []*clientv3.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	before := p.ringStateLocked()
+	for _, event := range events {
+		addr := registry.AddrFromMetadataKey(p.svcName, string(event.Kv.Key))
+		if event.Type == clientv3.EventTypeDelete {
+			//节点下线由服务key的删除事件处理
+			delete(p.meta, addr)
+			continue
+		}
+		ep, err := registry.ParseEndpoint(event.Kv.Value)
+		if err != nil {
+			logrus.Warnf("Ignoring malformed service metadata %s: %v", event.Kv.Key, err)
+			continue
+		}
+		ep.Addr = addr
+		p.meta[addr] = ep
+		if addr == p.selfAddr {
+			p.setSelfWeight(ep.EffectiveWeight())
+		} else if _, exists := p.clients[addr]; exists {
+			p.updateEndpointLocked(ep)
+		}
+	}
+	p.notifyRingChange(before)
+}
+
+// updateEndpointLocked 刷新已发现节点的元信息，权重变化时重建虚拟节点，调用者需持有写锁
+func (p *ClientPicker) updateEndpointLocked(ep registry.Endpoint) {
+	if p.endpoints[ep.Addr].EffectiveWeight() != ep.EffectiveWeight() {
+		p.consHash.AddWithWeight(ep.Addr, ep.EffectiveWeight())
+	}
+	p.endpoints[ep.Addr] = ep
+}
+
 // handleWatchEvents 处理监听到的事件
 func (p *ClientPicker) handleWatchEvents(events []*clientv3.Event) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+
+	//记录变化前的成员和路由，用于通知订阅者
+	before := p.ringStateLocked()
+
 	//etcd不会改一个就推一次，而是在网络繁忙时将一堆变化打包成一个events列表发送
 	for _, event := range events {
-		addr := string(event.Kv.Value)
+		//删除事件中value为空，地址只能从key中解析
+		addr := registry.AddrFromKey(p.svcName, string(event.Kv.Key))
 		//过滤自己，以免造成环路（etcd的广播也会发给自己）
 		if addr == p.selfAddr {
+			if event.Type == clientv3.EventTypePut {
+				if ep, err := registry.ParseEndpoint(event.Kv.Value); err == nil {
+					p.setSelfWeight(p.endpointLocked(ep).EffectiveWeight())
+				}
+			}
 			continue
 		}
 
 		switch event.Type {
 		//EventTypePut：上线/更新
 		case clientv3.EventTypePut:
+			ep, err := registry.ParseEndpoint(event.Kv.Value)
+			if err != nil {
+				logrus.Warnf("Ignoring malformed service entry %s: %v", event.Kv.Key, err)
+				continue
+			}
+			ep = p.endpointLocked(ep)
 			if _, exists := p.clients[addr]; !exists {
-				p.set(addr)
+				p.set(ep)
 				logrus.Infof("New service discovered at %s", addr)
+			} else {
+				//已存在的节点重新注册（如租约恢复、滚动升级），刷新元信息
+				p.updateEndpointLocked(ep)
 			}
 			//EventTypeDelete：下线/故障
 		case clientv3.EventTypeDelete:
@@ -199,6 +421,128 @@ func (p *ClientPicker) handleWatchEvents(events []*clientv3.Event) {
 			}
 		}
 	}
+
+	p.notifyRingChange(before)
+}
+
+// membersLocked 返回当前环上的成员及权重（含自己），调用者需持有锁
+func (p *ClientPicker) membersLocked() map[string]int {
+	members := make(map[string]int, len(p.endpoints)+1)
+	members[p.selfAddr] = p.selfWeight
+	for addr := range p.clients {
+		members[addr] = p.endpoints[addr].EffectiveWeight()
+	}
+	return members
+}
+
+// ringListener 一个环变化订阅者
+type ringListener struct {
+	id uint64
+	fn func(RingChange)
+}
+
+// ringState 成员变化前的环，用于计算变化和判断key原来的归属
+type ringState struct {
+	members map[string]int
+	// 变化前正在使用的路由器的副本，路由器不支持复制时为nil
+	router consistenthash.Router
+}
+
+// ringStateLocked 记录当前的成员和路由，没有订阅者时不复制路由器，调用者需持有锁
+func (p *ClientPicker) ringStateLocked() ringState {
+	state := ringState{members: p.membersLocked()}
+	if len(p.ringListeners) == 0 {
+		return state
+	}
+	if cloner, ok := p.consHash.(consistenthash.Cloner); ok {
+		state.router = cloner.Clone()
+	}
+	return state
+}
+
+// notifyRingChange 成员有变化时异步通知订阅者，调用者需持有锁
+func (p *ClientPicker) notifyRingChange(before ringState) {
+	if len(p.ringListeners) == 0 {
+		return
+	}
+
+	after := p.membersLocked()
+	change := RingChange{self: p.selfAddr}
+	for addr, w := range after {
+		if bw, ok := before.members[addr]; !ok || bw != w {
+			change.Added = append(change.Added, addr)
+		}
+	}
+	for addr := range before.members {
+		if _, ok := after[addr]; !ok {
+			change.Removed = append(change.Removed, addr)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return
+	}
+
+	change.previous = before.router
+	if change.previous == nil {
+		//自定义路由器不支持复制时，用变化前的成员按路由算法重建一份
+		previous, err := consistenthash.NewRouter(p.routing)
+		if err != nil {
+			logrus.Errorf("Failed to rebuild previous ring: %v", err)
+			return
+		}
+		for addr, w := range before.members {
+			previous.AddWithWeight(addr, w)
+		}
+		if closer, ok := previous.(io.Closer); ok {
+			defer closer.Close()
+		}
+		change.previous = previous
+	}
+
+	listeners := make([]func(RingChange), 0, len(p.ringListeners))
+	for _, l := range p.ringListeners {
+		listeners = append(listeners, l.fn)
+	}
+	go func() {
+		for _, fn := range listeners {
+			fn(change)
+		}
+	}()
+}
+
+// OnRingChange 订阅哈希环成员变化，回调在独立的goroutine中执行；返回的函数用于取消订阅
+func (p *ClientPicker) OnRingChange(fn func(RingChange)) (cancel func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextListener++
+	id := p.nextListener
+	p.ringListeners = append(p.ringListeners, ringListener{id: id, fn: fn})
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, l := range p.ringListeners {
+			if l.id == id {
+				p.ringListeners = append(p.ringListeners[:i:i], p.ringListeners[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Owner 返回key当前归属节点的地址，以及是否为自己
+func (p *ClientPicker) Owner(key string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	addr := p.consHash.Get(key)
+	return addr, addr != "" && addr == p.selfAddr
+}
+
+// HandoffPeer 返回指定地址节点的迁移客户端
+func (p *ClientPicker) HandoffPeer(addr string) (HandoffPeer, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	client, ok := p.clients[addr]
+	return client, ok
 }
 
 // 删除节点
@@ -206,6 +550,7 @@ func (p *ClientPicker) remove(addr string) {
 	//consHash的Remove内部有锁
 	p.consHash.Remove(addr)
 	delete(p.clients, addr)
+	delete(p.endpoints, addr)
 }
 
 // Close 关闭所有资源
@@ -221,8 +566,19 @@ func (p *ClientPicker) Close() error {
 		}
 	}
 
-	if err := p.etcdCli.Close(); err != nil {
-		errs = append(errs, fmt.Errorf("failed to close etcd client: %v", err))
+	if p.etcdCli != nil {
+		if err := p.etcdCli.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close etcd client: %v", err))
+		}
+	}
+
+	p.closeCerts()
+
+	//停止路由器的后台协程（如哈希环的自动再平衡）
+	if closer, ok := p.consHash.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close router: %v", err))
+		}
 	}
 
 	if len(errs) > 0 {
@@ -231,6 +587,13 @@ func (p *ClientPicker) Close() error {
 	return nil
 }
 
+// closeCerts 停止证书热加载
+func (p *ClientPicker) closeCerts() {
+	if p.certs != nil {
+		p.certs.Close()
+	}
+}
+
 // pickpeer 通过使用key与一致性哈希查找client,从而选择peer节点
 func (p *ClientPicker) PickPeer(key string) (Peer, bool, bool) {
 	//需要读consHash的map，所以需要加读锁
@@ -238,17 +601,167 @@ func (p *ClientPicker) PickPeer(key string) (Peer, bool, bool) {
 	defer p.mu.RUnlock()
 	//一致性哈希查找
 	if addr := p.consHash.Get(key); addr != "" {
+		//归自己负责，不需要远程客户端
+		if addr == p.selfAddr {
+			return nil, true, true
+		}
 		if client, ok := p.clients[addr]; ok {
-			return client, true, addr == p.selfAddr
+			//节点处于熔断状态时绕开它，由调用方回源
+			if client.breaker != nil && !client.breaker.Allow() {
+				return nil, false, false
+			}
+			return p.peerLocked(addr, client), true, false
 		}
 	}
 	return nil, false, false
 }
 
+// PickPeers 按路由优先级返回key的前n个远程候选节点，跳过自己和非正常状态的节点
+// 用于对冲请求选择下一个副本；路由器不支持多候选时只返回负责节点
+func (p *ClientPicker) PickPeers(key string, n int) []Peer {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var addrs []string
+	if multi, ok := p.consHash.(consistenthash.MultiRouter); ok {
+		//多取一个，自己可能在候选列表中
+		addrs = multi.GetN(key, n+1)
+	} else if addr := p.consHash.Get(key); addr != "" {
+		addrs = []string{addr}
+	}
+
+	peers := make([]Peer, 0, n)
+	for _, addr := range addrs {
+		if len(peers) == n {
+			break
+		}
+		client, ok := p.clients[addr]
+		if addr == p.selfAddr || !ok || client.BreakerState() != BreakerClosed {
+			continue
+		}
+		peers = append(peers, p.peerLocked(addr, client))
+	}
+	return peers
+}
+
+// peerLocked 路由器需要感知在途请求数时（有界负载哈希），包装一层用于计数，调用者需持有读锁
+func (p *ClientPicker) peerLocked(addr string, client *Client) Peer {
+	if tracker, ok := p.consHash.(consistenthash.LoadTracker); ok {
+		return &trackedPeer{Peer: client, addr: addr, tracker: tracker}
+	}
+	return client
+}
+
+// localLoadTracker 由需要统计本节点在途加载数的节点选择器实现，Group在本地回源前后调用
+type localLoadTracker interface {
+	trackLocal() (done func())
+}
+
+// trackLocal 路由器需要感知在途请求数时，路由到自己的加载也计入自己的负载，
+// 否则自己的负载始终为0，会吸收所有溢出的key，并拉低用于计算上限的平均负载
+func (p *ClientPicker) trackLocal() func() {
+	p.mu.RLock()
+	tracker, ok := p.consHash.(consistenthash.LoadTracker)
+	p.mu.RUnlock()
+	if !ok {
+		return func() {}
+	}
+	tracker.Inc(p.selfAddr)
+	return func() { tracker.Done(p.selfAddr) }
+}
+
+// trackedPeer 在每次RPC前后向路由器汇报节点的在途请求数
+type trackedPeer struct {
+	Peer
+	addr    string
+	tracker consistenthash.LoadTracker
+}
+
+func (t *trackedPeer) Get(ctx context.Context, group string, key string) ([]byte, error) {
+	t.tracker.Inc(t.addr)
+	defer t.tracker.Done(t.addr)
+	return t.Peer.Get(ctx, group, key)
+}
+
+func (t *trackedPeer) Set(ctx context.Context, group string, key string, value []byte) error {
+	t.tracker.Inc(t.addr)
+	defer t.tracker.Done(t.addr)
+	return t.Peer.Set(ctx, group, key, value)
+}
+
+func (t *trackedPeer) SetWithTTL(ctx context.Context, group string, key string, value []byte, ttl time.Duration) error {
+	t.tracker.Inc(t.addr)
+	defer t.tracker.Done(t.addr)
+	if tp, ok := t.Peer.(ttlPeer); ok {
+		return tp.SetWithTTL(ctx, group, key, value, ttl)
+	}
+	return t.Peer.Set(ctx, group, key, value)
+}
+
+func (t *trackedPeer) Delete(ctx context.Context, group string, key string) (bool, error) {
+	t.tracker.Inc(t.addr)
+	defer t.tracker.Done(t.addr)
+	return t.Peer.Delete(ctx, group, key)
+}
+
 func (p *ClientPicker) Delete(key string) {
 	p.remove(key)
 }
 
+// Endpoints 返回当前已发现节点的注册信息
+func (p *ClientPicker) Endpoints() []registry.Endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	eps := make([]registry.Endpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		eps = append(eps, ep)
+	}
+	sort.Slice(eps, func(i, j int) bool { return eps[i].Addr < eps[j].Addr })
+	return eps
+}
+
+// Endpoint 返回指定节点的注册信息
+func (p *ClientPicker) Endpoint(addr string) (registry.Endpoint, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	ep, ok := p.endpoints[addr]
+	return ep, ok
+}
+
+// BreakerStates 返回各远程节点的熔断状态
+func (p *ClientPicker) BreakerStates() map[string]BreakerState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	states := make(map[string]BreakerState, len(p.clients))
+	for addr, client := range p.clients {
+		states[addr] = client.BreakerState()
+	}
+	return states
+}
+
+// RingSnapshot 返回当前哈希环的快照，路由算法不是哈希环（如rendezvous、jump）时返回错误
+func (p *ClientPicker) RingSnapshot() (consistenthash.Snapshot, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	snapshotter, ok := p.consHash.(consistenthash.Snapshotter)
+	if !ok {
+		return consistenthash.Snapshot{}, fmt.Errorf("router %T does not support ring snapshots", p.consHash)
+	}
+	return snapshotter.Snapshot(), nil
+}
+
+// RingDiff 比较给定快照与当前环，估算期间需要迁移的key比例
+func (p *ClientPicker) RingDiff(since consistenthash.Snapshot) (consistenthash.RingDiff, error) {
+	current, err := p.RingSnapshot()
+	if err != nil {
+		return consistenthash.RingDiff{}, err
+	}
+	return consistenthash.Diff(since, current), nil
+}
+
 // PrintPeers 打印当前已发现的节点
 func (p *ClientPicker) PrintPeers() {
 	p.mu.RLock()
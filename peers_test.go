@@ -0,0 +1,155 @@
+package blockcache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crypt0walker/BlockCache/consistenthash"
+	"github.com/crypt0walker/BlockCache/registry"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// newTestPicker 不连接etcd的节点选择器，通过handleWatchEvents等方法驱动
+func newTestPicker(t *testing.T, self string, router consistenthash.Router) *ClientPicker {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &ClientPicker{
+		selfAddr:   self,
+		svcName:    "test",
+		consHash:   router,
+		routing:    consistenthash.AlgRing,
+		clients:    make(map[string]*Client),
+		endpoints:  make(map[string]registry.Endpoint),
+		meta:       make(map[string]registry.Endpoint),
+		ctx:        ctx,
+		cancel:     cancel,
+		selfWeight: 1,
+		clientOpts: []ClientOption{WithNonBlockingDial()},
+	}
+	router.AddWithWeight(self, 1)
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+func putEvent(key, value string) *clientv3.Event {
+	return &clientv3.Event{Type: clientv3.EventTypePut, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}
+}
+
+func deleteEvent(key string) *clientv3.Event {
+	return &clientv3.Event{Type: clientv3.EventTypeDelete, Kv: &mvccpb.KeyValue{Key: []byte(key)}}
+}
+
+// TestPicker_MetadataKey 服务key只有地址，元信息从单独的key合并，先后到达都能生效
+func TestPicker_MetadataKey(t *testing.T) {
+	p := newTestPicker(t, "self:1", consistenthash.New())
+	svc, meta := registry.ServicePrefix("test"), registry.MetadataPrefix("test")
+
+	// 元信息先到
+	p.handleMetadataEvents([]*clientv3.Event{putEvent(meta+"a:1", `{"addr":"a:1","weight":3,"zone":"z1"}`)})
+	p.handleWatchEvents([]*clientv3.Event{putEvent(svc+"a:1", "a:1")})
+	// 服务key先到
+	p.handleWatchEvents([]*clientv3.Event{putEvent(svc+"b:1", "b:1")})
+	if w := p.endpoints["b:1"].EffectiveWeight(); w != 1 {
+		t.Fatalf("b weight before metadata = %d", w)
+	}
+	p.handleMetadataEvents([]*clientv3.Event{putEvent(meta+"b:1", `{"addr":"b:1","weight":2}`)})
+	// 早期版本直接在服务key中写JSON
+	p.handleWatchEvents([]*clientv3.Event{putEvent(svc+"c:1", `{"addr":"c:1","weight":4}`)})
+
+	want := map[string]int{"a:1": 3, "b:1": 2, "c:1": 4}
+	for addr, w := range want {
+		if got := p.endpoints[addr].EffectiveWeight(); got != w {
+			t.Errorf("%s weight = %d, want %d", addr, got, w)
+		}
+	}
+	if p.endpoints["a:1"].Zone != "z1" {
+		t.Errorf("a zone = %q", p.endpoints["a:1"].Zone)
+	}
+
+	// 自己的元信息调整自己在环上的权重
+	p.handleMetadataEvents([]*clientv3.Event{putEvent(meta+"self:1", `{"addr":"self:1","weight":5}`)})
+	if p.selfWeight != 5 {
+		t.Errorf("self weight = %d", p.selfWeight)
+	}
+
+	// 服务key删除时节点下线
+	p.handleWatchEvents([]*clientv3.Event{deleteEvent(svc + "a:1")})
+	p.handleMetadataEvents([]*clientv3.Event{deleteEvent(meta + "a:1")})
+	if _, ok := p.clients["a:1"]; ok {
+		t.Fatal("a still present after delete")
+	}
+	if _, ok := p.meta["a:1"]; ok {
+		t.Fatal("a metadata kept after delete")
+	}
+}
+
+// TestPicker_TrackLocal 有界负载路由下，路由到自己的本地加载计入自己的负载
+func TestPicker_TrackLocal(t *testing.T) {
+	router := consistenthash.NewBoundedLoad(consistenthash.DefaultEpsilon)
+	p := newTestPicker(t, "self:1", router)
+
+	done := p.trackLocal()
+	if got := router.Loads()["self:1"]; got != 1 {
+		t.Fatalf("self load = %d, want 1", got)
+	}
+	done()
+	if got := router.Loads()["self:1"]; got != 0 {
+		t.Fatalf("self load after done = %d, want 0", got)
+	}
+
+	// 普通哈希环不需要统计负载
+	newTestPicker(t, "self:2", consistenthash.New()).trackLocal()()
+}
+
+// TestPicker_NotifyRingChange 变化前的路由来自正在使用的路由器，取消订阅后不再收到通知
+func TestPicker_NotifyRingChange(t *testing.T) {
+	// 自定义ε和虚拟节点数的有界负载哈希无法按算法名称重建
+	router := consistenthash.NewBoundedLoad(0.5, consistenthash.WithConfig(&consistenthash.Config{
+		DefaultReplicas: 7,
+		HashFunc:        consistenthash.DefaultConfig.HashFunc,
+	}))
+	p := newTestPicker(t, "self:1", router)
+	svc := registry.ServicePrefix("test")
+
+	changes := make(chan RingChange, 4)
+	cancel := p.OnRingChange(func(c RingChange) { changes <- c })
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	p.handleWatchEvents([]*clientv3.Event{putEvent(svc+"a:1", "a:1")})
+	change := <-changes
+	if len(change.Added) != 1 || change.Added[0] != "a:1" || len(change.Removed) != 0 {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	// 变化前只有自己，所有key都归自己
+	for _, key := range keys {
+		if !change.WasOwner(key) {
+			t.Fatalf("%s was owned by self before the change", key)
+		}
+	}
+
+	owners := consistenthash.Assign(router, keys)
+	p.handleWatchEvents([]*clientv3.Event{deleteEvent(svc + "a:1")})
+	change = <-changes
+	if len(change.Removed) != 1 || change.Removed[0] != "a:1" {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	// 变化前的路由与删除前正在使用的路由器一致
+	if m := consistenthash.Movement(owners, consistenthash.Assign(change.previous, keys)); m != 0 {
+		t.Fatalf("previous router differs from the router in use: %.2f moved", m)
+	}
+
+	cancel()
+	p.handleWatchEvents([]*clientv3.Event{putEvent(svc+"b:1", "b:1")})
+	select {
+	case c := <-changes:
+		t.Fatalf("notified after cancel: %+v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Metadata 节点注册时附带的元信息
+type Metadata struct {
+	Weight   int      `json:"weight,omitempty"`   // 节点权重，决定哈希环上的虚拟节点比例
+	Zone     string   `json:"zone,omitempty"`     // 所在可用区/机架
+	Version  string   `json:"version,omitempty"`  // 构建版本，用于滚动升级
+	Features []string `json:"features,omitempty"` // 支持的特性列表
+}
+
+// Endpoint 节点的地址和元信息
+// 服务key（ServicePrefix下）的value只写地址，旧版本节点可以照常读取；
+// 完整的Endpoint以JSON写在MetadataPrefix下的同名key中，与服务key共用租约。
+type Endpoint struct {
+	Addr string `json:"addr"`
+	Metadata
+}
+
+// HasFeature 判断节点是否声明支持某个特性
+func (e Endpoint) HasFeature(name string) bool {
+	for _, f := range e.Features {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveWeight 返回节点权重，未设置时视为1
+func (e Endpoint) EffectiveWeight() int {
+	if e.Weight <= 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// Marshal 编码为etcd中存储的value
+func (e Endpoint) Marshal() (string, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// ParseEndpoint 解析服务key或元信息key的value
+// value不是JSON时，整个value即为地址；早期版本曾把JSON直接写在服务key中，同样可以解析
+func ParseEndpoint(value []byte) (Endpoint, error) {
+	s := strings.TrimSpace(string(value))
+	if s == "" {
+		return Endpoint{}, errors.New("empty endpoint value")
+	}
+	if !strings.HasPrefix(s, "{") {
+		return Endpoint{Addr: s}, nil
+	}
+
+	var ep Endpoint
+	if err := json.Unmarshal([]byte(s), &ep); err != nil {
+		return Endpoint{}, err
+	}
+	if ep.Addr == "" {
+		return Endpoint{}, errors.New("endpoint without addr")
+	}
+	return ep, nil
+}
+
+// ServicePrefix 返回服务在etcd中的key前缀
+func ServicePrefix(svcName string) string {
+	return "/services/" + svcName + "/"
+}
+
+// MetadataPrefix 返回节点元信息在etcd中的key前缀，不在ServicePrefix之下，
+// 只按前缀列出服务key的旧版本节点不会读到它
+func MetadataPrefix(svcName string) string {
+	return "/metadata/" + svcName + "/"
+}
+
+// AddrFromKey 从etcd key中解析出节点地址（删除事件中value为空，只能依赖key）
+func AddrFromKey(svcName, key string) string {
+	return strings.TrimPrefix(key, ServicePrefix(svcName))
+}
+
+// AddrFromMetadataKey 从元信息key中解析出节点地址
+func AddrFromMetadataKey(svcName, key string) string {
+	return strings.TrimPrefix(key, MetadataPrefix(svcName))
+}
@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseEndpoint_Legacy 旧版本节点只写入地址字符串
+func TestParseEndpoint_Legacy(t *testing.T) {
+	ep, err := ParseEndpoint([]byte("127.0.0.1:8001"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ep.Addr != "127.0.0.1:8001" || ep.EffectiveWeight() != 1 {
+		t.Fatalf("unexpected endpoint: %+v", ep)
+	}
+}
+
+// TestParseEndpoint_RoundTrip 结构化记录编码后可以完整解析
+func TestParseEndpoint_RoundTrip(t *testing.T) {
+	in := Endpoint{Addr: ":8002", Metadata: Metadata{
+		Weight:   3,
+		Zone:     "az-1",
+		Version:  "v1.2.0",
+		Features: []string{"handoff"},
+	}}
+	value, err := in.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := ParseEndpoint([]byte(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Addr != in.Addr || out.Weight != 3 || out.Zone != "az-1" || out.Version != "v1.2.0" {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+	if !out.HasFeature("handoff") || out.HasFeature("tls") {
+		t.Fatalf("unexpected features: %v", out.Features)
+	}
+}
+
+// TestParseEndpoint_Invalid 非法记录应返回错误
+func TestParseEndpoint_Invalid(t *testing.T) {
+	for _, v := range []string{"", "{}", "{bad json"} {
+		if _, err := ParseEndpoint([]byte(v)); err == nil {
+			t.Fatalf("expected error for %q", v)
+		}
+	}
+}
+
+// TestAddrFromKey 从etcd key中解析地址
+func TestAddrFromKey(t *testing.T) {
+	if got := AddrFromKey("block-cache", "/services/block-cache/10.0.0.1:8001"); got != "10.0.0.1:8001" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+// TestMetadataPrefix 元信息key不在服务前缀下，旧版本节点按前缀列出服务时读不到它
+func TestMetadataPrefix(t *testing.T) {
+	key := MetadataPrefix("block-cache") + "10.0.0.1:8001"
+	if strings.HasPrefix(key, ServicePrefix("block-cache")) || strings.HasPrefix(key, "/services/") {
+		t.Fatalf("metadata key %q is under the service prefix", key)
+	}
+	if got := AddrFromMetadataKey("block-cache", key); got != "10.0.0.1:8001" {
+		t.Fatalf("got %q", got)
+	}
+}
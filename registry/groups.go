@@ -0,0 +1,354 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// 缓存组定义
+// 组定义保存在发现后端中，每个节点监听变化并在本地创建、调整或销毁对应的缓存组，
+// 新加入的节点在启动时按已有定义建好所有组，不再依赖每个进程自己调用NewGroup。
+
+var (
+	// ErrGroupExists 创建时组定义已存在
+	ErrGroupExists = errors.New("group definition already exists")
+	// ErrGroupUndefined 组定义不存在
+	ErrGroupUndefined = errors.New("group definition not found")
+)
+
+// GroupDefinition 集群范围的缓存组定义
+type GroupDefinition struct {
+	Name      string        `json:"name"`
+	MaxBytes  int64         `json:"maxBytes"`            // 每个节点上的容量上限
+	TTL       time.Duration `json:"ttl,omitempty"`       // 默认过期时间（JSON中为纳秒），0表示不过期
+	CacheType string        `json:"cacheType,omitempty"` // lru或lru2，空表示默认
+	// Getter 数据源配置，由节点上的工厂函数解释，空表示组只保存Set写入的数据
+	Getter json.RawMessage `json:"getter,omitempty"`
+}
+
+// Validate 检查定义是否完整
+func (d GroupDefinition) Validate() error {
+	switch {
+	case d.Name == "" || strings.Contains(d.Name, "/"):
+		return fmt.Errorf("invalid group name %q", d.Name)
+	case d.MaxBytes <= 0:
+		return fmt.Errorf("group %s: max bytes must be positive", d.Name)
+	case d.TTL < 0:
+		return fmt.Errorf("group %s: ttl must not be negative", d.Name)
+	}
+	return nil
+}
+
+// GroupEvent 组定义的一次变化
+type GroupEvent struct {
+	Definition GroupDefinition // 删除事件中只有Name
+	Deleted    bool
+}
+
+// GroupStore 组定义的存储，由发现后端实现
+type GroupStore interface {
+	// Create 写入新定义，已存在时返回ErrGroupExists
+	Create(ctx context.Context, def GroupDefinition) error
+	// Update 对已有定义做读-改-写，并发修改时自动重试；不存在时返回ErrGroupUndefined
+	Update(ctx context.Context, name string, fn func(*GroupDefinition) error) (GroupDefinition, error)
+	// Delete 删除定义，返回定义是否存在
+	Delete(ctx context.Context, name string) (bool, error)
+	// List 返回所有定义，按名称排序
+	List(ctx context.Context) ([]GroupDefinition, error)
+	// Watch 先同步调用fn投递所有已有定义，然后在后台投递后续变化，直到ctx结束
+	Watch(ctx context.Context, fn func(GroupEvent)) error
+}
+
+// GroupPrefix 返回服务的组定义在etcd中的key前缀
+func GroupPrefix(svcName string) string {
+	return "/groups/" + svcName + "/"
+}
+
+// etcdGroupStore 基于etcd的组定义存储
+type etcdGroupStore struct {
+	cli    *clientv3.Client
+	prefix string
+}
+
+// NewEtcdGroupStore 在etcd中保存svcName的组定义，cli由调用方管理
+func NewEtcdGroupStore(cli *clientv3.Client, svcName string) GroupStore {
+	return &etcdGroupStore{cli: cli, prefix: GroupPrefix(svcName)}
+}
+
+func (s *etcdGroupStore) Create(ctx context.Context, def GroupDefinition) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	value, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	key := s.prefix + def.Name
+	resp, err := s.cli.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !resp.Succeeded {
+		return fmt.Errorf("%w: %s", ErrGroupExists, def.Name)
+	}
+	return nil
+}
+
+func (s *etcdGroupStore) Update(ctx context.Context, name string, fn func(*GroupDefinition) error) (GroupDefinition, error) {
+	key := s.prefix + name
+	for {
+		resp, err := s.cli.Get(ctx, key)
+		if err != nil {
+			return GroupDefinition{}, err
+		}
+		if len(resp.Kvs) == 0 {
+			return GroupDefinition{}, fmt.Errorf("%w: %s", ErrGroupUndefined, name)
+		}
+		var def GroupDefinition
+		if err := json.Unmarshal(resp.Kvs[0].Value, &def); err != nil {
+			return GroupDefinition{}, err
+		}
+		if err := fn(&def); err != nil {
+			return GroupDefinition{}, err
+		}
+		def.Name = name
+		if err := def.Validate(); err != nil {
+			return GroupDefinition{}, err
+		}
+		value, err := json.Marshal(def)
+		if err != nil {
+			return GroupDefinition{}, err
+		}
+		// 只有在读取之后没有被其他人修改时才写入，否则重新读取
+		txn, err := s.cli.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(clientv3.OpPut(key, string(value))).
+			Commit()
+		if err != nil {
+			return GroupDefinition{}, err
+		}
+		if txn.Succeeded {
+			return def, nil
+		}
+	}
+}
+
+func (s *etcdGroupStore) Delete(ctx context.Context, name string) (bool, error) {
+	resp, err := s.cli.Delete(ctx, s.prefix+name)
+	if err != nil {
+		return false, err
+	}
+	return resp.Deleted > 0, nil
+}
+
+func (s *etcdGroupStore) List(ctx context.Context) ([]GroupDefinition, error) {
+	defs, _, err := s.list(ctx)
+	return defs, err
+}
+
+// list 返回所有定义及读取时的revision，跳过无法解析的记录
+func (s *etcdGroupStore) list(ctx context.Context) ([]GroupDefinition, int64, error) {
+	resp, err := s.cli.Get(ctx, s.prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, 0, err
+	}
+	defs := make([]GroupDefinition, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		var def GroupDefinition
+		if err := json.Unmarshal(kv.Value, &def); err != nil {
+			logrus.Warnf("skip invalid group definition %s: %v", kv.Key, err)
+			continue
+		}
+		defs = append(defs, def)
+	}
+	return defs, resp.Header.Revision, nil
+}
+
+func (s *etcdGroupStore) Watch(ctx context.Context, fn func(GroupEvent)) error {
+	defs, rev, err := s.list(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		known[def.Name] = true
+		fn(GroupEvent{Definition: def})
+	}
+	go s.watch(ctx, rev, known, fn)
+	return nil
+}
+
+// watch 从rev之后开始监听；watch中断（如revision被压缩）时重新全量同步，
+// 对比known补发期间错过的删除
+func (s *etcdGroupStore) watch(ctx context.Context, rev int64, known map[string]bool, fn func(GroupEvent)) {
+	for ctx.Err() == nil {
+		wch := s.cli.Watch(clientv3.WithRequireLeader(ctx), s.prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1))
+		for resp := range wch {
+			if resp.Err() != nil {
+				logrus.Warnf("group definition watch interrupted: %v", resp.Err())
+				break
+			}
+			rev = resp.Header.Revision
+			for _, ev := range resp.Events {
+				name := strings.TrimPrefix(string(ev.Kv.Key), s.prefix)
+				if ev.Type == clientv3.EventTypeDelete {
+					delete(known, name)
+					fn(GroupEvent{Definition: GroupDefinition{Name: name}, Deleted: true})
+					continue
+				}
+				var def GroupDefinition
+				if err := json.Unmarshal(ev.Kv.Value, &def); err != nil {
+					logrus.Warnf("skip invalid group definition %s: %v", ev.Kv.Key, err)
+					continue
+				}
+				known[name] = true
+				fn(GroupEvent{Definition: def})
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		// 重新全量同步，失败时稍后重试
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+		defs, newRev, err := s.list(ctx)
+		if err != nil {
+			logrus.Warnf("failed to resync group definitions: %v", err)
+			continue
+		}
+		current := make(map[string]bool, len(defs))
+		for _, def := range defs {
+			current[def.Name] = true
+			fn(GroupEvent{Definition: def})
+		}
+		for name := range known {
+			if !current[name] {
+				fn(GroupEvent{Definition: GroupDefinition{Name: name}, Deleted: true})
+			}
+		}
+		known, rev = current, newRev
+	}
+}
+
+// memoryGroupStore 进程内的组定义存储
+type memoryGroupStore struct {
+	mu       sync.Mutex
+	defs     map[string]GroupDefinition
+	watchers map[int]groupWatcher
+	nextID   int
+}
+
+type groupWatcher struct {
+	ctx context.Context
+	fn  func(GroupEvent)
+}
+
+// NewMemoryGroupStore 返回进程内的组定义存储，用于测试或不依赖etcd的单进程部署
+func NewMemoryGroupStore() GroupStore {
+	return &memoryGroupStore{
+		defs:     make(map[string]GroupDefinition),
+		watchers: make(map[int]groupWatcher),
+	}
+}
+
+func (s *memoryGroupStore) Create(ctx context.Context, def GroupDefinition) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.defs[def.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrGroupExists, def.Name)
+	}
+	s.defs[def.Name] = def
+	s.notify(GroupEvent{Definition: def})
+	return nil
+}
+
+func (s *memoryGroupStore) Update(ctx context.Context, name string, fn func(*GroupDefinition) error) (GroupDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	def, ok := s.defs[name]
+	if !ok {
+		return GroupDefinition{}, fmt.Errorf("%w: %s", ErrGroupUndefined, name)
+	}
+	if err := fn(&def); err != nil {
+		return GroupDefinition{}, err
+	}
+	def.Name = name
+	if err := def.Validate(); err != nil {
+		return GroupDefinition{}, err
+	}
+	s.defs[name] = def
+	s.notify(GroupEvent{Definition: def})
+	return def, nil
+}
+
+func (s *memoryGroupStore) Delete(ctx context.Context, name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.defs[name]; !ok {
+		return false, nil
+	}
+	delete(s.defs, name)
+	s.notify(GroupEvent{Definition: GroupDefinition{Name: name}, Deleted: true})
+	return true, nil
+}
+
+func (s *memoryGroupStore) List(ctx context.Context) ([]GroupDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sorted(), nil
+}
+
+func (s *memoryGroupStore) sorted() []GroupDefinition {
+	defs := make([]GroupDefinition, 0, len(s.defs))
+	for _, def := range s.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+func (s *memoryGroupStore) Watch(ctx context.Context, fn func(GroupEvent)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, def := range s.sorted() {
+		fn(GroupEvent{Definition: def})
+	}
+	id := s.nextID
+	s.nextID++
+	s.watchers[id] = groupWatcher{ctx: ctx, fn: fn}
+	context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		delete(s.watchers, id)
+		s.mu.Unlock()
+	})
+	return nil
+}
+
+// notify 调用方持有锁，事件按写入顺序同步投递
+func (s *memoryGroupStore) notify(ev GroupEvent) {
+	for _, w := range s.watchers {
+		// AfterFunc异步执行，ctx结束后到移除之前的事件也不再投递
+		if w.ctx.Err() == nil {
+			w.fn(ev)
+		}
+	}
+}
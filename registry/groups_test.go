@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestMemoryGroupStore 创建、修改、删除都会通知监听者，已有定义在Watch时先同步投递
+func TestMemoryGroupStore(t *testing.T) {
+	s := NewMemoryGroupStore()
+	ctx := context.Background()
+	if err := s.Create(ctx, GroupDefinition{Name: "a", MaxBytes: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Create(ctx, GroupDefinition{Name: "a", MaxBytes: 1}); !errors.Is(err, ErrGroupExists) {
+		t.Fatalf("duplicate create err = %v", err)
+	}
+	if err := s.Create(ctx, GroupDefinition{Name: "bad/name", MaxBytes: 1}); err == nil {
+		t.Fatal("invalid name accepted")
+	}
+
+	var events []GroupEvent
+	watchCtx, cancel := context.WithCancel(ctx)
+	if err := s.Watch(watchCtx, func(ev GroupEvent) { events = append(events, ev) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Definition.Name != "a" {
+		t.Fatalf("initial events = %+v", events)
+	}
+
+	def, err := s.Update(ctx, "a", func(d *GroupDefinition) error {
+		d.MaxBytes = 2 << 20
+		return nil
+	})
+	if err != nil || def.MaxBytes != 2<<20 {
+		t.Fatalf("update = %+v, %v", def, err)
+	}
+	if _, err := s.Update(ctx, "missing", func(*GroupDefinition) error { return nil }); !errors.Is(err, ErrGroupUndefined) {
+		t.Fatalf("update missing err = %v", err)
+	}
+	if existed, err := s.Delete(ctx, "a"); !existed || err != nil {
+		t.Fatalf("delete = %v, %v", existed, err)
+	}
+	if existed, _ := s.Delete(ctx, "a"); existed {
+		t.Fatal("second delete reported existing definition")
+	}
+	if len(events) != 3 || events[1].Definition.MaxBytes != 2<<20 || !events[2].Deleted {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// 取消后不再收到通知
+	cancel()
+	s.Create(ctx, GroupDefinition{Name: "b", MaxBytes: 1})
+	if len(events) != 3 {
+		t.Fatalf("event after cancel: %+v", events[3:])
+	}
+	if defs, _ := s.List(ctx); len(defs) != 1 || defs[0].Name != "b" {
+		t.Fatalf("list = %+v", defs)
+	}
+}
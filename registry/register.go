@@ -3,8 +3,10 @@ package registry
 //服务注册
 import (
 	"context"
+	"math/rand/v2"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
 )
 
@@ -20,51 +22,257 @@ var DefaultConfig = &Config{
 	DialTimeout: 5 * time.Second,
 }
 
+// State 表示节点在etcd中的注册状态
+type State int32
+
+const (
+	StateRegistered State = iota // 已注册，租约正常续期
+	StateLost                    // 租约丢失，正在重新注册
+	StateStopped                 // 已收到停止信号，注销完成
+)
+
+func (s State) String() string {
+	switch s {
+	case StateRegistered:
+		return "registered"
+	case StateLost:
+		return "lost"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+// StateHandler 注册状态变化时的回调，err为导致状态变化的原因（可为nil）
+type StateHandler func(state State, err error)
+
+// options 注册选项
+type options struct {
+	leaseTTL   int64         // 租约TTL（秒）
+	minBackoff time.Duration // 重新注册的初始退避时间
+	maxBackoff time.Duration // 重新注册的最大退避时间
+	onState    StateHandler
+	metadata   Metadata
+	etcd       *Config // nil表示使用DefaultConfig
+}
+
+// Option 定义注册选项函数类型
+type Option func(*options)
+
+// WithLeaseTTL 设置租约TTL（秒）
+func WithLeaseTTL(ttl int64) Option {
+	return func(o *options) {
+		o.leaseTTL = ttl
+	}
+}
+
+// WithBackoff 设置租约丢失后重新注册的退避区间
+func WithBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithMetadata 设置随注册记录一起写入的节点元信息
+func WithMetadata(md Metadata) Option {
+	return func(o *options) {
+		o.metadata = md
+	}
+}
+
+// WithStateHandler 设置注册状态变化回调
+func WithStateHandler(fn StateHandler) Option {
+	return func(o *options) {
+		o.onState = fn
+	}
+}
+
+// WithEtcdConfig 使用指定的etcd集群，而不是DefaultConfig
+func WithEtcdConfig(cfg Config) Option {
+	return func(o *options) {
+		o.etcd = &cfg
+	}
+}
+
 // Register 注册服务到etcd
-func Register(svcName, addr string, stopCh chan error) error {
+// 首次注册失败或租约丢失（etcd重启、长时间GC停顿等导致keepalive通道关闭）时，
+// 都会以带抖动的指数退避重新申请租约并写回key，直到stopCh被关闭；
+// 只有无法创建etcd客户端（配置错误）时返回错误。
+func Register(svcName, addr string, stopCh chan error, opts ...Option) error {
+	o := &options{
+		leaseTTL:   10, // 10秒TTL
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	etcdCfg := DefaultConfig
+	if o.etcd != nil {
+		etcdCfg = o.etcd
+	}
 	cli, err := clientv3.New(clientv3.Config{
-		Endpoints:   DefaultConfig.Endpoints,
-		DialTimeout: DefaultConfig.DialTimeout,
+		Endpoints:   etcdCfg.Endpoints,
+		DialTimeout: etcdCfg.DialTimeout,
 	})
 	if err != nil {
 		return err
 	}
 
-	// 创建租约
-	lease, err := cli.Grant(context.Background(), 10) // 10秒TTL
+	// 服务key的value保持为地址，旧版本节点可以照常读取；元信息写在单独的key下，与地址共用租约
+	meta, err := Endpoint{Addr: addr, Metadata: o.metadata}.Marshal()
 	if err != nil {
 		cli.Close()
 		return err
 	}
-
-	// 注册服务
-	key := "/services/" + svcName + "/" + addr
-	_, err = cli.Put(context.Background(), key, addr, clientv3.WithLease(lease.ID))
-	if err != nil {
-		cli.Close()
-		return err
+	kvs := []keyValue{
+		{key: ServicePrefix(svcName) + addr, value: addr},
+		{key: MetadataPrefix(svcName) + addr, value: meta},
 	}
 
-	// 保持租约活跃
-	keepAliveCh, err := cli.KeepAlive(context.Background(), lease.ID)
+	b := &etcdBackend{cli: cli, timeout: etcdCfg.DialTimeout}
+	go o.run(b, kvs, stopCh)
+	return nil
+}
+
+// keyValue 注册时写入的一个key
+type keyValue struct {
+	key, value string
+}
+
+// leaseBackend 注册需要的租约操作，由etcd实现，测试中可以替换
+type leaseBackend interface {
+	// register 申请租约、在同一事务中写入所有key并开启续期，续期停止时返回的通道被关闭
+	register(kvs []keyValue, ttl int64) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, error)
+	revoke(leaseID clientv3.LeaseID)
+	close()
+}
+
+// run 注册状态机：首次注册、监听租约、丢失后重新注册，直到stopCh被关闭
+func (o *options) run(b leaseBackend, kvs []keyValue, stopCh chan error) {
+	defer b.close()
+
+	key := kvs[0].key
+	leaseID, keepAliveCh, err := b.register(kvs, o.leaseTTL)
 	if err != nil {
-		cli.Close()
-		return err
+		logrus.Warnf("failed to register %s: %v", key, err)
+		o.notify(StateLost, err)
+		var stopped bool
+		if leaseID, keepAliveCh, stopped = o.reRegister(b, kvs, stopCh); stopped {
+			o.notify(StateStopped, nil)
+			return
+		}
 	}
+	o.notify(StateRegistered, nil)
 
-	// 监听停止信号
-	go func() {
-		for {
-			select {
-			case <-stopCh:
-				cli.Revoke(context.Background(), lease.ID)
-				cli.Close()
-				return
-			case <-keepAliveCh:
+	// 监听停止信号与租约状态
+	for {
+		select {
+		case <-stopCh:
+			b.revoke(leaseID)
+			o.notify(StateStopped, nil)
+			return
+		case _, ok := <-keepAliveCh:
+			if ok {
 				// 保持连接
+				continue
 			}
+			// keepalive通道关闭说明租约已失效，节点已从其他人的哈希环中消失
+			logrus.Warnf("lease for %s lost, re-registering", key)
+			o.notify(StateLost, nil)
+
+			var stopped bool
+			if leaseID, keepAliveCh, stopped = o.reRegister(b, kvs, stopCh); stopped {
+				o.notify(StateStopped, nil)
+				return
+			}
+			logrus.Infof("service %s re-registered", key)
+			o.notify(StateRegistered, nil)
 		}
-	}()
+	}
+}
 
-	return nil
+// etcdBackend 基于etcd客户端的租约操作
+type etcdBackend struct {
+	cli     *clientv3.Client
+	timeout time.Duration
+}
+
+// register 申请租约、写入key并开启续期
+func (b *etcdBackend) register(kvs []keyValue, ttl int64) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+
+	// 创建租约
+	lease, err := b.cli.Grant(ctx, ttl)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	// 注册服务，地址和元信息同时可见
+	ops := make([]clientv3.Op, 0, len(kvs))
+	for _, kv := range kvs {
+		ops = append(ops, clientv3.OpPut(kv.key, kv.value, clientv3.WithLease(lease.ID)))
+	}
+	if _, err = b.cli.Txn(ctx).Then(ops...).Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	// 保持租约活跃，续期不能绑定在带超时的ctx上
+	keepAliveCh, err := b.cli.KeepAlive(context.Background(), lease.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	return lease.ID, keepAliveCh, nil
+}
+
+func (b *etcdBackend) revoke(leaseID clientv3.LeaseID) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.timeout)
+	defer cancel()
+	b.cli.Revoke(ctx, leaseID)
+}
+
+func (b *etcdBackend) close() {
+	b.cli.Close()
+}
+
+// reRegister 以带抖动的指数退避重新注册，直到成功或stopCh被关闭（此时stopped为true）
+func (o *options) reRegister(b leaseBackend, kvs []keyValue, stopCh chan error) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, bool) {
+	key := kvs[0].key
+	backoff := o.minBackoff
+	wait := jitter(backoff)
+	for {
+		select {
+		case <-stopCh:
+			return 0, nil, true
+		case <-time.After(wait):
+		}
+
+		leaseID, keepAliveCh, err := b.register(kvs, o.leaseTTL)
+		if err == nil {
+			return leaseID, keepAliveCh, false
+		}
+
+		backoff = min(backoff*2, o.maxBackoff)
+		wait = jitter(backoff)
+		logrus.Warnf("failed to re-register %s, retry in %v: %v", key, wait, err)
+		o.notify(StateLost, err)
+	}
+}
+
+// jitter 在[d/2, d]之间随机取值，etcd恢复后各节点不会同时重新注册
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func (o *options) notify(state State, err error) {
+	if o.onState != nil {
+		o.onState(state, err)
+	}
 }
@@ -0,0 +1,159 @@
+package registry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeBackend 按脚本返回注册结果的租约后端
+type fakeBackend struct {
+	mu       sync.Mutex
+	failures int // 接下来多少次register失败
+	attempts int
+	keep     chan *clientv3.LeaseKeepAliveResponse
+	revoked  []clientv3.LeaseID
+	closed   bool
+}
+
+func (f *fakeBackend) register(kvs []keyValue, ttl int64) (clientv3.LeaseID, <-chan *clientv3.LeaseKeepAliveResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.failures > 0 {
+		f.failures--
+		return 0, nil, errors.New("etcd unavailable")
+	}
+	f.keep = make(chan *clientv3.LeaseKeepAliveResponse)
+	return clientv3.LeaseID(f.attempts), f.keep, nil
+}
+
+func (f *fakeBackend) revoke(id clientv3.LeaseID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked = append(f.revoked, id)
+}
+
+func (f *fakeBackend) close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+// loseLease 模拟租约丢失
+func (f *fakeBackend) loseLease() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	close(f.keep)
+}
+
+// stateRecorder 记录状态变化
+type stateRecorder struct {
+	ch chan State
+}
+
+func newStateRecorder() *stateRecorder {
+	return &stateRecorder{ch: make(chan State, 16)}
+}
+
+func (r *stateRecorder) handle(state State, err error) {
+	r.ch <- state
+}
+
+func (r *stateRecorder) expect(t *testing.T, want ...State) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case got := <-r.ch:
+			if got != w {
+				t.Fatalf("state = %s, want %s", got, w)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for state %s", w)
+		}
+	}
+}
+
+var testKVs = []keyValue{{key: "/services/s/a", value: "a"}, {key: "/metadata/s/a", value: `{"addr":"a"}`}}
+
+func testOptions(r *stateRecorder) *options {
+	return &options{leaseTTL: 1, minBackoff: time.Millisecond, maxBackoff: 4 * time.Millisecond, onState: r.handle}
+}
+
+// TestRun_InitialFailureRetried 首次注册失败后在后台重试，直到成功
+func TestRun_InitialFailureRetried(t *testing.T) {
+	r := newStateRecorder()
+	b := &fakeBackend{failures: 3}
+	stopCh := make(chan error)
+	go testOptions(r).run(b, testKVs, stopCh)
+
+	r.expect(t, StateLost, StateLost, StateLost, StateRegistered)
+	close(stopCh)
+	r.expect(t, StateStopped)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.attempts != 4 || len(b.revoked) != 1 || !b.closed {
+		t.Fatalf("attempts=%d revoked=%v closed=%v", b.attempts, b.revoked, b.closed)
+	}
+}
+
+// TestRun_LeaseLost 租约丢失后重新注册
+func TestRun_LeaseLost(t *testing.T) {
+	r := newStateRecorder()
+	b := &fakeBackend{}
+	stopCh := make(chan error)
+	go testOptions(r).run(b, testKVs, stopCh)
+	r.expect(t, StateRegistered)
+
+	b.mu.Lock()
+	b.failures = 1
+	b.mu.Unlock()
+	b.loseLease()
+	r.expect(t, StateLost, StateLost, StateRegistered)
+
+	close(stopCh)
+	r.expect(t, StateStopped)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.revoked) != 1 || b.revoked[0] != clientv3.LeaseID(b.attempts) {
+		t.Fatalf("revoked %v, want the current lease %d", b.revoked, b.attempts)
+	}
+}
+
+// TestRun_StopWhileRetrying 重试期间停止不会撤销租约
+func TestRun_StopWhileRetrying(t *testing.T) {
+	r := newStateRecorder()
+	b := &fakeBackend{failures: 1 << 30}
+	o := testOptions(r)
+	o.minBackoff, o.maxBackoff = time.Hour, time.Hour
+	stopCh := make(chan error)
+	go o.run(b, testKVs, stopCh)
+
+	r.expect(t, StateLost)
+	close(stopCh)
+	r.expect(t, StateStopped)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.revoked) != 0 || !b.closed {
+		t.Fatalf("revoked=%v closed=%v", b.revoked, b.closed)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	d := 100 * time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for range 100 {
+		j := jitter(d)
+		if j < d/2 || j > d {
+			t.Fatalf("jitter(%v) = %v", d, j)
+		}
+		seen[j] = true
+	}
+	if len(seen) < 2 {
+		t.Fatal("jitter returned a constant delay")
+	}
+}
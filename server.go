@@ -3,20 +3,23 @@ package blockcache
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"crypto/tls"
-
 	pb "github.com/crypt0walker/BlockCache/pb"
 	"github.com/crypt0walker/BlockCache/registry"
 	"github.com/sirupsen/logrus"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 )
 
 // Server 定义缓存服务器
@@ -29,6 +32,11 @@ type Server struct {
 	etcdCli    *clientv3.Client // etcd客户端
 	stopCh     chan error       // 停止信号
 	opts       *ServerOptions   // 服务器选项
+	regState   int32            // 注册状态，registry.State
+	certs      *CertReloader    // TLS证书热加载，nil表示未启用TLS
+	metricsSrv *http.Server     // /metrics HTTP服务，nil表示未开启
+	httpSrv    *http.Server     // HTTP/JSON接口，nil表示未开启
+	groupReg   *groupRegistry   // 组定义注册表，nil表示未开启
 }
 
 // ServerOptions 服务器配置选项
@@ -39,6 +47,26 @@ type ServerOptions struct {
 	TLS           bool          // 是否启用TLS
 	CertFile      string        // 证书文件
 	KeyFile       string        // 密钥文件
+	// TLSConfig 完整的TLS/mTLS配置（CA、客户端证书校验、SAN、热加载），优先于TLS/CertFile/KeyFile
+	TLSConfig *TLSConfig
+	// Auth 认证与按组授权，nil表示不做认证
+	Auth *AuthOptions
+	// 用户拦截器，在panic恢复和认证之后执行
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
+	// OnRegistrationChange 注册状态变化回调（租约丢失、重新注册成功等）
+	OnRegistrationChange registry.StateHandler
+	Metadata             registry.Metadata // 注册到etcd的节点元信息
+	// TracerProvider 为每个RPC创建span，父span来自调用方，nil表示不追踪
+	TracerProvider trace.TracerProvider
+	// MetricsAddr 非空时在该地址以HTTP提供/metrics
+	MetricsAddr string
+	// HTTPAddr 非空时在该地址提供HTTP/JSON接口
+	HTTPAddr string
+	// Picker 供/metrics和HTTP管理接口读取节点与哈希环状态，可为nil
+	Picker *ClientPicker
+	// GroupRegistry 非nil时按发现后端中的组定义创建和销毁组
+	GroupRegistry *GroupRegistryOptions
 }
 
 // DefaultServerOptions 默认配置
@@ -74,9 +102,32 @@ func WithTLS(certFile, keyFile string) ServerOption {
 	}
 }
 
+// WithServerTLS 使用完整的TLS配置，可开启mTLS和证书热加载
+func WithServerTLS(cfg TLSConfig) ServerOption {
+	return func(o *ServerOptions) {
+		o.TLSConfig = &cfg
+	}
+}
+
+// WithRegistrationHandler 设置注册状态变化回调
+func WithRegistrationHandler(fn registry.StateHandler) ServerOption {
+	return func(o *ServerOptions) {
+		o.OnRegistrationChange = fn
+	}
+}
+
+// WithMetadata 设置节点元信息（权重、可用区、版本、特性）
+func WithMetadata(md registry.Metadata) ServerOption {
+	return func(o *ServerOptions) {
+		o.Metadata = md
+	}
+}
+
 // NewServer 创建新的服务器实例
 func NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
-	options := DefaultServerOptions
+	// 拷贝一份默认配置，避免选项函数修改全局默认值
+	defaults := *DefaultServerOptions
+	options := &defaults
 	for _, opt := range opts {
 		opt(options)
 	}
@@ -94,13 +145,20 @@ func NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
 	var serverOpts []grpc.ServerOption
 	serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(options.MaxMsgSize))
 
-	if options.TLS {
-		creds, err := loadTLSCredentials(options.CertFile, options.KeyFile)
+	tlsConfig := options.TLSConfig
+	if tlsConfig == nil && options.TLS {
+		tlsConfig = &TLSConfig{CertFile: options.CertFile, KeyFile: options.KeyFile}
+	}
+	var certs *CertReloader
+	if tlsConfig != nil {
+		certs, err = NewCertReloader(*tlsConfig)
 		if err != nil {
+			etcdCli.Close()
 			return nil, fmt.Errorf("failed to load TLS credentials: %v", err)
 		}
-		serverOpts = append(serverOpts, grpc.Creds(creds))
+		serverOpts = append(serverOpts, grpc.Creds(certs.ServerCredentials()))
 	}
+	serverOpts = append(serverOpts, serverInterceptors(options)...)
 	//这段代码执行完后，内存里就有了一个配置齐全的 Server 对象：
 	// 它知道自己是谁 (svcName)。
 	// 它有地方存数据 (groups)。
@@ -115,6 +173,16 @@ func NewServer(addr, svcName string, opts ...ServerOption) (*Server, error) {
 		etcdCli:    etcdCli,
 		stopCh:     make(chan error),
 		opts:       options,
+		regState:   int32(registry.StateStopped),
+		certs:      certs,
+	}
+
+	if options.GroupRegistry != nil {
+		store := options.GroupRegistry.Store
+		if store == nil {
+			store = registry.NewEtcdGroupStore(etcdCli, svcName)
+		}
+		srv.groupReg = newGroupRegistry(*options.GroupRegistry, store)
 	}
 
 	// 注册服务
@@ -136,27 +204,75 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	// 注册到etcd
-	stopCh := make(chan error)
-	go func() {
-		if err := registry.Register(s.svcName, s.addr, stopCh); err != nil {
-			logrus.Errorf("failed to register service: %v", err)
-			close(stopCh)
-			return
+	// 先按已有定义建好所有组，再注册让其他节点把请求路由过来
+	if s.groupReg != nil {
+		if err := s.groupReg.start(); err != nil {
+			lis.Close()
+			return err
 		}
-	}()
+	}
+
+	// 注册到etcd，失败时在后台重试；Stop关闭s.stopCh时会撤销租约
+	if err := registry.Register(s.svcName, s.addr, s.stopCh,
+		registry.WithStateHandler(s.onRegistrationChange),
+		registry.WithMetadata(s.opts.Metadata),
+		registry.WithEtcdConfig(registry.Config{
+			Endpoints:   s.opts.EtcdEndpoints,
+			DialTimeout: s.opts.DialTimeout,
+		})); err != nil {
+		lis.Close()
+		return fmt.Errorf("failed to register service: %w", err)
+	}
+
+	if s.opts.MetricsAddr != "" {
+		if err := s.startMetrics(); err != nil {
+			lis.Close()
+			return err
+		}
+	}
+	if s.opts.HTTPAddr != "" {
+		if err := s.startHTTP(); err != nil {
+			lis.Close()
+			return err
+		}
+	}
 
 	logrus.Infof("Server starting at %s", s.addr)
 	return s.grpcServer.Serve(lis)
 }
 
+// onRegistrationChange 记录注册状态并转发给用户回调
+func (s *Server) onRegistrationChange(state registry.State, err error) {
+	atomic.StoreInt32(&s.regState, int32(state))
+	if s.opts.OnRegistrationChange != nil {
+		s.opts.OnRegistrationChange(state, err)
+	}
+}
+
+// RegistrationState 返回当前在etcd中的注册状态
+func (s *Server) RegistrationState() registry.State {
+	return registry.State(atomic.LoadInt32(&s.regState))
+}
+
 // Stop 停止服务器
 func (s *Server) Stop() {
 	close(s.stopCh)
 	s.grpcServer.GracefulStop()
+	if s.groupReg != nil {
+		s.groupReg.stop()
+	}
+	if s.metricsSrv != nil {
+		s.metricsSrv.Close()
+	}
+	if s.httpSrv != nil {
+		s.httpSrv.Close()
+	}
 	if s.etcdCli != nil {
 		s.etcdCli.Close()
 	}
+	if s.certs != nil {
+		s.certs.Close()
+	}
 }
 
 //context在本代码中有两个作用：
@@ -165,34 +281,30 @@ func (s *Server) Stop() {
 
 // Get 实现Cache服务的Get方法
 func (s *Server) Get(ctx context.Context, req *pb.Request) (*pb.ResponseForGet, error) {
-	group := GetGroup(req.Group)
-	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
+	// 其他节点转发来的请求在本地处理，不再继续转发
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(fromPeerMetadataKey)) > 0 {
+		ctx = context.WithValue(ctx, "from_peer", true)
 	}
 
-	view, err := group.Get(ctx, req.Key)
+	value, err := s.getValue(ctx, req.Group, req.Key)
 	if err != nil {
-		return nil, err
+		return nil, toStatus(err)
 	}
 
-	return &pb.ResponseForGet{Value: view.ByteSlice()}, nil
+	return &pb.ResponseForGet{Value: value}, nil
 }
 
 // Set 实现Cache服务的Set方法
 func (s *Server) Set(ctx context.Context, req *pb.Request) (*pb.ResponseForGet, error) {
-	group := GetGroup(req.Group)
-	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
-	}
-
 	// 从 context 中获取标记，如果没有则创建新的 context
 	fromPeer := ctx.Value("from_peer")
 	if fromPeer == nil {
 		ctx = context.WithValue(ctx, "from_peer", true)
 	}
 
-	if err := group.Set(ctx, req.Key, req.Value); err != nil {
-		return nil, err
+	ttl := time.Duration(req.TtlMs) * time.Millisecond
+	if err := s.setValue(ctx, req.Group, req.Key, req.Value, ttl); err != nil {
+		return nil, toStatus(err)
 	}
 
 	return &pb.ResponseForGet{Value: req.Value}, nil
@@ -200,22 +312,166 @@ func (s *Server) Set(ctx context.Context, req *pb.Request) (*pb.ResponseForGet,
 
 // Delete 实现Cache服务的Delete方法
 func (s *Server) Delete(ctx context.Context, req *pb.Request) (*pb.ResponseForDelete, error) {
+	err := s.deleteValue(ctx, req.Group, req.Key)
+	return &pb.ResponseForDelete{Value: err == nil}, toStatus(err)
+}
+
+// getValue gRPC和HTTP接口共用的读取路径
+func (s *Server) getValue(ctx context.Context, groupName, key string) ([]byte, error) {
+	group := GetGroup(groupName)
+	if group == nil {
+		return nil, groupNotFound(groupName)
+	}
+	view, err := group.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return view.ByteSlice(), nil
+}
+
+// setValue gRPC和HTTP接口共用的写入路径，ctx中带有from_peer时不再同步给其他节点
+func (s *Server) setValue(ctx context.Context, groupName, key string, value []byte, ttl time.Duration) error {
+	group := GetGroup(groupName)
+	if group == nil {
+		return groupNotFound(groupName)
+	}
+	return group.SetWithTTL(ctx, key, value, ttl)
+}
+
+// deleteValue gRPC和HTTP接口共用的删除路径
+func (s *Server) deleteValue(ctx context.Context, groupName, key string) error {
+	group := GetGroup(groupName)
+	if group == nil {
+		return groupNotFound(groupName)
+	}
+	return group.Delete(ctx, key)
+}
+
+// Handoff 接收其他节点在哈希环变化时迁移过来的key
+func (s *Server) Handoff(stream pb.BlockCache_HandoffServer) error {
+	var accepted int64
+	for {
+		entry, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&pb.HandoffResponse{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+
+		// 流建立时只做了认证，每条数据按所属的组授权
+		if err := s.authorize(stream.Context(), entry.Group, OpHandoff); err != nil {
+			return err
+		}
+
+		group := GetGroup(entry.Group)
+		if group == nil {
+			// 本节点尚未创建该组，跳过而不是中断整个流
+			continue
+		}
+		ttl := time.Duration(entry.TtlMs) * time.Millisecond
+		if group.acceptHandoff(stream.Context(), entry.Key, entry.Value, ttl) {
+			accepted++
+		}
+	}
+}
+
+// ListGroups 实现Cache服务的ListGroups方法
+func (s *Server) ListGroups(ctx context.Context, req *pb.ListGroupsRequest) (*pb.ListGroupsResponse, error) {
+	names := ListGroups()
+	sort.Strings(names)
+	return &pb.ListGroupsResponse{Groups: names}, nil
+}
+
+// Stats 实现Cache服务的Stats方法
+func (s *Server) Stats(ctx context.Context, req *pb.StatsRequest) (*pb.StatsResponse, error) {
 	group := GetGroup(req.Group)
 	if group == nil {
-		return nil, fmt.Errorf("group %s not found", req.Group)
+		return nil, toStatus(groupNotFound(req.Group))
 	}
+	stats := group.Stats()
+	cache := group.mainCache.StoreStats()
+	return &pb.StatsResponse{
+		Group:           req.Group,
+		Loads:           stats.Loads,
+		LocalHits:       stats.LocalHits,
+		LocalMisses:     stats.LocalMisses,
+		PeerHits:        stats.PeerHits,
+		PeerMisses:      stats.PeerMisses,
+		LoaderHits:      stats.LoaderHits,
+		LoaderErrors:    stats.LoaderErrors,
+		PeerRetries:     stats.PeerRetries,
+		Hedges:          stats.Hedges,
+		HedgeWins:       stats.HedgeWins,
+		Items:           int64(cache.Items),
+		Bytes:           cache.Bytes,
+		MaxBytes:        cache.MaxBytes,
+		Evictions:       cache.Evictions,
+		Expirations:     cache.Expirations,
+		LocalHitLatency: latencySummary(stats.Latency.LocalHit),
+		PeerLatency:     latencySummary(stats.Latency.Peer),
+		LoaderLatency:   latencySummary(stats.Latency.Loader),
+		CacheType:       string(group.mainCache.CacheType()),
+	}, nil
+}
 
-	err := group.Delete(ctx, req.Key)
-	return &pb.ResponseForDelete{Value: err == nil}, err
+func latencySummary(s LatencySnapshot) *pb.LatencySummary {
+	return &pb.LatencySummary{
+		Count:  s.Count,
+		MeanNs: int64(s.Mean()),
+		P50Ns:  int64(s.Quantile(0.5)),
+		P99Ns:  int64(s.Quantile(0.99)),
+		MaxNs:  int64(s.Max),
+	}
 }
 
-// loadTLSCredentials 加载TLS证书
-func loadTLSCredentials(certFile, keyFile string) (credentials.TransportCredentials, error) {
-	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+// Peers 实现Cache服务的Peers方法
+func (s *Server) Peers(ctx context.Context, req *pb.PeersRequest) (*pb.PeersResponse, error) {
+	picker, err := s.picker()
 	if err != nil {
 		return nil, err
 	}
-	return credentials.NewTLS(&tls.Config{
-		Certificates: []tls.Certificate{cert},
-	}), nil
+	states := picker.BreakerStates()
+	resp := &pb.PeersResponse{Self: picker.selfAddr}
+	for _, ep := range picker.Endpoints() {
+		resp.Peers = append(resp.Peers, &pb.PeerInfo{
+			Addr:    ep.Addr,
+			Breaker: states[ep.Addr].String(),
+			Weight:  int32(ep.Weight),
+			Zone:    ep.Zone,
+			Version: ep.Version,
+		})
+	}
+	return resp, nil
+}
+
+// Dump 实现Cache服务的Dump方法，导出的剩余存活时间可以直接用于Handoff导入
+func (s *Server) Dump(req *pb.DumpRequest, stream pb.BlockCache_DumpServer) error {
+	if err := s.authorize(stream.Context(), req.Group, OpAdmin); err != nil {
+		return err
+	}
+	group := GetGroup(req.Group)
+	if group == nil {
+		return toStatus(groupNotFound(req.Group))
+	}
+	var sendErr error
+	group.mainCache.Range(func(key string, value ByteView, ttl time.Duration) bool {
+		sendErr = stream.Send(&pb.HandoffEntry{
+			Group: req.Group,
+			Key:   key,
+			Value: value.ByteSlice(),
+			TtlMs: ttlMillis(ttl),
+		})
+		return sendErr == nil
+	})
+	return sendErr
+}
+
+// authorize 按ACL检查流式调用中单条数据的权限，未开启认证时总是放行
+func (s *Server) authorize(ctx context.Context, group string, op Operation) error {
+	if s.opts == nil || s.opts.Auth == nil {
+		return nil
+	}
+	identity, _ := IdentityFromContext(ctx)
+	return s.opts.Auth.authorize(identity, group, op)
 }
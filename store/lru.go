@@ -12,6 +12,8 @@ type lruCache struct {
 	mu          sync.RWMutex
 	maxBytes    int64                    // 最大内存容量
 	usedBytes   int64                    // 当前已使用内存
+	evictions   int64                    // 因容量不足被淘汰的条目数
+	expirations int64                    // 因过期被清理的条目数
 	ll          *list.List               // 双向链表，存储缓存数据的访问顺序
 	items       map[string]*list.Element // 键到双向链表节点的映射，后端是最新访问的节点
 	expiredTime map[string]time.Time     // 键到过期时间的映射
@@ -77,9 +79,9 @@ func (c *lruCache) Get(key string) (value Value, ok bool) {
 			//已过期，删除该元素
 			//锁升级
 			c.mu.RUnlock()
-			//Delete方法会加写锁
+			//deleteIfExpired方法会加写锁
 			//异步删除，避免阻塞
-			go c.Delete(key)
+			go c.deleteIfExpired(key)
 			return nil, false
 		}
 	}
@@ -206,6 +208,7 @@ func (c *lruCache) removeOldest() {
 		elem := c.ll.Front()
 		if elem != nil {
 			c.removeElement(elem)
+			c.evictions++
 		}
 	}
 }
@@ -222,6 +225,7 @@ func (c *lruCache) deleteExpired() {
 		if now.After(expTime) {
 			if elem, ok := c.items[key]; ok {
 				c.removeElement(elem)
+				c.expirations++
 			}
 		}
 
@@ -233,6 +237,41 @@ func (c *lruCache) deleteExpired() {
 	}
 }
 
+// deleteIfExpired 删除读取时发现已过期的key，重新加锁后再确认一次，避免误删刚写入的新值
+func (c *lruCache) deleteIfExpired(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expTime, ok := c.expiredTime[key]
+	if !ok || !time.Now().After(expTime) {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		c.removeElement(elem)
+		c.expirations++
+	}
+}
+
+// Stats 返回容量与淘汰统计
+func (c *lruCache) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Stats{
+		Items:       c.ll.Len(),
+		Bytes:       c.usedBytes,
+		MaxBytes:    c.maxBytes,
+		Evictions:   c.evictions,
+		Expirations: c.expirations,
+	}
+}
+
+// SetMaxBytes 调整容量上限，缩小时立即淘汰最久未访问的条目
+func (c *lruCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxBytes = maxBytes
+	c.removeOldest()
+}
+
 // Clear 清空缓存
 func (c *lruCache) Clear() {
 	c.mu.Lock()
@@ -258,3 +297,33 @@ func (c *lruCache) Len() int {
 	defer c.mu.RUnlock()
 	return c.ll.Len()
 }
+
+// Range 遍历缓存项，先在读锁下拍快照，再在锁外回调，回调中可以安全地访问缓存
+func (c *lruCache) Range(fn func(key string, value Value, ttl time.Duration) bool) {
+	type item struct {
+		key   string
+		value Value
+		ttl   time.Duration
+	}
+
+	c.mu.RLock()
+	now := time.Now()
+	items := make([]item, 0, c.ll.Len())
+	for e := c.ll.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*lruEntry)
+		var ttl time.Duration
+		if expireTime, ok := c.expiredTime[entry.key]; ok {
+			if ttl = expireTime.Sub(now); ttl <= 0 {
+				continue // 已过期，跳过
+			}
+		}
+		items = append(items, item{key: entry.key, value: entry.value, ttl: ttl})
+	}
+	c.mu.RUnlock()
+
+	for _, it := range items {
+		if !fn(it.key, it.value, it.ttl) {
+			return
+		}
+	}
+}
@@ -208,3 +208,94 @@ func TestLRU_SetWithExpire_Overlap(t *testing.T) {
 		t.Fatal("key should survive after being updated with no expiration")
 	}
 }
+
+// TestLRU_Range 遍历时跳过已过期的条目，并返回剩余TTL
+func TestLRU_Range(t *testing.T) {
+	cache := newLRUCache(Options{MaxBytes: 100})
+
+	cache.Set("forever", String("v1"))
+	cache.SetWithExpiration("short", String("v2"), 10*time.Millisecond)
+	cache.SetWithExpiration("long", String("v3"), time.Hour)
+	time.Sleep(20 * time.Millisecond)
+
+	seen := make(map[string]time.Duration)
+	cache.Range(func(key string, value Value, ttl time.Duration) bool {
+		seen[key] = ttl
+		return true
+	})
+
+	if _, ok := seen["short"]; ok {
+		t.Fatal("expired key should be skipped")
+	}
+	if ttl, ok := seen["forever"]; !ok || ttl != 0 {
+		t.Fatalf("forever: ttl %v, present %v", ttl, ok)
+	}
+	if ttl := seen["long"]; ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("long: unexpected ttl %v", ttl)
+	}
+
+	// 返回false时停止遍历
+	n := 0
+	cache.Range(func(string, Value, time.Duration) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("range should stop after first item, visited %d", n)
+	}
+}
+
+// TestLRU_Stats 统计容量占用、淘汰数和过期清理数
+func TestLRU_Stats(t *testing.T) {
+	cache := newLRUCache(Options{MaxBytes: 10, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	cache.Set("a", String("1234")) // 5字节
+	cache.Set("b", String("1234")) // 10字节
+	cache.Set("c", String("1234")) // 超出上限，淘汰a
+
+	stats := cache.Stats()
+	if stats.Items != 2 || stats.Bytes != 10 || stats.MaxBytes != 10 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats after eviction: %+v", stats)
+	}
+
+	cache.SetWithExpiration("b", String("1234"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	cache.mu.Lock()
+	cache.deleteExpired()
+	cache.mu.Unlock()
+
+	stats = cache.Stats()
+	if stats.Items != 1 || stats.Expirations != 1 || stats.Evictions != 1 {
+		t.Fatalf("unexpected stats after expiration: %+v", stats)
+	}
+}
+
+// TestLRU_SetMaxBytes 缩小容量时按LRU顺序淘汰，扩大后可以继续写入
+func TestLRU_SetMaxBytes(t *testing.T) {
+	cache := newLRUCache(Options{MaxBytes: 20, CleanupInterval: time.Hour})
+	defer cache.Close()
+
+	for _, k := range []string{"a", "b", "c", "d"} {
+		cache.Set(k, String("1234")) // 每项5字节
+	}
+	cache.Get("a") // a变为最近访问
+
+	cache.SetMaxBytes(10)
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("recently used key a evicted")
+	}
+	if _, ok := cache.Get("d"); !ok {
+		t.Fatal("key d evicted")
+	}
+	if stats := cache.Stats(); stats.Items != 2 || stats.MaxBytes != 10 || stats.Evictions != 2 {
+		t.Fatalf("unexpected stats after shrink: %+v", stats)
+	}
+
+	cache.SetMaxBytes(20)
+	cache.Set("e", String("1234"))
+	cache.Set("f", String("1234"))
+	if stats := cache.Stats(); stats.Items != 4 || stats.Evictions != 2 {
+		t.Fatalf("unexpected stats after grow: %+v", stats)
+	}
+}
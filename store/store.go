@@ -16,6 +16,21 @@ type Store interface {
 	Clear()
 	Len() int
 	Close()
+	// Range 遍历未过期的缓存项，ttl为剩余存活时间（0表示不过期），fn返回false时停止
+	Range(fn func(key string, value Value, ttl time.Duration) bool)
+	// Stats 返回容量与淘汰统计
+	Stats() Stats
+	// SetMaxBytes 调整容量上限，缩小时按LRU顺序淘汰直到不超过新上限，0表示不限制
+	SetMaxBytes(maxBytes int64)
+}
+
+// Stats 存储层统计
+type Stats struct {
+	Items       int   `json:"items"`       // 当前条目数
+	Bytes       int64 `json:"bytes"`       // 当前占用的字节数（key+value）
+	MaxBytes    int64 `json:"maxBytes"`    // 容量上限，0表示不限制
+	Evictions   int64 `json:"evictions"`   // 因容量不足被淘汰的条目数
+	Expirations int64 `json:"expirations"` // 因过期被清理的条目数
 }
 
 // CacheType 缓存类型
@@ -58,3 +73,13 @@ func NewStore(cacheType CacheType, opts Options) Store {
 		return newLRUCache(opts)
 	}
 }
+
+// Implementation 返回NewStore实际创建的存储实现，实现相同的类型之间切换不需要迁移数据
+func Implementation(cacheType CacheType) CacheType {
+	switch cacheType {
+	case LRU2:
+		return LRU // LRU2尚未单独实现，与LRU共用同一个存储
+	default:
+		return LRU
+	}
+}
@@ -0,0 +1,259 @@
+package blockcache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/credentials"
+)
+
+// 节点间TLS/mTLS
+// 同一份配置同时用于Server（校验客户端证书）和Client（出示客户端证书、校验服务端证书）。
+// 证书和CA都从磁盘加载，CertReloader会定期检查文件变化并原子替换，
+// 证书轮换时不需要重启节点，已建立的连接不受影响，新握手使用新证书。
+
+// TLSConfig 节点间TLS配置
+type TLSConfig struct {
+	CertFile string // 本节点证书，作为服务端证书，mTLS时同时作为客户端证书
+	KeyFile  string // 本节点私钥
+	CAFile   string // 用于校验对端证书的CA，空表示使用系统根证书
+	// ServerName 客户端校验服务端证书时使用的名称，空表示使用拨号地址的主机部分
+	ServerName string
+	// RequireClientCert 服务端是否要求并校验客户端证书（mTLS）
+	RequireClientCert bool
+	// AllowedSANs 对端证书必须包含其中之一（DNS名、IP、URI或邮箱），空表示只校验证书链
+	AllowedSANs []string
+	// ReloadInterval 检查证书文件变化的周期，0表示不自动热加载（仍可手动调用Reload）
+	ReloadInterval time.Duration
+}
+
+// certBundle 某一时刻加载的证书和CA
+type certBundle struct {
+	cert *tls.Certificate
+	pool *x509.CertPool // nil表示使用系统根证书
+}
+
+// CertReloader 从磁盘加载证书并支持热更新，可以在多个Server/Client间共享
+type CertReloader struct {
+	cfg     TLSConfig
+	current atomic.Pointer[certBundle]
+	// 上次加载时各文件的修改时间
+	modTimes map[string]time.Time
+	mu       sync.Mutex
+	stopCh   chan struct{}
+	once     sync.Once
+}
+
+// NewCertReloader 加载证书，ReloadInterval>0时在后台定期检查文件变化
+func NewCertReloader(cfg TLSConfig) (*CertReloader, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("cert file and key file must be set together")
+	}
+	r := &CertReloader{
+		cfg:      cfg,
+		modTimes: make(map[string]time.Time),
+		stopCh:   make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	if cfg.ReloadInterval > 0 {
+		go r.watch()
+	}
+	return r, nil
+}
+
+// Reload 重新从磁盘加载证书和CA，失败时保留当前证书
+func (r *CertReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *CertReloader) reloadLocked() error {
+	bundle := &certBundle{}
+	if r.cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load key pair: %w", err)
+		}
+		bundle.cert = &cert
+	}
+	if r.cfg.CAFile != "" {
+		pem, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file: %w", err)
+		}
+		bundle.pool = x509.NewCertPool()
+		if !bundle.pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificates in CA file %s", r.cfg.CAFile)
+		}
+	}
+
+	for _, file := range r.files() {
+		if info, err := os.Stat(file); err == nil {
+			r.modTimes[file] = info.ModTime()
+		}
+	}
+	r.current.Store(bundle)
+	return nil
+}
+
+// files 需要监视的文件
+func (r *CertReloader) files() []string {
+	var files []string
+	for _, f := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// changed 文件修改时间与上次加载时不同
+func (r *CertReloader) changed() bool {
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch 定期检查文件变化
+func (r *CertReloader) watch() {
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.changed() {
+				// 证书和私钥可能不是同时写完的，加载失败时下个周期再试
+				if err := r.reloadLocked(); err != nil {
+					logrus.Warnf("failed to reload TLS certificates: %v", err)
+				} else {
+					logrus.Infof("TLS certificates reloaded from %s", r.cfg.CertFile)
+				}
+			}
+			r.mu.Unlock()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// Close 停止后台检查
+func (r *CertReloader) Close() error {
+	r.once.Do(func() { close(r.stopCh) })
+	return nil
+}
+
+// ServerCredentials 返回服务端的gRPC传输凭证
+func (r *CertReloader) ServerCredentials() credentials.TransportCredentials {
+	return credentials.NewTLS(r.ServerTLSConfig())
+}
+
+// ServerTLSConfig 返回服务端的TLS配置，HTTP接口和/metrics与gRPC使用同一份证书和客户端校验
+func (r *CertReloader) ServerTLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.certificate()
+		},
+	}
+	if r.cfg.RequireClientCert {
+		// 证书链由verify使用当前CA校验，这样CA轮换也能热加载
+		cfg.ClientAuth = tls.RequireAnyClientCert
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			return r.verify(cs.PeerCertificates, "", x509.ExtKeyUsageClientAuth)
+		}
+	}
+	return cfg
+}
+
+// ClientCredentials 返回客户端的gRPC传输凭证，配置了证书时自动出示（mTLS）
+func (r *CertReloader) ClientCredentials() credentials.TransportCredentials {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: r.cfg.ServerName,
+		// 标准校验使用固定的RootCAs，无法热加载，改由VerifyConnection用当前CA校验
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return r.verify(cs.PeerCertificates, cs.ServerName, x509.ExtKeyUsageServerAuth)
+		},
+	}
+	if r.cfg.CertFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return r.certificate()
+		}
+	}
+	return credentials.NewTLS(cfg)
+}
+
+func (r *CertReloader) certificate() (*tls.Certificate, error) {
+	bundle := r.current.Load()
+	if bundle.cert == nil {
+		return nil, errors.New("no certificate configured")
+	}
+	return bundle.cert, nil
+}
+
+// verify 用当前CA校验对端证书链，并检查主机名和SAN
+func (r *CertReloader) verify(certs []*x509.Certificate, host string, usage x509.ExtKeyUsage) error {
+	if len(certs) == 0 {
+		return errors.New("peer presented no certificate")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         r.current.Load().pool,
+		Intermediates: x509.NewCertPool(),
+		DNSName:       host,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	}
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return fmt.Errorf("failed to verify peer certificate: %w", err)
+	}
+	if len(r.cfg.AllowedSANs) > 0 && !matchSAN(certs[0], r.cfg.AllowedSANs) {
+		return fmt.Errorf("peer certificate SANs do not match any of %v", r.cfg.AllowedSANs)
+	}
+	return nil
+}
+
+// certSANs 证书中的所有SAN
+func certSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	sans = append(sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// matchSAN 证书是否包含allowed中的任意一个SAN
+func matchSAN(cert *x509.Certificate, allowed []string) bool {
+	for _, san := range certSANs(cert) {
+		for _, a := range allowed {
+			if san == a {
+				return true
+			}
+		}
+	}
+	return false
+}
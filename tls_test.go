@@ -0,0 +1,229 @@
+package blockcache
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// testCA 测试用的自签名CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "blockcache-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发同时可用于服务端和客户端的证书，返回PEM格式的证书和私钥
+func (ca *testCA) issue(t *testing.T, serial int64, dnsName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{dnsName},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeTLSFiles 把证书、私钥和CA写入临时目录，返回对应的TLSConfig
+func writeTLSFiles(t *testing.T, dir string, ca *testCA, certPEM, keyPEM []byte) TLSConfig {
+	t.Helper()
+	cfg := TLSConfig{
+		CertFile: filepath.Join(dir, "node.crt"),
+		KeyFile:  filepath.Join(dir, "node.key"),
+		CAFile:   filepath.Join(dir, "ca.crt"),
+	}
+	for file, data := range map[string][]byte{cfg.CertFile: certPEM, cfg.KeyFile: keyPEM, cfg.CAFile: ca.pem} {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+// startTLSServer 启动要求客户端证书的测试服务
+func startTLSServer(t *testing.T, ca *testCA) string {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, 10, "node-a")
+	cfg := writeTLSFiles(t, t.TempDir(), ca, certPEM, keyPEM)
+	cfg.RequireClientCert = true
+	certs, err := NewCertReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { certs.Close() })
+	return startTestServer(t, grpc.Creds(certs.ServerCredentials()))
+}
+
+func newTLSTestGroup(t *testing.T) {
+	g := NewGroup("tls-test", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("secure-" + key), nil
+	}))
+	t.Cleanup(func() { g.Close() })
+}
+
+// TestMTLS_RoundTrip 双方都出示同一CA签发的证书时可以正常通信
+func TestMTLS_RoundTrip(t *testing.T) {
+	newTLSTestGroup(t)
+	ca := newTestCA(t)
+	addr := startTLSServer(t, ca)
+
+	certPEM, keyPEM := ca.issue(t, 11, "node-b")
+	cfg := writeTLSFiles(t, t.TempDir(), ca, certPEM, keyPEM)
+	cfg.AllowedSANs = []string{"node-a"}
+	client, err := NewClient(addr, "test", nil, WithClientTLS(cfg), WithClientDialTimeout(2*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	value, err := client.Get(context.Background(), "tls-test", "k")
+	if err != nil || string(value) != "secure-k" {
+		t.Fatalf("Get = %q, %v", value, err)
+	}
+}
+
+// TestMTLS_Rejected 没有客户端证书、CA不匹配或SAN不匹配时连接失败
+func TestMTLS_Rejected(t *testing.T) {
+	newTLSTestGroup(t)
+	ca := newTestCA(t)
+	addr := startTLSServer(t, ca)
+
+	certPEM, keyPEM := ca.issue(t, 12, "node-b")
+	good := writeTLSFiles(t, t.TempDir(), ca, certPEM, keyPEM)
+
+	otherCA := newTestCA(t)
+	otherPEM, otherKey := otherCA.issue(t, 13, "node-b")
+	untrusted := writeTLSFiles(t, t.TempDir(), otherCA, otherPEM, otherKey)
+
+	noCert := TLSConfig{CAFile: good.CAFile}
+	wrongSAN := good
+	wrongSAN.AllowedSANs = []string{"node-z"}
+
+	tests := map[string]TLSConfig{
+		"no client cert": noCert,
+		"untrusted":      untrusted,
+		"wrong SAN":      wrongSAN,
+	}
+	for name, cfg := range tests {
+		t.Run(name, func(t *testing.T) {
+			client, err := NewClient(addr, "test", nil, WithClientTLS(cfg), WithNonBlockingDial(),
+				WithRPCTimeouts(300*time.Millisecond, 0, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			if _, err := client.Get(context.Background(), "tls-test", "k"); err == nil {
+				t.Fatal("expected TLS failure")
+			}
+		})
+	}
+}
+
+// TestCertReloader_HotReload 证书文件更新后自动加载新证书
+func TestCertReloader_HotReload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, 20, "node-a")
+	cfg := writeTLSFiles(t, dir, ca, certPEM, keyPEM)
+	cfg.ReloadInterval = 10 * time.Millisecond
+
+	r, err := NewCertReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	serial := func() int64 {
+		cert, err := r.certificate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.SerialNumber.Int64()
+	}
+	if serial() != 20 {
+		t.Fatalf("serial = %d, want 20", serial())
+	}
+
+	certPEM, keyPEM = ca.issue(t, 21, "node-a")
+	writeTLSFiles(t, dir, ca, certPEM, keyPEM)
+	// 文件系统的时间精度可能较粗，显式推后修改时间
+	future := time.Now().Add(time.Minute)
+	for _, f := range []string{cfg.CertFile, cfg.KeyFile, cfg.CAFile} {
+		os.Chtimes(f, future, future)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serial() != 21 {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestCertReloader_KeepsOldOnError 新文件无效时保留旧证书
+func TestCertReloader_KeepsOldOnError(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.issue(t, 30, "node-a")
+	cfg := writeTLSFiles(t, dir, ca, certPEM, keyPEM)
+
+	r, err := NewCertReloader(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	os.WriteFile(cfg.CertFile, []byte("garbage"), 0o600)
+	if err := r.Reload(); err == nil {
+		t.Fatal("expected reload error")
+	}
+	if _, err := r.certificate(); err != nil {
+		t.Fatalf("old certificate lost: %v", err)
+	}
+}
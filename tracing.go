@@ -0,0 +1,216 @@
+package blockcache
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"strconv"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 分布式追踪
+// Group、Client和Server分别通过WithTracerProvider、WithClientTracerProvider、WithServerTracerProvider开启，
+// 未开启时使用no-op实现。节点间的调用把W3C Trace Context写入gRPC metadata，
+// 因此一次 节点A Group.Get → Client.Get → 节点B Server.Get → Getter 的请求落在同一条trace上。
+
+// tracerName 追踪的instrumentation名称
+const tracerName = "github.com/crypt0walker/BlockCache"
+
+// tracePropagator 节点间传递追踪上下文的格式
+var tracePropagator = propagation.TraceContext{}
+
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+func newTracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		return noopTracer
+	}
+	return tp.Tracer(tracerName)
+}
+
+// WithTracerProvider 为Group的Get、加载和回源创建span
+func WithTracerProvider(tp trace.TracerProvider) GroupOption {
+	return func(g *Group) {
+		g.tracer = newTracer(tp)
+	}
+}
+
+// WithClientTracerProvider 为Client发出的每个RPC创建span，并把追踪上下文传给对端
+func WithClientTracerProvider(tp trace.TracerProvider) ClientOption {
+	return func(o *ClientOptions) {
+		o.TracerProvider = tp
+	}
+}
+
+// WithServerTracerProvider 从请求metadata中恢复追踪上下文，并为每个RPC创建span
+func WithServerTracerProvider(tp trace.TracerProvider) ServerOption {
+	return func(o *ServerOptions) {
+		o.TracerProvider = tp
+	}
+}
+
+// endSpan 按错误设置span状态并结束
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// keyAttribute 只记录key的哈希：key可能包含用户ID、邮箱等敏感信息，不应原样写入追踪后端，
+// 同一个key在各节点的span上哈希相同，仍然可以关联起来
+func keyAttribute(key string) attribute.KeyValue {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return attribute.String("blockcache.key_hash", strconv.FormatUint(h.Sum64(), 16))
+}
+
+// rpcAttributes RPC span的公共属性
+func rpcAttributes(fullMethod string, req interface{}) []attribute.KeyValue {
+	ri := requestInfo(fullMethod, req)
+	attrs := []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.method", fullMethod),
+	}
+	if ri.Group != "" {
+		attrs = append(attrs, attribute.String("blockcache.group", ri.Group), keyAttribute(ri.Key))
+	}
+	return attrs
+}
+
+// endRPCSpan 记录gRPC状态码后结束span
+func endRPCSpan(span trace.Span, err error) {
+	span.SetAttributes(attribute.String("rpc.grpc.status_code", status.Code(err).String()))
+	endSpan(span, err)
+}
+
+// metadataCarrier 让propagator读写gRPC metadata
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// injectTrace 把ctx中的追踪上下文追加到发出的metadata
+func injectTrace(ctx context.Context) context.Context {
+	md := metadata.MD{}
+	tracePropagator.Inject(ctx, metadataCarrier(md))
+	for k, v := range md {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v[0])
+	}
+	return ctx
+}
+
+// extractTrace 从收到的metadata中恢复调用方的追踪上下文
+func extractTrace(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return tracePropagator.Extract(ctx, metadataCarrier(md))
+}
+
+// clientTracingUnary 客户端一元调用的span
+func clientTracingUnary(tracer trace.Tracer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method, req)...),
+			trace.WithAttributes(attribute.String("net.peer.name", cc.Target())))
+		err := invoker(injectTrace(ctx), method, req, reply, cc, opts...)
+		endRPCSpan(span, err)
+		return err
+	}
+}
+
+// clientTracingStream 客户端流式调用的span，在收到最后一条响应或出错时结束
+func clientTracingStream(tracer trace.Tracer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := tracer.Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(rpcAttributes(method, nil)...),
+			trace.WithAttributes(attribute.String("net.peer.name", cc.Target())))
+		cs, err := streamer(injectTrace(ctx), desc, cc, method, opts...)
+		if err != nil {
+			endRPCSpan(span, err)
+			return nil, err
+		}
+		return &tracedClientStream{ClientStream: cs, span: span, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// tracedClientStream 在流结束时结束span
+type tracedClientStream struct {
+	grpc.ClientStream
+	span          trace.Span
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		// 服务端只返回一条响应，收到即结束
+		s.end(nil)
+	}
+	return err
+}
+
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() { endRPCSpan(s.span, err) })
+}
+
+// serverTracingUnary 服务端一元调用的span，父span来自调用方
+func serverTracingUnary(tracer trace.Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, span := tracer.Start(extractTrace(ctx), info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod, req)...))
+		resp, err := handler(ctx, req)
+		endRPCSpan(span, err)
+		return resp, err
+	}
+}
+
+// serverTracingStream 服务端流式调用的span
+func serverTracingStream(tracer trace.Tracer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := tracer.Start(extractTrace(ss.Context()), info.FullMethod,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(rpcAttributes(info.FullMethod, nil)...))
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		endRPCSpan(span, err)
+		return err
+	}
+}
@@ -0,0 +1,133 @@
+package blockcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// renamedPeer 把请求转发到另一个组名，用来在同一进程内模拟两个节点
+type renamedPeer struct {
+	Peer
+	group string
+}
+
+func (p renamedPeer) Get(ctx context.Context, group, key string) ([]byte, error) {
+	return p.Peer.Get(ctx, p.group, key)
+}
+
+func newTestTracerProvider(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	t.Cleanup(func() { tp.Shutdown(context.Background()) })
+	return tp, exp
+}
+
+// TestTracing_AcrossPeerHop 节点A Get → Client → 节点B Server → Getter 落在同一条trace上
+func TestTracing_AcrossPeerHop(t *testing.T) {
+	tp, exp := newTestTracerProvider(t)
+
+	b := NewGroup("trace-b", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("from-b"), nil
+	}), WithTracerProvider(tp))
+	defer b.Close()
+
+	addr := startOptionsServer(t, &ServerOptions{TracerProvider: tp})
+	client, err := NewClient(addr, "test", nil, WithClientDialTimeout(time.Second), WithClientTracerProvider(tp))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	a := NewGroup("trace-a", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		t.Error("node A should not load locally")
+		return nil, nil
+	}), WIthPeers(&fakePeerPicker{peers: []Peer{renamedPeer{Peer: client, group: "trace-b"}}}), WithTracerProvider(tp))
+	defer a.Close()
+
+	if v, err := a.Get(context.Background(), "k"); err != nil || v.String() != "from-b" {
+		t.Fatalf("Get = %q, %v", v.String(), err)
+	}
+
+	spans := exp.GetSpans()
+	byName := map[string][]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = append(byName[s.Name], s)
+	}
+	root := byName["Group.Get"]
+	if len(root) != 2 {
+		t.Fatalf("Group.Get spans = %d, want 2 (one per node)", len(root))
+	}
+	traceID := root[0].SpanContext.TraceID()
+	for _, s := range spans {
+		if s.SpanContext.TraceID() != traceID {
+			t.Fatalf("span %s is on a different trace", s.Name)
+		}
+	}
+
+	var clientSpan, serverSpan tracetest.SpanStub
+	for _, s := range byName["/pb.BlockCache/Get"] {
+		switch s.SpanKind {
+		case trace.SpanKindClient:
+			clientSpan = s
+		case trace.SpanKindServer:
+			serverSpan = s
+		}
+	}
+	if !clientSpan.SpanContext.IsValid() || !serverSpan.SpanContext.IsValid() {
+		t.Fatalf("missing RPC spans: %v", names(spans))
+	}
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() {
+		t.Fatal("server span is not a child of the client span")
+	}
+	if len(byName["Getter.Get"]) != 1 {
+		t.Fatalf("Getter.Get spans = %d, want 1", len(byName["Getter.Get"]))
+	}
+
+	// span上只有key的哈希，各节点的哈希一致
+	want := keyAttribute("k")
+	for _, s := range append(root, clientSpan, serverSpan) {
+		found := false
+		for _, attr := range s.Attributes {
+			if attr.Key == "blockcache.key" || attr.Value.AsString() == "k" {
+				t.Fatalf("span %s records the raw key", s.Name)
+			}
+			if attr == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("span %s has no key hash", s.Name)
+		}
+	}
+}
+
+// TestTracing_RecordsErrors 回源失败时span带错误状态
+func TestTracing_RecordsErrors(t *testing.T) {
+	tp, exp := newTestTracerProvider(t)
+	g := NewGroup("trace-errors", 1<<20, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return nil, ErrNotFound
+	}), WithTracerProvider(tp))
+	defer g.Close()
+
+	if _, err := g.Get(context.Background(), "missing"); err == nil {
+		t.Fatal("expected error")
+	}
+	for _, s := range exp.GetSpans() {
+		if s.Name == "Group.Get" && s.Status.Description == "" {
+			t.Fatalf("Group.Get span has no error status")
+		}
+	}
+}
+
+func names(spans tracetest.SpanStubs) []string {
+	out := make([]string, len(spans))
+	for i, s := range spans {
+		out[i] = s.Name
+	}
+	return out
+}